@@ -0,0 +1,93 @@
+// Package apperrors defines the domain errors shared by the repository,
+// service and handler layers. Handlers map them to HTTP responses through
+// their Kind and expose their Code to clients.
+package apperrors
+
+import "errors"
+
+// Kind classifies an error by how a client should react to it.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindInvalid
+	KindNotFound
+	KindConflict
+	KindUnprocessable
+	KindUnavailable
+)
+
+// Error is a domain error with a stable machine-readable code.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+var (
+	ErrInvalidSchedule = New(KindInvalid, "invalid_schedule", "invalid recurrence schedule")
+
+	ErrInvalidAmount         = New(KindUnprocessable, "invalid_amount", "amount must be positive")
+	ErrSameAccount           = New(KindUnprocessable, "same_account", "source and destination accounts must differ")
+	ErrInsufficientFunds     = New(KindUnprocessable, "insufficient_funds", "insufficient funds")
+	ErrCurrencyMismatch      = New(KindUnprocessable, "currency_mismatch", "currencies do not match")
+	ErrConversionUnavailable = New(KindUnprocessable, "conversion_unavailable", "currency conversion is not available")
+	ErrQuoteExpired          = New(KindUnprocessable, "quote_expired", "fx quote has expired")
+	ErrAuthorizationExpired  = New(KindUnprocessable, "authorization_expired", "pending transfer has expired")
+	ErrNotReversible         = New(KindUnprocessable, "transaction_not_reversible", "transaction cannot be reversed")
+	ErrReversalExceedsAmount = New(KindUnprocessable, "reversal_exceeds_amount", "reversal exceeds the unreversed amount")
+	ErrAccountFrozen         = New(KindUnprocessable, "account_frozen", "account is frozen")
+	ErrAccountClosed         = New(KindUnprocessable, "account_closed", "account is closed")
+	ErrBalanceNotZero        = New(KindUnprocessable, "balance_not_zero", "account balance must be zero or swept to another account")
+	ErrPaymentDeclined       = New(KindUnprocessable, "payment_declined", "payment declined by the payment rail")
+	ErrSystemAccount         = New(KindUnprocessable, "system_account", "system accounts cannot be changed directly")
+
+	// Limit breaches carry the name of the rule in their message
+	ErrTransferLimitExceeded = New(KindUnprocessable, "transfer_limit_exceeded", "transfer amount limit exceeded")
+	ErrDailyLimitExceeded    = New(KindUnprocessable, "daily_limit_exceeded", "daily outgoing limit exceeded")
+	ErrMonthlyLimitExceeded  = New(KindUnprocessable, "monthly_limit_exceeded", "monthly outgoing limit exceeded")
+	ErrVelocityLimitExceeded = New(KindUnprocessable, "velocity_limit_exceeded", "too many transfers in the last hour")
+
+	ErrAccountNotFound          = New(KindNotFound, "account_not_found", "account not found")
+	ErrTransactionNotFound      = New(KindNotFound, "transaction_not_found", "transaction not found")
+	ErrQuoteNotFound            = New(KindNotFound, "quote_not_found", "fx quote not found")
+	ErrTransferNotFound         = New(KindNotFound, "transfer_not_found", "pending transfer not found")
+	ErrScheduleNotFound         = New(KindNotFound, "scheduled_transfer_not_found", "scheduled transfer not found")
+	ErrStandingOrderNotFound    = New(KindNotFound, "standing_order_not_found", "standing order not found")
+	ErrExternalTransferNotFound = New(KindNotFound, "external_transfer_not_found", "deposit or withdrawal not found")
+	ErrWebhookNotFound          = New(KindNotFound, "webhook_not_found", "webhook subscription not found")
+	ErrWebhookDeliveryNotFound  = New(KindNotFound, "webhook_delivery_not_found", "webhook delivery not found")
+
+	ErrDuplicateAccount        = New(KindConflict, "duplicate_account", "account already exists")
+	ErrIdempotencyKeyReused    = New(KindConflict, "idempotency_key_reused", "idempotency key already used with a different request")
+	ErrQuoteRedeemed           = New(KindConflict, "quote_already_redeemed", "fx quote has already been redeemed")
+	ErrTransferNotPending      = New(KindConflict, "transfer_not_pending", "pending transfer is no longer pending")
+	ErrInvalidTransition       = New(KindConflict, "invalid_status_transition", "account status change not allowed")
+	ErrAccountHasHolds         = New(KindConflict, "account_has_holds", "account has pending transfers holding funds")
+	ErrScheduleNotScheduled    = New(KindConflict, "scheduled_transfer_not_cancellable", "scheduled transfer has already run or been cancelled")
+	ErrInvalidOrderTransition  = New(KindConflict, "invalid_standing_order_transition", "standing order status change not allowed")
+	ErrExternalReferenceReused = New(KindConflict, "external_reference_reused", "external reference already used with a different request")
+	ErrExternalTransferSettled = New(KindConflict, "external_transfer_settled", "external transfer is already settled")
+	ErrWebhookDeliveryPending  = New(KindConflict, "webhook_delivery_pending", "webhook delivery is still being attempted")
+
+	ErrDatabaseUnavailable    = New(KindUnavailable, "database_unavailable", "database unavailable")
+	ErrConcurrentUpdate       = New(KindUnavailable, "concurrent_update", "aborted by a concurrent update, please retry")
+	ErrPaymentRailUnavailable = New(KindUnavailable, "payment_rail_unavailable", "payment rail did not confirm the payment; retry with the same external_reference")
+)
+
+// As returns the first *Error in err's chain, if any.
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
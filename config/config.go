@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -11,6 +13,81 @@ type Config struct {
 	DBName     string
 	DBHost     string
 	DBPort     string
+
+	// IdempotencyKeyTTL is how long an Idempotency-Key is remembered after
+	// the transaction it guards was submitted.
+	IdempotencyKeyTTL time.Duration
+	// IdempotencySweepInterval is how often expired keys are removed.
+	IdempotencySweepInterval time.Duration
+
+	// FXRatesFile is a JSON file of "FROM/TO" exchange rates. Without it
+	// cross-currency transfers are unavailable.
+	FXRatesFile string
+	// FXQuoteTTL is how long a quoted rate can be redeemed.
+	FXQuoteTTL time.Duration
+
+	// AuthorizationTTL is how long a pending transfer holds funds before it
+	// expires if never captured or voided.
+	AuthorizationTTL time.Duration
+	// AuthorizationExpiryInterval is how often expired holds are released.
+	AuthorizationExpiryInterval time.Duration
+
+	// LimitRulesFile is a JSON file of transfer limit rules. Without it the
+	// rules are read from the transfer_limit_rules table.
+	LimitRulesFile string
+
+	// FeeSchedulesFile is a JSON file of fee schedules. Without it the
+	// schedules are read from the fee_schedules table.
+	FeeSchedulesFile string
+	// FeeRevenueAccountID receives collected fees in its currency. Fees in
+	// other currencies, or all fees when unset, go to the fees:<CUR> system
+	// account.
+	FeeRevenueAccountID int64
+
+	// ScheduledTransferInterval is how often due scheduled transfers and
+	// standing orders are run.
+	ScheduledTransferInterval time.Duration
+	// ScheduledTransferLease is how long a claimed scheduled transfer or
+	// standing order may go without a recorded outcome before another
+	// attempt claims it. It must stay below IdempotencyKeyTTL.
+	ScheduledTransferLease time.Duration
+
+	// PaymentRail names the rail deposits and withdrawals move over. Only
+	// "fake", an in-process rail that accepts every payment, is built in.
+	PaymentRail string
+	// PaymentRailTimeout bounds each call to the payment rail.
+	PaymentRailTimeout time.Duration
+
+	// BalanceSnapshotInterval is how often ended days are checked for
+	// missing end-of-day balance snapshots.
+	BalanceSnapshotInterval time.Duration
+
+	// ReconciliationInterval is how often balances are reconciled against
+	// the ledger and the findings recorded.
+	ReconciliationInterval time.Duration
+
+	// EventPublisher names where outbox events are published: "stdout" or
+	// "file", which appends them to EventPublisherFile.
+	EventPublisher     string
+	EventPublisherFile string
+	// OutboxRelayInterval is how often the outbox is checked for new
+	// events, OutboxBatchSize how many one pass attempts, and
+	// EventPublishTimeout bounds each call to the publisher.
+	OutboxRelayInterval time.Duration
+	OutboxBatchSize     int64
+	EventPublishTimeout time.Duration
+
+	// WebhookInterval is how often due webhook deliveries are sent and
+	// WebhookTimeout bounds each request; a delivery claimed more than
+	// WebhookLease ago without an outcome is claimed again. A failed
+	// delivery waits WebhookBackoffBase, doubling per attempt up to
+	// WebhookBackoffMax, and is dead after WebhookMaxAttempts.
+	WebhookInterval    time.Duration
+	WebhookTimeout     time.Duration
+	WebhookLease       time.Duration
+	WebhookMaxAttempts int64
+	WebhookBackoffBase time.Duration
+	WebhookBackoffMax  time.Duration
 }
 
 var logger = log.New(os.Stdout, "[transactions] ", log.LstdFlags|log.Lshortfile)
@@ -26,6 +103,43 @@ func LoadConfig() *Config {
 		DBName:     getEnv("DB_NAME", "postgres"),
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
+
+		IdempotencyKeyTTL:        getDurationEnv("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		IdempotencySweepInterval: getDurationEnv("IDEMPOTENCY_SWEEP_INTERVAL", time.Hour),
+
+		FXRatesFile: getEnv("FX_RATES_FILE", ""),
+		FXQuoteTTL:  getDurationEnv("FX_QUOTE_TTL", time.Minute),
+
+		AuthorizationTTL:            getDurationEnv("AUTHORIZATION_TTL", 7*24*time.Hour),
+		AuthorizationExpiryInterval: getDurationEnv("AUTHORIZATION_EXPIRY_INTERVAL", time.Minute),
+
+		LimitRulesFile: getEnv("LIMIT_RULES_FILE", ""),
+
+		FeeSchedulesFile:    getEnv("FEE_SCHEDULES_FILE", ""),
+		FeeRevenueAccountID: getInt64Env("FEE_REVENUE_ACCOUNT_ID", 0),
+
+		ScheduledTransferInterval: getDurationEnv("SCHEDULED_TRANSFER_INTERVAL", 30*time.Second),
+		ScheduledTransferLease:    getDurationEnv("SCHEDULED_TRANSFER_LEASE", 5*time.Minute),
+
+		PaymentRail:        getEnv("PAYMENT_RAIL", "fake"),
+		PaymentRailTimeout: getDurationEnv("PAYMENT_RAIL_TIMEOUT", 30*time.Second),
+
+		BalanceSnapshotInterval: getDurationEnv("BALANCE_SNAPSHOT_INTERVAL", time.Hour),
+
+		ReconciliationInterval: getDurationEnv("RECONCILIATION_INTERVAL", 24*time.Hour),
+
+		EventPublisher:      getEnv("EVENT_PUBLISHER", "stdout"),
+		EventPublisherFile:  getEnv("EVENT_PUBLISHER_FILE", "events.log"),
+		OutboxRelayInterval: getDurationEnv("OUTBOX_RELAY_INTERVAL", time.Second),
+		OutboxBatchSize:     getInt64Env("OUTBOX_BATCH_SIZE", 100),
+		EventPublishTimeout: getDurationEnv("EVENT_PUBLISH_TIMEOUT", 10*time.Second),
+
+		WebhookInterval:    getDurationEnv("WEBHOOK_INTERVAL", 5*time.Second),
+		WebhookTimeout:     getDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookLease:       getDurationEnv("WEBHOOK_LEASE", time.Minute),
+		WebhookMaxAttempts: getInt64Env("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookBackoffBase: getDurationEnv("WEBHOOK_BACKOFF_BASE", 30*time.Second),
+		WebhookBackoffMax:  getDurationEnv("WEBHOOK_BACKOFF_MAX", time.Hour),
 	}
 }
 
@@ -35,3 +149,29 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getInt64Env(key string, fallback int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		logger.Printf("invalid integer %q for %s, using %d", value, key, fallback)
+		return fallback
+	}
+	return n
+}
+
+func getDurationEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		logger.Printf("invalid duration %q for %s, using %s", value, key, fallback)
+		return fallback
+	}
+	return d
+}
@@ -9,7 +9,9 @@ import (
 )
 
 func NewDB(cfg *config.Config) (*sql.DB, error) {
-	dsn := fmt.Sprintf("postgresql://%s:%s@%s:%s/%s?sslmode=disable",
+	// Timestamps are stored as UTC wall-clock times in TIMESTAMP columns, so
+	// the session runs in UTC for NOW() and column defaults to match them
+	dsn := fmt.Sprintf("postgresql://%s:%s@%s:%s/%s?sslmode=disable&timezone=UTC",
 		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName)
 	return sql.Open("postgres", dsn)
 }
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_destination_account_id;
+DROP INDEX IF EXISTS idx_transactions_source_account_id;
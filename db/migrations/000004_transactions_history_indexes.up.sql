@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_transactions_source_account_id ON transactions (source_account_id, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_destination_account_id ON transactions (destination_account_id, id);
@@ -0,0 +1,3 @@
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS destination_balance_after,
+    DROP COLUMN IF EXISTS source_balance_after;
@@ -0,0 +1,3 @@
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS source_balance_after NUMERIC(20,10),
+    ADD COLUMN IF NOT EXISTS destination_balance_after NUMERIC(20,10);
@@ -0,0 +1,3 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE accounts DROP COLUMN IF EXISTS currency;
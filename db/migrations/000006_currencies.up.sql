@@ -0,0 +1,5 @@
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD' CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD' CHECK (currency ~ '^[A-Z]{3}$');
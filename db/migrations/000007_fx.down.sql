@@ -0,0 +1,7 @@
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS fx_quote_id,
+    DROP COLUMN IF EXISTS fx_rate,
+    DROP COLUMN IF EXISTS destination_currency,
+    DROP COLUMN IF EXISTS destination_amount;
+
+DROP TABLE IF EXISTS fx_quotes;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id VARCHAR(64) PRIMARY KEY,
+    source_currency CHAR(3) NOT NULL,
+    destination_currency CHAR(3) NOT NULL,
+    rate NUMERIC(20,10) NOT NULL CHECK (rate > 0),
+    source_amount NUMERIC(20,10),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    redeemed_at TIMESTAMP,
+    transaction_id INTEGER REFERENCES transactions(id)
+);
+
+-- amount stays the amount debited in the source currency; the destination
+-- side of a conversion is recorded separately.
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS destination_amount NUMERIC(20,10),
+    ADD COLUMN IF NOT EXISTS destination_currency CHAR(3),
+    ADD COLUMN IF NOT EXISTS fx_rate NUMERIC(20,10),
+    ADD COLUMN IF NOT EXISTS fx_quote_id VARCHAR(64) REFERENCES fx_quotes(id);
+
+UPDATE transactions
+SET destination_amount = amount, destination_currency = currency
+WHERE destination_amount IS NULL;
+
+ALTER TABLE transactions
+    ALTER COLUMN destination_amount SET NOT NULL,
+    ALTER COLUMN destination_currency SET NOT NULL;
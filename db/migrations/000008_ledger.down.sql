@@ -0,0 +1,15 @@
+DROP TRIGGER IF EXISTS postings_append_only ON postings;
+DROP FUNCTION IF EXISTS reject_posting_change();
+DROP TRIGGER IF EXISTS postings_balanced ON postings;
+DROP FUNCTION IF EXISTS check_journal_entry_balanced();
+
+DROP TABLE IF EXISTS postings;
+DROP TABLE IF EXISTS journal_entries;
+
+DELETE FROM accounts WHERE system_code IS NOT NULL;
+
+ALTER TABLE accounts
+    DROP CONSTRAINT IF EXISTS accounts_account_id_currency_key,
+    DROP COLUMN IF EXISTS system_code;
+
+DROP SEQUENCE IF EXISTS system_account_id_seq;
@@ -0,0 +1,87 @@
+-- System accounts (opening-balance equity, FX positions, ...) live in
+-- accounts with negative ids so they never collide with client-chosen ids.
+CREATE SEQUENCE IF NOT EXISTS system_account_id_seq INCREMENT BY -1 MAXVALUE -1 START WITH -1;
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS system_code VARCHAR(64) UNIQUE,
+    ADD CONSTRAINT accounts_account_id_currency_key UNIQUE (account_id, currency);
+
+CREATE TABLE IF NOT EXISTS journal_entries (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id INTEGER REFERENCES transactions(id),
+    kind VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_journal_entries_transaction_id ON journal_entries (transaction_id);
+
+-- A posting is a signed movement on one account: negative debits it,
+-- positive credits it. Its currency must be the account's currency.
+CREATE TABLE IF NOT EXISTS postings (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id BIGINT NOT NULL REFERENCES journal_entries(id),
+    account_id BIGINT NOT NULL,
+    amount NUMERIC(20,10) NOT NULL CHECK (amount <> 0),
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (account_id, currency) REFERENCES accounts(account_id, currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_postings_entry_id ON postings (entry_id);
+CREATE INDEX IF NOT EXISTS idx_postings_account_id ON postings (account_id, id);
+
+-- Every journal entry must balance per currency. The check is deferred to
+-- commit so an entry's postings can be inserted one by one.
+CREATE OR REPLACE FUNCTION check_journal_entry_balanced() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM postings
+        WHERE entry_id = NEW.entry_id
+        GROUP BY currency
+        HAVING SUM(amount) <> 0
+    ) THEN
+        RAISE EXCEPTION 'journal entry % does not balance', NEW.entry_id
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER postings_balanced
+    AFTER INSERT ON postings
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_journal_entry_balanced();
+
+-- Postings are append-only; corrections are new entries.
+CREATE OR REPLACE FUNCTION reject_posting_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'postings are append-only' USING ERRCODE = 'restrict_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER postings_append_only
+    BEFORE UPDATE OR DELETE ON postings
+    FOR EACH ROW EXECUTE FUNCTION reject_posting_change();
+
+-- Open the ledger with the balances held at migration time, each funded
+-- from the opening-balance equity account of its currency.
+DO $$
+DECLARE
+    acc RECORD;
+    equity_id BIGINT;
+    new_entry_id BIGINT;
+BEGIN
+    FOR acc IN SELECT account_id, balance, currency FROM accounts WHERE balance <> 0 AND system_code IS NULL ORDER BY account_id LOOP
+        INSERT INTO accounts (account_id, balance, currency, system_code)
+        VALUES (nextval('system_account_id_seq'), 0, acc.currency, 'equity:' || acc.currency)
+        ON CONFLICT (system_code) DO NOTHING;
+        SELECT account_id INTO equity_id FROM accounts WHERE system_code = 'equity:' || acc.currency;
+
+        INSERT INTO journal_entries (kind) VALUES ('opening_balance') RETURNING id INTO new_entry_id;
+        INSERT INTO postings (entry_id, account_id, amount, currency) VALUES
+            (new_entry_id, acc.account_id, acc.balance, acc.currency),
+            (new_entry_id, equity_id, -acc.balance, acc.currency);
+        UPDATE accounts SET balance = balance - acc.balance WHERE account_id = equity_id;
+    END LOOP;
+END;
+$$;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS transfer_batches (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS batch_id BIGINT REFERENCES transfer_batches(id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_batch_id ON transactions (batch_id);
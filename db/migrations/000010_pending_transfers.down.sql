@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS pending_transfers;
+
+ALTER TABLE accounts DROP COLUMN IF EXISTS held_balance;
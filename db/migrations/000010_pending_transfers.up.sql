@@ -0,0 +1,22 @@
+-- held_balance is the sum of open authorizations on an account; the
+-- available balance is balance - held_balance.
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS held_balance NUMERIC(20,10) NOT NULL DEFAULT 0 CHECK (held_balance >= 0);
+
+CREATE TABLE IF NOT EXISTS pending_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    source_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    destination_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    amount NUMERIC(20,10) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'captured', 'voided', 'expired')),
+    captured_amount NUMERIC(20,10),
+    transaction_id INTEGER REFERENCES transactions(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_transfers_expiry
+    ON pending_transfers (expires_at) WHERE status = 'pending';
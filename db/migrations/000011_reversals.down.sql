@@ -0,0 +1,4 @@
+ALTER TABLE transactions
+    DROP CONSTRAINT IF EXISTS transactions_reversed_amount_check,
+    DROP COLUMN IF EXISTS reversed_amount,
+    DROP COLUMN IF EXISTS reverses_transaction_id;
@@ -0,0 +1,9 @@
+-- A reversal is a compensating transaction pointing at the one it undoes.
+-- reversed_amount tracks, in the original's source currency, how much of a
+-- transaction has been reversed so far.
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS reverses_transaction_id INTEGER REFERENCES transactions(id),
+    ADD COLUMN IF NOT EXISTS reversed_amount NUMERIC(20,10) NOT NULL DEFAULT 0,
+    ADD CONSTRAINT transactions_reversed_amount_check CHECK (reversed_amount >= 0 AND reversed_amount <= amount);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_reverses ON transactions (reverses_transaction_id);
@@ -0,0 +1,8 @@
+-- status gates money movement: frozen accounts cannot send and receive only
+-- when allow_incoming is set; closed accounts do neither.
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'frozen', 'closed')),
+    ADD COLUMN IF NOT EXISTS status_reason VARCHAR(32),
+    ADD COLUMN IF NOT EXISTS allow_incoming BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMP;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_accounts_overdrawn;
+DROP TABLE IF EXISTS overdraft_limit_changes;
+
+ALTER TABLE accounts DROP COLUMN IF EXISTS overdraft_limit;
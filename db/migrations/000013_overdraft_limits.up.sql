@@ -0,0 +1,17 @@
+-- overdraft_limit is how far below zero an account's balance may go.
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS overdraft_limit NUMERIC(20,10) NOT NULL DEFAULT 0 CHECK (overdraft_limit >= 0);
+
+-- Every change to a limit is kept for audit.
+CREATE TABLE IF NOT EXISTS overdraft_limit_changes (
+    id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    previous_limit NUMERIC(20,10) NOT NULL,
+    new_limit NUMERIC(20,10) NOT NULL,
+    reason TEXT NOT NULL,
+    changed_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_overdraft_limit_changes_account ON overdraft_limit_changes (account_id, id);
+CREATE INDEX IF NOT EXISTS idx_accounts_overdrawn ON accounts (account_id) WHERE balance < 0 AND system_code IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_transactions_source_created;
+DROP TABLE IF EXISTS transfer_limit_rules;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS transfer_limit_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    rule_type VARCHAR(32) NOT NULL
+        CHECK (rule_type IN ('max_amount', 'daily_outgoing', 'monthly_outgoing', 'hourly_count')),
+    account_id BIGINT REFERENCES accounts(account_id),
+    currency CHAR(3),
+    amount NUMERIC(20,10) CHECK (amount > 0),
+    max_count INTEGER CHECK (max_count > 0),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((rule_type = 'hourly_count') = (max_count IS NOT NULL)),
+    CHECK ((rule_type = 'hourly_count') = (amount IS NULL))
+);
+
+-- Outgoing usage is summed per source account over recent windows
+CREATE INDEX IF NOT EXISTS idx_transactions_source_created ON transactions (source_account_id, created_at);
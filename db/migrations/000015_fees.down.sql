@@ -0,0 +1,7 @@
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS fee_schedule,
+    DROP COLUMN IF EXISTS fee;
+
+DROP TABLE IF EXISTS fee_schedules;
+
+ALTER TABLE accounts DROP COLUMN IF EXISTS account_type;
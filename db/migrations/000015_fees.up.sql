@@ -0,0 +1,28 @@
+-- account_type groups accounts for fee schedules, e.g. 'standard' or 'business'.
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS account_type VARCHAR(32) NOT NULL DEFAULT 'standard';
+
+-- A schedule applies to one account, to every account of a type, or, with
+-- neither set, to all accounts. tiers is a JSON array of
+-- {"up_to", "flat", "percentage"} objects, ordered by up_to.
+CREATE TABLE IF NOT EXISTS fee_schedules (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    fee_type VARCHAR(16) NOT NULL CHECK (fee_type IN ('flat', 'percentage', 'tiered')),
+    account_id BIGINT REFERENCES accounts(account_id),
+    account_type VARCHAR(32),
+    currency CHAR(3),
+    flat_amount NUMERIC(20,10) CHECK (flat_amount >= 0),
+    percentage NUMERIC(12,8) CHECK (percentage >= 0),
+    tiers JSONB,
+    min_fee NUMERIC(20,10) CHECK (min_fee >= 0),
+    max_fee NUMERIC(20,10) CHECK (max_fee >= 0),
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (account_id IS NULL OR account_type IS NULL)
+);
+
+-- fee is charged to the source on top of amount, in the source currency
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS fee NUMERIC(20,10) NOT NULL DEFAULT 0 CHECK (fee >= 0),
+    ADD COLUMN IF NOT EXISTS fee_schedule VARCHAR(64);
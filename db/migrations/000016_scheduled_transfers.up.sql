@@ -0,0 +1,27 @@
+-- Transfers submitted for a future execute_at. The worker claims due rows
+-- by moving them to 'processing'; a row whose claim is older than the lease
+-- is claimed again.
+CREATE TABLE IF NOT EXISTS scheduled_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    source_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    destination_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    amount NUMERIC(20,10) NOT NULL CHECK (amount > 0),
+    currency CHAR(3),
+    allow_conversion BOOLEAN NOT NULL DEFAULT FALSE,
+    execute_at TIMESTAMP NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'scheduled'
+        CHECK (status IN ('scheduled', 'processing', 'executed', 'failed', 'cancelled')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    claimed_at TIMESTAMP,
+    transaction_id INTEGER REFERENCES transactions(id),
+    failure_code VARCHAR(64),
+    failure_reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_due
+    ON scheduled_transfers (execute_at) WHERE status IN ('scheduled', 'processing');
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_source
+    ON scheduled_transfers (source_account_id, execute_at);
@@ -0,0 +1,47 @@
+-- Recurring transfers. next_run_at is the next occurrence of the cron
+-- schedule; retry_at, when set, is when a run short of funds is attempted
+-- again. claimed_at marks an order a worker is running.
+CREATE TABLE IF NOT EXISTS standing_orders (
+    id BIGSERIAL PRIMARY KEY,
+    source_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    destination_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    amount NUMERIC(20,10) NOT NULL CHECK (amount > 0),
+    currency CHAR(3),
+    allow_conversion BOOLEAN NOT NULL DEFAULT FALSE,
+    schedule VARCHAR(100) NOT NULL,
+    on_insufficient_funds VARCHAR(8) NOT NULL DEFAULT 'skip'
+        CHECK (on_insufficient_funds IN ('skip', 'retry')),
+    max_retries INTEGER NOT NULL DEFAULT 0 CHECK (max_retries >= 0),
+    retry_interval_seconds INTEGER NOT NULL DEFAULT 3600 CHECK (retry_interval_seconds > 0),
+    status VARCHAR(16) NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'paused', 'cancelled', 'completed')),
+    next_run_at TIMESTAMP NOT NULL,
+    retry_at TIMESTAMP,
+    retries INTEGER NOT NULL DEFAULT 0,
+    ends_at TIMESTAMP,
+    claimed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_standing_orders_due
+    ON standing_orders (COALESCE(retry_at, next_run_at)) WHERE status = 'active';
+
+CREATE INDEX IF NOT EXISTS idx_standing_orders_source
+    ON standing_orders (source_account_id);
+
+CREATE TABLE IF NOT EXISTS standing_order_runs (
+    id BIGSERIAL PRIMARY KEY,
+    standing_order_id BIGINT NOT NULL REFERENCES standing_orders(id),
+    scheduled_for TIMESTAMP NOT NULL,
+    attempt INTEGER NOT NULL,
+    status VARCHAR(16) NOT NULL
+        CHECK (status IN ('executed', 'retrying', 'skipped', 'failed')),
+    transaction_id INTEGER REFERENCES transactions(id),
+    failure_code VARCHAR(64),
+    failure_reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_standing_order_runs_order
+    ON standing_order_runs (standing_order_id, id);
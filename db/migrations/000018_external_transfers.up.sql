@@ -0,0 +1,25 @@
+-- Deposits and withdrawals against external funding sources. The ledger
+-- side is a transaction with the world:<CUR> settlement account;
+-- rail_reference is the payment rail's id for the movement.
+CREATE TABLE IF NOT EXISTS external_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    direction VARCHAR(16) NOT NULL CHECK (direction IN ('deposit', 'withdrawal')),
+    amount NUMERIC(20,10) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    rail VARCHAR(32) NOT NULL,
+    funding_source VARCHAR(255) NOT NULL,
+    external_reference VARCHAR(255) NOT NULL,
+    rail_reference VARCHAR(255),
+    status VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'completed', 'failed')),
+    failure_reason TEXT,
+    transaction_id INTEGER REFERENCES transactions(id),
+    refund_transaction_id INTEGER REFERENCES transactions(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (direction, external_reference)
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_transfers_account
+    ON external_transfers (account_id, id);
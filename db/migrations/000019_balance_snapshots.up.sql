@@ -0,0 +1,15 @@
+-- End-of-day balances, one row per account and day. balance is the sum of
+-- the account's postings created before the end of snapshot_date, i.e.
+-- before snapshot_date + 1 at midnight.
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    snapshot_date DATE NOT NULL,
+    balance NUMERIC(20,10) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (account_id, snapshot_date)
+);
+
+-- Point-in-time balances add up an account's postings after its latest
+-- snapshot.
+CREATE INDEX IF NOT EXISTS idx_postings_account_created_at
+    ON postings (account_id, created_at);
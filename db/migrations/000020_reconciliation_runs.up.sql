@@ -0,0 +1,26 @@
+-- Results of reconciling cached balances against the ledger. A run is
+-- balanced when no account drifts and every currency sums to zero.
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id BIGSERIAL PRIMARY KEY,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    accounts_checked INTEGER NOT NULL,
+    drifted_accounts INTEGER NOT NULL,
+    balanced BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- One row per drifted account (account_id set) and per currency
+-- (account_id NULL). recorded is the cached balance or the sum of cached
+-- balances; computed is the sum of postings.
+CREATE TABLE IF NOT EXISTS reconciliation_findings (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL REFERENCES reconciliation_runs(id),
+    account_id BIGINT,
+    currency CHAR(3) NOT NULL,
+    recorded NUMERIC(20,10) NOT NULL,
+    computed NUMERIC(20,10) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconciliation_findings_run
+    ON reconciliation_findings (run_id);
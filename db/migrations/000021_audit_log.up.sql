@@ -0,0 +1,38 @@
+-- Who changed what, written in the same DB transaction as the change. Each
+-- row's hash covers its own fields and the previous row's hash, so editing
+-- or removing a row breaks the chain from that point on. before and after
+-- are JSON rather than JSONB so the hashed text is stored verbatim.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255) NOT NULL DEFAULT '',
+    source_ip VARCHAR(64) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    entity_type VARCHAR(64) NOT NULL,
+    entity_id VARCHAR(64) NOT NULL DEFAULT '',
+    before JSON,
+    after JSON,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log (entity_type, entity_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_request ON audit_log (request_id) WHERE request_id <> '';
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log (occurred_at);
+
+-- The audit log is append-only, like postings.
+CREATE OR REPLACE FUNCTION reject_audit_log_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only' USING ERRCODE = 'restrict_violation';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION reject_audit_log_change();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_log_change();
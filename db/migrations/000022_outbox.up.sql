@@ -0,0 +1,17 @@
+-- Domain events recorded in the same DB transaction as the change they
+-- describe, and published afterwards by the outbox relay. account_ids are
+-- the accounts an event concerns; events are published in id order per
+-- account. A row is published once published_at is set.
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(64) NOT NULL,
+    account_ids BIGINT[] NOT NULL,
+    payload JSON NOT NULL,
+    occurred_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished
+    ON outbox (id) WHERE published_at IS NULL;
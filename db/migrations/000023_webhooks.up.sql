@@ -0,0 +1,52 @@
+-- Partner callbacks. A subscription receives the outbox events of its
+-- event_types, limited to events concerning one of its account_ids unless
+-- that is empty. Deleted subscriptions are kept for their delivery log.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT[] NOT NULL,
+    account_ids BIGINT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    deleted_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- One row per event and subscription, so an event the outbox relay
+-- publishes twice is still delivered once. payload is the body sent.
+-- A pending delivery is due at next_attempt_at; claiming it pushes that
+-- forward by the worker's lease.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions(id),
+    event_id BIGINT NOT NULL REFERENCES outbox(id),
+    event_type VARCHAR(64) NOT NULL,
+    payload JSON NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'delivered', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    last_error TEXT,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+    ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription
+    ON webhook_deliveries (subscription_id, id);
+
+-- Every HTTP request made for a delivery
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id),
+    attempted_at TIMESTAMP NOT NULL,
+    status_code INTEGER,
+    error TEXT,
+    duration_ms BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery
+    ON webhook_delivery_attempts (delivery_id, id);
@@ -0,0 +1,145 @@
+// Package events delivers the domain events recorded in the outbox to
+// downstream systems.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"transactions/models"
+)
+
+// Publisher hands one event to downstream systems. The outbox relay calls
+// it at least once per event, so consumers must tolerate duplicates; events
+// sharing an account arrive in the order they were recorded. A returned
+// error makes the relay try the event again later.
+type Publisher interface {
+	Name() string
+	Publish(ctx context.Context, e models.Event) error
+}
+
+// WriterPublisher writes each event as one line of JSON.
+type WriterPublisher struct {
+	mu   sync.Mutex
+	name string
+	w    io.Writer
+}
+
+func NewWriterPublisher(name string, w io.Writer) *WriterPublisher {
+	return &WriterPublisher{name: name, w: w}
+}
+
+// NewStdoutPublisher writes events to standard output.
+func NewStdoutPublisher() *WriterPublisher {
+	return NewWriterPublisher("stdout", os.Stdout)
+}
+
+// OpenFilePublisher appends events to the file at path, creating it if
+// needed. The caller closes the returned file.
+func OpenFilePublisher(path string) (*WriterPublisher, *os.File, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open event file: %w", err)
+	}
+	return NewWriterPublisher("file", f), f, nil
+}
+
+func (p *WriterPublisher) Name() string {
+	return p.name
+}
+
+func (p *WriterPublisher) Publish(ctx context.Context, e models.Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
+
+// MultiPublisher hands each event to every one of its publishers. It fails
+// if any of them fails, so the event is retried with all of them; each must
+// tolerate the duplicates that follow.
+type MultiPublisher struct {
+	publishers []Publisher
+}
+
+func NewMultiPublisher(publishers ...Publisher) *MultiPublisher {
+	return &MultiPublisher{publishers: publishers}
+}
+
+func (p *MultiPublisher) Name() string {
+	name := ""
+	for i, pub := range p.publishers {
+		if i > 0 {
+			name += "+"
+		}
+		name += pub.Name()
+	}
+	return name
+}
+
+func (p *MultiPublisher) Publish(ctx context.Context, e models.Event) error {
+	var errs []error
+	for _, pub := range p.publishers {
+		if err := pub.Publish(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", pub.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MemoryPublisher keeps published events in memory, for tests. Publishing
+// an event that concerns a failing account returns that account's error.
+type MemoryPublisher struct {
+	mu      sync.Mutex
+	events  []models.Event
+	failing map[int64]error
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{failing: map[int64]error{}}
+}
+
+func (p *MemoryPublisher) Name() string {
+	return "memory"
+}
+
+// Fail makes every later event concerning accountID fail with err, until
+// Recover is called.
+func (p *MemoryPublisher) Fail(accountID int64, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failing[accountID] = err
+}
+
+func (p *MemoryPublisher) Recover(accountID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.failing, accountID)
+}
+
+// Events returns the events published so far, in publishing order.
+func (p *MemoryPublisher) Events() []models.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]models.Event(nil), p.events...)
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, e models.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, id := range e.AccountIDs {
+		if err := p.failing[id]; err != nil {
+			return err
+		}
+	}
+	p.events = append(p.events, e)
+	return nil
+}
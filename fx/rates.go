@@ -0,0 +1,85 @@
+// Package fx provides the exchange rates used for cross-currency transfers.
+package fx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"transactions/apperrors"
+	"transactions/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// RateProvider returns how many units of to one unit of from buys.
+type RateProvider interface {
+	Rate(from, to models.Currency) (decimal.Decimal, error)
+}
+
+// inverseRatePrecision is the number of decimal places kept when a rate is
+// derived by inverting the opposite pair.
+const inverseRatePrecision = 10
+
+type pair struct {
+	from, to models.Currency
+}
+
+// StaticRateProvider serves a fixed set of rates. It is meant for tests and
+// offline use; a rate for A/B also answers B/A by inversion.
+type StaticRateProvider struct {
+	rates map[pair]decimal.Decimal
+}
+
+// NewStaticRateProvider builds a provider from rates keyed "FROM/TO",
+// e.g. {"USD/EUR": 0.92}.
+func NewStaticRateProvider(rates map[string]decimal.Decimal) (*StaticRateProvider, error) {
+	p := &StaticRateProvider{rates: make(map[pair]decimal.Decimal, len(rates))}
+	for key, rate := range rates {
+		from, to, ok := strings.Cut(key, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid currency pair %q, want FROM/TO", key)
+		}
+		fromCur, err := models.ParseCurrency(from)
+		if err != nil {
+			return nil, err
+		}
+		toCur, err := models.ParseCurrency(to)
+		if err != nil {
+			return nil, err
+		}
+		if !rate.IsPositive() {
+			return nil, fmt.Errorf("rate for %s must be positive", key)
+		}
+		p.rates[pair{fromCur, toCur}] = rate
+	}
+	return p, nil
+}
+
+// LoadRatesFile reads a JSON object of "FROM/TO" pairs to decimal strings:
+//
+//	{"USD/EUR": "0.92", "EUR/GBP": "0.85"}
+func LoadRatesFile(path string) (*StaticRateProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rates map[string]decimal.Decimal
+	if err := json.Unmarshal(b, &rates); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return NewStaticRateProvider(rates)
+}
+
+func (p *StaticRateProvider) Rate(from, to models.Currency) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	if rate, ok := p.rates[pair{from, to}]; ok {
+		return rate, nil
+	}
+	if rate, ok := p.rates[pair{to, from}]; ok {
+		return decimal.NewFromInt(1).DivRound(rate, inverseRatePrecision), nil
+	}
+	return decimal.Decimal{}, fmt.Errorf("%w: no rate for %s/%s", apperrors.ErrConversionUnavailable, from, to)
+}
@@ -2,14 +2,18 @@ package handler
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
+	"transactions/models"
 	"transactions/service"
 
 	"github.com/gorilla/mux"
 )
 
+var accountTypePattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
 type AccountHandler struct {
 	Service *service.AccountService
 }
@@ -22,36 +26,62 @@ func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		AccountID      int64  `json:"account_id"`
 		InitialBalance string `json:"initial_balance"`
+		Currency       string `json:"currency"`
+		AccountType    string `json:"account_type"`
 	}
 
 	// WriteErrorResponse is a convenience function for 400 Bad Request errors
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteErrorResponse(w, http.StatusBadRequest, "invalid request: could not decode JSON")
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
 		return
 	}
 
-	// Input validation
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
 	if req.AccountID <= 0 {
-		WriteErrorResponse(w, http.StatusBadRequest, "account_id must be a positive integer")
+		fieldErrors = append(fieldErrors, FieldError{"account_id", "account_id must be a positive integer"})
+	}
+
+	// Currency defaults to USD so existing clients keep working
+	currency := models.DefaultCurrency
+	if req.Currency != "" {
+		c, err := models.ParseCurrency(req.Currency)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"currency", "currency must be a supported ISO 4217 code"})
+		}
+		currency = c
 	}
 
 	if req.InitialBalance == "" {
-		WriteErrorResponse(w, http.StatusBadRequest, "initial_balance is required")
-		return
+		fieldErrors = append(fieldErrors, FieldError{"initial_balance", "initial_balance is required"})
+	} else if bal, err := models.NewMoneyFromString(req.InitialBalance); err != nil || bal.IsNegative() {
+		fieldErrors = append(fieldErrors, FieldError{"initial_balance", "initial_balance must be a valid non-negative number"})
+	} else if currency != "" {
+		if err := bal.CheckScale(currency); err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"initial_balance", "initial_balance " + err.Error()})
+		}
+	}
+
+	// Account type selects fee schedules; it defaults to standard
+	accountType := models.DefaultAccountType
+	if req.AccountType != "" {
+		if !accountTypePattern.MatchString(req.AccountType) {
+			fieldErrors = append(fieldErrors, FieldError{"account_type", "account_type must be 1-32 lowercase letters, digits or underscores"})
+		}
+		accountType = req.AccountType
 	}
 
-	var bal float64
-	if _, err := fmt.Sscanf(req.InitialBalance, "%f", &bal); err != nil || bal < 0 {
-		WriteErrorResponse(w, http.StatusBadRequest, "initial_balance must be a valid non-negative number")
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
 		return
 	}
 
-	if err := h.Service.CreateAccount(req.AccountID, req.InitialBalance); err != nil {
-		WriteErrorResponse(w, http.StatusBadRequest, "failed to create account: "+err.Error())
+	if err := h.Service.CreateAccount(req.AccountID, req.InitialBalance, currency, accountType, auditContext(r)); err != nil {
+		WriteError(w, r, err)
 		return
 	}
 	// If everything is successful, return a success response
-	WriteCreatedResponse(w, "Account created successfully")
+	WriteCreatedResponse(w, "account created successfully")
 }
 
 func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
@@ -60,15 +90,158 @@ func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
 	accountID, err := strconv.ParseInt(idStr, 10, 64)
 	// WriteErrorResponse is a convenience function for 400 Bad Request errors
 	if err != nil {
-		WriteErrorResponse(w, http.StatusBadRequest, "invalid account id: "+err.Error())
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: "+err.Error())
 		return
 	}
 
 	acc, err := h.Service.GetAccount(accountID)
 	if err != nil {
-		WriteErrorResponse(w, http.StatusNotFound, "account not found: "+err.Error())
+		WriteError(w, r, err)
 		return
 	}
 
 	WriteSuccessResponse(w, http.StatusOK, "Account retrieved successfully", acc)
 }
+
+// UpdateAccount changes an account's status: freeze, unfreeze or close.
+// System accounts, which have negative ids, cannot be changed.
+func (h *AccountHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["account_id"], 10, 64)
+	if err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: "+err.Error())
+		return
+	}
+	if accountID <= 0 {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: must be a positive integer")
+		return
+	}
+
+	var req struct {
+		Status           string `json:"status"`
+		Reason           string `json:"reason"`
+		AllowIncoming    bool   `json:"allow_incoming"`
+		SweepToAccountID int64  `json:"sweep_to_account_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	switch req.Status {
+	case models.AccountStatusActive, models.AccountStatusFrozen, models.AccountStatusClosed:
+	default:
+		fieldErrors = append(fieldErrors, FieldError{"status", "status must be active, frozen or closed"})
+	}
+	if !models.StatusReasons[req.Reason] {
+		fieldErrors = append(fieldErrors, FieldError{"reason", "reason must be a supported reason code"})
+	}
+	if req.AllowIncoming && req.Status != models.AccountStatusFrozen {
+		fieldErrors = append(fieldErrors, FieldError{"allow_incoming", "allow_incoming only applies to frozen accounts"})
+	}
+	if req.SweepToAccountID != 0 {
+		if req.Status != models.AccountStatusClosed {
+			fieldErrors = append(fieldErrors, FieldError{"sweep_to_account_id", "sweep_to_account_id only applies when closing"})
+		} else if req.SweepToAccountID < 0 || req.SweepToAccountID == accountID {
+			fieldErrors = append(fieldErrors, FieldError{"sweep_to_account_id", "sweep_to_account_id must be another account"})
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	acc, err := h.Service.UpdateAccountStatus(accountID, models.AccountStatusChange{
+		Status:           req.Status,
+		Reason:           req.Reason,
+		AllowIncoming:    req.AllowIncoming,
+		SweepToAccountID: req.SweepToAccountID,
+		Audit:            auditContext(r),
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "account updated successfully", acc)
+}
+
+// SetOverdraftLimit changes an account's overdraft limit. The reason, and
+// who made the change, are kept in the limit's audit trail. System
+// accounts cannot be given a limit.
+func (h *AccountHandler) SetOverdraftLimit(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["account_id"], 10, 64)
+	if err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: "+err.Error())
+		return
+	}
+	if accountID <= 0 {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: must be a positive integer")
+		return
+	}
+
+	var req struct {
+		Limit     string `json:"limit"`
+		Reason    string `json:"reason"`
+		ChangedBy string `json:"changed_by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	limit, err := models.NewMoneyFromString(req.Limit)
+	if err != nil || limit.IsNegative() {
+		fieldErrors = append(fieldErrors, FieldError{"limit", "limit must be a valid non-negative number"})
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		fieldErrors = append(fieldErrors, FieldError{"reason", "reason is required"})
+	}
+	if len(req.ChangedBy) > 255 {
+		fieldErrors = append(fieldErrors, FieldError{"changed_by", "changed_by must be at most 255 characters"})
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	acc, err := h.Service.SetOverdraftLimit(accountID, limit, req.Reason, req.ChangedBy, auditContext(r))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "overdraft limit updated successfully", acc)
+}
+
+func (h *AccountHandler) ListOverdraftLimitChanges(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["account_id"], 10, 64)
+	if err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: "+err.Error())
+		return
+	}
+
+	changes, err := h.Service.ListOverdraftLimitChanges(accountID)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "overdraft limit changes retrieved successfully", changes)
+}
+
+// ListOverdrawnAccounts reports every customer account below zero.
+func (h *AccountHandler) ListOverdrawnAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.Service.ListOverdrawnAccounts()
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "overdrawn accounts retrieved successfully", accounts)
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"transactions/models"
+	"transactions/service"
+)
+
+// Request headers recorded in the audit log. The router fills in
+// RequestIDHeader when a client does not send one.
+const (
+	ActorHeader     = "X-Actor"
+	RequestIDHeader = "X-Request-ID"
+)
+
+const maxAuditHeaderLength = 255
+
+type AuditHandler struct {
+	Service service.AuditServiceInterface
+}
+
+func NewAuditHandler(service service.AuditServiceInterface) *AuditHandler {
+	return &AuditHandler{Service: service}
+}
+
+// auditContext is who made r, for the audit log. The source IP is the peer
+// address; forwarding headers are not trusted.
+func auditContext(r *http.Request) models.AuditContext {
+	audit := models.AuditContext{
+		Actor:     truncate(r.Header.Get(ActorHeader), maxAuditHeaderLength),
+		RequestID: truncate(r.Header.Get(RequestIDHeader), maxAuditHeaderLength),
+		SourceIP:  r.RemoteAddr,
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		audit.SourceIP = host
+	}
+	if audit.Actor == "" {
+		audit.Actor = models.AuditActorAnonymous
+	}
+	return audit
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// ListAuditEntries returns audit log entries matching the query string,
+// newest first.
+func (h *AuditHandler) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		WriteBadRequestError(w, r, err.Error())
+		return
+	}
+
+	page, err := h.Service.ListAuditEntries(filter)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "audit entries retrieved successfully", page)
+}
+
+// VerifyAuditChain reports whether the audit log's hash chain is intact.
+func (h *AuditHandler) VerifyAuditChain(w http.ResponseWriter, r *http.Request) {
+	v, err := h.Service.VerifyAuditChain()
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "audit chain verified", v)
+}
+
+// parseAuditFilter reads the audit query string: actor, action,
+// entity_type, entity_id, request_id, from, to (RFC3339), cursor and limit.
+func parseAuditFilter(q url.Values) (models.AuditFilter, error) {
+	f := models.AuditFilter{
+		Actor:      q.Get("actor"),
+		Action:     q.Get("action"),
+		EntityType: q.Get("entity_type"),
+		EntityID:   q.Get("entity_id"),
+		RequestID:  q.Get("request_id"),
+	}
+	if f.EntityID != "" && f.EntityType == "" {
+		return f, errors.New("entity_id requires entity_type")
+	}
+
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, errors.New("from must be an RFC3339 timestamp")
+		}
+		f.From = &t
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, errors.New("to must be an RFC3339 timestamp")
+		}
+		f.To = &t
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return f, errors.New("from must be before to")
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		id, err := models.DecodeCursor(v)
+		if err != nil {
+			return f, err
+		}
+		f.Cursor = id
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > service.MaxTransactionPageSize {
+			return f, errors.New("limit must be between 1 and " + strconv.Itoa(service.MaxTransactionPageSize))
+		}
+		f.Limit = n
+	}
+
+	return f, nil
+}
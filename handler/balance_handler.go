@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// defaultBalanceHistoryDays is how many days of history are returned
+	// when no range is given
+	defaultBalanceHistoryDays = 30
+	// maxBalanceHistoryDays bounds the range of one history request
+	maxBalanceHistoryDays = 366
+)
+
+type BalanceHandler struct {
+	Service service.BalanceServiceInterface
+}
+
+func NewBalanceHandler(service service.BalanceServiceInterface) *BalanceHandler {
+	return &BalanceHandler{Service: service}
+}
+
+// GetBalance returns an account's balance now, or at the RFC3339 time in
+// the at query parameter.
+func (h *BalanceHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["account_id"], 10, 64)
+	if err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: "+err.Error())
+		return
+	}
+
+	now := time.Now()
+	at := now
+	if v := r.URL.Query().Get("at"); v != "" {
+		at, err = time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			WriteValidationErrors(w, r, []FieldError{{"at", "at must be an RFC3339 timestamp"}})
+			return
+		}
+		if at.After(now) {
+			WriteValidationErrors(w, r, []FieldError{{"at", "at must not be in the future"}})
+			return
+		}
+	}
+
+	b, err := h.Service.GetBalanceAt(accountID, at)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "balance retrieved successfully", b)
+}
+
+// ListBalanceHistory returns an account's end-of-day balances for the days
+// from through to (YYYY-MM-DD, UTC). It defaults to the last 30 days.
+func (h *BalanceHandler) ListBalanceHistory(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["account_id"], 10, 64)
+	if err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: "+err.Error())
+		return
+	}
+
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	q := r.URL.Query()
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(models.SnapshotDateFormat, v); err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"to", "to must be a date in YYYY-MM-DD format"})
+		}
+	}
+	from := to.AddDate(0, 0, -(defaultBalanceHistoryDays - 1))
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(models.SnapshotDateFormat, v); err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"from", "from must be a date in YYYY-MM-DD format"})
+		}
+	}
+	if len(fieldErrors) == 0 {
+		if from.After(to) {
+			fieldErrors = append(fieldErrors, FieldError{"from", "from must not be after to"})
+		} else if to.Sub(from) >= maxBalanceHistoryDays*24*time.Hour {
+			fieldErrors = append(fieldErrors, FieldError{"from", "the range must not exceed " + strconv.Itoa(maxBalanceHistoryDays) + " days"})
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	snapshots, err := h.Service.ListBalanceSnapshots(accountID, from, to)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "balance history retrieved successfully", snapshots)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"transactions/apperrors"
+	"transactions/config"
+)
+
+// validationErrorCode is reported when one or more request fields are invalid
+const validationErrorCode = "validation_failed"
+
+// WriteError maps err onto an HTTP status and error code. Domain errors from
+// apperrors keep their code and message; anything else is logged and reported
+// as a generic 500 so driver details never reach the client.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, message := errorStatus(err)
+	if status == http.StatusInternalServerError || status == http.StatusServiceUnavailable {
+		config.GetLogger().Printf("request failed: %v", err)
+	}
+	writeErrorResponse(w, r, status, code, message, nil)
+}
+
+func errorStatus(err error) (int, string, string) {
+	e, ok := apperrors.As(err)
+	if !ok {
+		return http.StatusInternalServerError, defaultErrorCode(http.StatusInternalServerError), "internal server error"
+	}
+	switch e.Kind {
+	case apperrors.KindInvalid:
+		return http.StatusBadRequest, e.Code, err.Error()
+	case apperrors.KindNotFound:
+		return http.StatusNotFound, e.Code, err.Error()
+	case apperrors.KindConflict:
+		return http.StatusConflict, e.Code, err.Error()
+	case apperrors.KindUnprocessable:
+		return http.StatusUnprocessableEntity, e.Code, err.Error()
+	case apperrors.KindUnavailable:
+		return http.StatusServiceUnavailable, e.Code, e.Message
+	default:
+		return http.StatusInternalServerError, e.Code, e.Message
+	}
+}
+
+// defaultErrorCode is the code used for errors raised directly by handlers,
+// such as request validation failures.
+func defaultErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusUnprocessableEntity:
+		return "unprocessable"
+	case http.StatusServiceUnavailable:
+		return "service_unavailable"
+	default:
+		return "internal_error"
+	}
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+// maxExternalFieldLength bounds external_reference and funding_source
+const maxExternalFieldLength = 255
+
+type ExternalTransferHandler struct {
+	Service service.ExternalTransferServiceInterface
+}
+
+func NewExternalTransferHandler(service service.ExternalTransferServiceInterface) *ExternalTransferHandler {
+	return &ExternalTransferHandler{Service: service}
+}
+
+func (h *ExternalTransferHandler) Deposit(w http.ResponseWriter, r *http.Request) {
+	t, ok := decodeExternalTransfer(w, r)
+	if !ok {
+		return
+	}
+
+	d, err := h.Service.Deposit(t, auditContext(r))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/external-transfers/"+strconv.FormatInt(d.ID, 10))
+	WriteSuccessResponse(w, http.StatusCreated, "deposit completed successfully", d)
+}
+
+func (h *ExternalTransferHandler) Withdraw(w http.ResponseWriter, r *http.Request) {
+	t, ok := decodeExternalTransfer(w, r)
+	if !ok {
+		return
+	}
+
+	wd, err := h.Service.Withdraw(t, auditContext(r))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/external-transfers/"+strconv.FormatInt(wd.ID, 10))
+	WriteSuccessResponse(w, http.StatusCreated, "withdrawal completed successfully", wd)
+}
+
+func (h *ExternalTransferHandler) GetExternalTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid external transfer id")
+		return
+	}
+
+	t, err := h.Service.GetExternalTransfer(id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "external transfer retrieved successfully", t)
+}
+
+// decodeExternalTransfer reads a deposit or withdrawal for the
+// {account_id} path variable, writing a 400 if it is invalid.
+func decodeExternalTransfer(w http.ResponseWriter, r *http.Request) (models.ExternalTransfer, bool) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["account_id"], 10, 64)
+	if err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: "+err.Error())
+		return models.ExternalTransfer{}, false
+	}
+	if accountID <= 0 {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: must be a positive integer")
+		return models.ExternalTransfer{}, false
+	}
+
+	var req struct {
+		Amount            string `json:"amount"`
+		Currency          string `json:"currency"`
+		ExternalReference string `json:"external_reference"`
+		FundingSource     string `json:"funding_source"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return models.ExternalTransfer{}, false
+	}
+
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	amount, err := models.NewMoneyFromString(req.Amount)
+	if err != nil || !amount.IsPositive() {
+		fieldErrors = append(fieldErrors, FieldError{"amount", "amount must be a valid positive number"})
+	}
+	var currency models.Currency
+	if req.Currency != "" {
+		c, err := models.ParseCurrency(req.Currency)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"currency", "currency must be a supported ISO 4217 code"})
+		}
+		currency = c
+	}
+	if req.ExternalReference == "" || len(req.ExternalReference) > maxExternalFieldLength {
+		fieldErrors = append(fieldErrors, FieldError{"external_reference", "external_reference is required and must be at most 255 characters"})
+	}
+	if req.FundingSource == "" || len(req.FundingSource) > maxExternalFieldLength {
+		fieldErrors = append(fieldErrors, FieldError{"funding_source", "funding_source is required and must be at most 255 characters"})
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return models.ExternalTransfer{}, false
+	}
+
+	return models.ExternalTransfer{
+		AccountID:         accountID,
+		Amount:            amount,
+		Currency:          currency,
+		ExternalReference: req.ExternalReference,
+		FundingSource:     req.FundingSource,
+	}, true
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+type FXHandler struct {
+	Service service.FXServiceInterface
+}
+
+func NewFXHandler(service service.FXServiceInterface) *FXHandler {
+	return &FXHandler{Service: service}
+}
+
+func (h *FXHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SourceCurrency      string `json:"source_currency"`
+		DestinationCurrency string `json:"destination_currency"`
+		Amount              string `json:"amount"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	source, err := models.ParseCurrency(req.SourceCurrency)
+	if err != nil {
+		fieldErrors = append(fieldErrors, FieldError{"source_currency", "source_currency must be a supported ISO 4217 code"})
+	}
+	dest, err := models.ParseCurrency(req.DestinationCurrency)
+	if err != nil {
+		fieldErrors = append(fieldErrors, FieldError{"destination_currency", "destination_currency must be a supported ISO 4217 code"})
+	}
+	if source != "" && source == dest {
+		fieldErrors = append(fieldErrors, FieldError{"destination_currency", "source_currency and destination_currency must differ"})
+	}
+
+	// Amount is optional; when given the quote is bound to it
+	var amount *models.Money
+	if req.Amount != "" {
+		m, err := models.NewMoneyFromString(req.Amount)
+		if err != nil || !m.IsPositive() {
+			fieldErrors = append(fieldErrors, FieldError{"amount", "amount must be a valid positive number"})
+		} else if source != "" {
+			if err := m.CheckScale(source); err != nil {
+				fieldErrors = append(fieldErrors, FieldError{"amount", err.Error()})
+			}
+		}
+		amount = &m
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	quote, err := h.Service.CreateQuote(source, dest, amount)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/fx/quotes/"+quote.ID)
+	WriteSuccessResponse(w, http.StatusCreated, "quote created successfully", quote)
+}
+
+func (h *FXHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
+	quote, err := h.Service.GetQuote(mux.Vars(r)["id"])
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "quote retrieved successfully", quote)
+}
@@ -7,11 +7,29 @@ import (
 type Handler struct {
 	Account     *AccountHandler
 	Transaction *TransactionHandler
+	FX          *FXHandler
+	Transfer    *PendingTransferHandler
+	Scheduled   *ScheduledTransferHandler
+	Standing    *StandingOrderHandler
+	External    *ExternalTransferHandler
+	Balance     *BalanceHandler
+	Statement   *StatementHandler
+	Audit       *AuditHandler
+	Webhook     *WebhookHandler
 }
 
-func NewHandler(accountService *service.AccountService, transactionService *service.TransactionService) *Handler {
+func NewHandler(accountService *service.AccountService, transactionService *service.TransactionService, fxService *service.FXService, pendingTransferService *service.PendingTransferService, scheduledTransferService *service.ScheduledTransferService, standingOrderService *service.StandingOrderService, externalTransferService *service.ExternalTransferService, balanceService *service.BalanceService, statementService *service.StatementService, auditService *service.AuditService, webhookService *service.WebhookService) *Handler {
 	return &Handler{
 		Account:     NewAccountHandler(accountService),
 		Transaction: NewTransactionHandler(transactionService),
+		FX:          NewFXHandler(fxService),
+		Transfer:    NewPendingTransferHandler(pendingTransferService),
+		Scheduled:   NewScheduledTransferHandler(scheduledTransferService),
+		Standing:    NewStandingOrderHandler(standingOrderService),
+		External:    NewExternalTransferHandler(externalTransferService),
+		Balance:     NewBalanceHandler(balanceService),
+		Statement:   NewStatementHandler(statementService),
+		Audit:       NewAuditHandler(auditService),
+		Webhook:     NewWebhookHandler(webhookService),
 	}
 }
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+type PendingTransferHandler struct {
+	Service service.PendingTransferServiceInterface
+}
+
+func NewPendingTransferHandler(service service.PendingTransferServiceInterface) *PendingTransferHandler {
+	return &PendingTransferHandler{Service: service}
+}
+
+func (h *PendingTransferHandler) AuthorizeTransfer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SourceAccountID      int64  `json:"source_account_id"`
+		DestinationAccountID int64  `json:"destination_account_id"`
+		Amount               string `json:"amount"`
+		Currency             string `json:"currency"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	if req.SourceAccountID <= 0 {
+		fieldErrors = append(fieldErrors, FieldError{"source_account_id", "source_account_id must be a positive integer"})
+	}
+	if req.DestinationAccountID <= 0 {
+		fieldErrors = append(fieldErrors, FieldError{"destination_account_id", "destination_account_id must be a positive integer"})
+	}
+	if req.SourceAccountID > 0 && req.SourceAccountID == req.DestinationAccountID {
+		fieldErrors = append(fieldErrors, FieldError{"destination_account_id", "source_account_id and destination_account_id must not be the same"})
+	}
+	amount, err := models.NewMoneyFromString(req.Amount)
+	if err != nil || !amount.IsPositive() {
+		fieldErrors = append(fieldErrors, FieldError{"amount", "amount must be a valid positive number"})
+	}
+	var currency models.Currency
+	if req.Currency != "" {
+		c, err := models.ParseCurrency(req.Currency)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"currency", "currency must be a supported ISO 4217 code"})
+		}
+		currency = c
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	p, err := h.Service.AuthorizeTransfer(models.TransferRequest{
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               amount,
+		Currency:             currency,
+		Audit:                auditContext(r),
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/transfers/"+strconv.FormatInt(p.ID, 10))
+	WriteSuccessResponse(w, http.StatusCreated, "transfer authorized successfully", p)
+}
+
+func (h *PendingTransferHandler) GetPendingTransfer(w http.ResponseWriter, r *http.Request) {
+	id, ok := pendingTransferID(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := h.Service.GetPendingTransfer(id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "transfer retrieved successfully", p)
+}
+
+// CaptureTransfer settles a pending transfer. The body is optional; without
+// an amount the full authorized amount is captured.
+func (h *PendingTransferHandler) CaptureTransfer(w http.ResponseWriter, r *http.Request) {
+	id, ok := pendingTransferID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Amount string `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	var amount *models.Money
+	if req.Amount != "" {
+		m, err := models.NewMoneyFromString(req.Amount)
+		if err != nil || !m.IsPositive() {
+			WriteValidationErrors(w, r, []FieldError{{"amount", "amount must be a valid positive number"}})
+			return
+		}
+		amount = &m
+	}
+
+	p, err := h.Service.CaptureTransfer(id, amount, auditContext(r))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "transfer captured successfully", p)
+}
+
+func (h *PendingTransferHandler) VoidTransfer(w http.ResponseWriter, r *http.Request) {
+	id, ok := pendingTransferID(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := h.Service.VoidTransfer(id, auditContext(r))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "transfer voided successfully", p)
+}
+
+// pendingTransferID parses the {id} path variable, writing a 400 if invalid
+func pendingTransferID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid transfer id")
+		return 0, false
+	}
+	return id, true
+}
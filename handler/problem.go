@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProblemContentType is the RFC 7807 media type. Clients opt in to it by
+// listing it in their Accept header.
+const ProblemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the error code to build a stable problem type URI
+const problemTypeBase = "urn:transactions:problem:"
+
+// Problem is an RFC 7807 problem details document. Code and Errors are
+// extension members carrying the same data as ErrorResponse.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, statusCode int, code, detail string, fieldErrors []FieldError) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(Problem{
+		Type:     problemTypeBase + code,
+		Title:    http.StatusText(statusCode),
+		Status:   statusCode,
+		Detail:   detail,
+		Instance: r.URL.RequestURI(),
+		Code:     code,
+		Errors:   fieldErrors,
+	})
+}
+
+// wantsProblemJSON reports whether the Accept header lists
+// application/problem+json with a non-zero quality.
+func wantsProblemJSON(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != ProblemContentType {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(q, 64); err != nil || v <= 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
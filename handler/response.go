@@ -3,12 +3,22 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
-// ErrorResponse represents a standard error response structure
+// ErrorResponse represents a standard error response structure.
+// Code is a stable machine-readable identifier; Error is for humans.
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Error   string `json:"error"`
+	Success bool         `json:"success"`
+	Code    string       `json:"code,omitempty"`
+	Error   string       `json:"error"`
+	Errors  []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes one invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 // SuccessResponse represents a standard success response structure
@@ -18,13 +28,35 @@ type SuccessResponse struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-// WriteErrorResponse writes a standardized error response
-func WriteErrorResponse(w http.ResponseWriter, statusCode int, errorMessage string) {
+// WriteErrorResponse writes a standardized error response with the generic
+// code for statusCode
+func WriteErrorResponse(w http.ResponseWriter, r *http.Request, statusCode int, errorMessage string) {
+	writeErrorResponse(w, r, statusCode, defaultErrorCode(statusCode), errorMessage, nil)
+}
+
+// WriteValidationErrors writes a 400 response listing every invalid field
+func WriteValidationErrors(w http.ResponseWriter, r *http.Request, fieldErrors []FieldError) {
+	messages := make([]string, len(fieldErrors))
+	for i, fe := range fieldErrors {
+		messages[i] = fe.Message
+	}
+	writeErrorResponse(w, r, http.StatusBadRequest, validationErrorCode, strings.Join(messages, "; "), fieldErrors)
+}
+
+// writeErrorResponse emits either the classic ErrorResponse or, when the
+// client asked for it, an RFC 7807 problem document.
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, statusCode int, code, errorMessage string, fieldErrors []FieldError) {
+	if wantsProblemJSON(r) {
+		writeProblem(w, r, statusCode, code, errorMessage, fieldErrors)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(ErrorResponse{
 		Success: false,
+		Code:    code,
 		Error:   errorMessage,
+		Errors:  fieldErrors,
 	})
 }
 
@@ -49,13 +81,13 @@ func WriteSuccessResponse(w http.ResponseWriter, statusCode int, message string,
 }
 
 // WriteBadRequestError is a convenience function for 400 Bad Request errors
-func WriteBadRequestError(w http.ResponseWriter, errorMessage string) {
-	WriteErrorResponse(w, http.StatusBadRequest, errorMessage)
+func WriteBadRequestError(w http.ResponseWriter, r *http.Request, errorMessage string) {
+	WriteErrorResponse(w, r, http.StatusBadRequest, errorMessage)
 }
 
 // WriteNotFoundError is a convenience function for 404 Not Found errors
-func WriteNotFoundError(w http.ResponseWriter, errorMessage string) {
-	WriteErrorResponse(w, http.StatusNotFound, errorMessage)
+func WriteNotFoundError(w http.ResponseWriter, r *http.Request, errorMessage string) {
+	WriteErrorResponse(w, r, http.StatusNotFound, errorMessage)
 }
 
 // WriteCreatedResponse is a convenience function for 201 Created responses
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+type ScheduledTransferHandler struct {
+	Service service.ScheduledTransferServiceInterface
+}
+
+func NewScheduledTransferHandler(service service.ScheduledTransferServiceInterface) *ScheduledTransferHandler {
+	return &ScheduledTransferHandler{Service: service}
+}
+
+func (h *ScheduledTransferHandler) CreateScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SourceAccountID      int64  `json:"source_account_id"`
+		DestinationAccountID int64  `json:"destination_account_id"`
+		Amount               string `json:"amount"`
+		Currency             string `json:"currency"`
+		AllowConversion      bool   `json:"allow_conversion"`
+		ExecuteAt            string `json:"execute_at"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	if req.SourceAccountID <= 0 {
+		fieldErrors = append(fieldErrors, FieldError{"source_account_id", "source_account_id must be a positive integer"})
+	}
+	if req.DestinationAccountID <= 0 {
+		fieldErrors = append(fieldErrors, FieldError{"destination_account_id", "destination_account_id must be a positive integer"})
+	}
+	if req.SourceAccountID > 0 && req.SourceAccountID == req.DestinationAccountID {
+		fieldErrors = append(fieldErrors, FieldError{"destination_account_id", "source_account_id and destination_account_id must not be the same"})
+	}
+	amount, err := models.NewMoneyFromString(req.Amount)
+	if err != nil || !amount.IsPositive() {
+		fieldErrors = append(fieldErrors, FieldError{"amount", "amount must be a valid positive number"})
+	}
+	var currency models.Currency
+	if req.Currency != "" {
+		c, err := models.ParseCurrency(req.Currency)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"currency", "currency must be a supported ISO 4217 code"})
+		}
+		currency = c
+	}
+	executeAt, err := time.Parse(time.RFC3339, req.ExecuteAt)
+	if err != nil {
+		fieldErrors = append(fieldErrors, FieldError{"execute_at", "execute_at must be an RFC3339 timestamp"})
+	} else if !executeAt.After(time.Now()) {
+		fieldErrors = append(fieldErrors, FieldError{"execute_at", "execute_at must be in the future"})
+	}
+	// Stored without a time zone, so the client's offset is applied here
+	executeAt = executeAt.UTC()
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	s, err := h.Service.CreateScheduledTransfer(models.TransferRequest{
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               amount,
+		Currency:             currency,
+		AllowConversion:      req.AllowConversion,
+	}, executeAt)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/scheduled-transfers/"+strconv.FormatInt(s.ID, 10))
+	WriteSuccessResponse(w, http.StatusCreated, "transfer scheduled successfully", s)
+}
+
+func (h *ScheduledTransferHandler) ListScheduledTransfers(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseScheduledTransferFilter(r.URL.Query())
+	if err != nil {
+		WriteBadRequestError(w, r, err.Error())
+		return
+	}
+
+	transfers, err := h.Service.ListScheduledTransfers(filter)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "scheduled transfers retrieved successfully", transfers)
+}
+
+func (h *ScheduledTransferHandler) GetScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	id, ok := scheduledTransferID(w, r)
+	if !ok {
+		return
+	}
+
+	s, err := h.Service.GetScheduledTransfer(id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "scheduled transfer retrieved successfully", s)
+}
+
+func (h *ScheduledTransferHandler) CancelScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	id, ok := scheduledTransferID(w, r)
+	if !ok {
+		return
+	}
+
+	s, err := h.Service.CancelScheduledTransfer(id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "scheduled transfer cancelled successfully", s)
+}
+
+// parseScheduledTransferFilter reads the listing query string: account_id,
+// status and limit.
+func parseScheduledTransferFilter(q url.Values) (models.ScheduledTransferFilter, error) {
+	var f models.ScheduledTransferFilter
+
+	if v := q.Get("account_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return f, errors.New("account_id must be a positive integer")
+		}
+		f.AccountID = id
+	}
+
+	switch s := q.Get("status"); s {
+	case "", models.ScheduledTransferScheduled, models.ScheduledTransferProcessing, models.ScheduledTransferExecuted,
+		models.ScheduledTransferFailed, models.ScheduledTransferCancelled:
+		f.Status = s
+	default:
+		return f, errors.New("status must be scheduled, processing, executed, failed or cancelled")
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > service.MaxTransactionPageSize {
+			return f, errors.New("limit must be between 1 and " + strconv.Itoa(service.MaxTransactionPageSize))
+		}
+		f.Limit = n
+	}
+
+	return f, nil
+}
+
+// scheduledTransferID parses the {id} path variable, writing a 400 if invalid
+func scheduledTransferID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid scheduled transfer id")
+		return 0, false
+	}
+	return id, true
+}
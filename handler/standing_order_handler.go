@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"transactions/models"
+	"transactions/recurrence"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+// Bounds on the retry policy a standing order may ask for
+const (
+	maxStandingOrderRetries    = 10
+	minStandingOrderRetryDelay = 60
+	defaultStandingOrderDelay  = 3600
+)
+
+type StandingOrderHandler struct {
+	Service service.StandingOrderServiceInterface
+}
+
+func NewStandingOrderHandler(service service.StandingOrderServiceInterface) *StandingOrderHandler {
+	return &StandingOrderHandler{Service: service}
+}
+
+func (h *StandingOrderHandler) CreateStandingOrder(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		SourceAccountID      int64  `json:"source_account_id"`
+		DestinationAccountID int64  `json:"destination_account_id"`
+		Amount               string `json:"amount"`
+		Currency             string `json:"currency"`
+		AllowConversion      bool   `json:"allow_conversion"`
+		Schedule             string `json:"schedule"`
+		StartAt              string `json:"start_at"`
+		EndsAt               string `json:"ends_at"`
+		OnInsufficientFunds  string `json:"on_insufficient_funds"`
+		MaxRetries           int    `json:"max_retries"`
+		RetryIntervalSeconds int    `json:"retry_interval_seconds"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	if req.SourceAccountID <= 0 {
+		fieldErrors = append(fieldErrors, FieldError{"source_account_id", "source_account_id must be a positive integer"})
+	}
+	if req.DestinationAccountID <= 0 {
+		fieldErrors = append(fieldErrors, FieldError{"destination_account_id", "destination_account_id must be a positive integer"})
+	}
+	if req.SourceAccountID > 0 && req.SourceAccountID == req.DestinationAccountID {
+		fieldErrors = append(fieldErrors, FieldError{"destination_account_id", "source_account_id and destination_account_id must not be the same"})
+	}
+	amount, err := models.NewMoneyFromString(req.Amount)
+	if err != nil || !amount.IsPositive() {
+		fieldErrors = append(fieldErrors, FieldError{"amount", "amount must be a valid positive number"})
+	}
+	var currency models.Currency
+	if req.Currency != "" {
+		c, err := models.ParseCurrency(req.Currency)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"currency", "currency must be a supported ISO 4217 code"})
+		}
+		currency = c
+	}
+	if _, err := recurrence.Parse(req.Schedule); err != nil {
+		fieldErrors = append(fieldErrors, FieldError{"schedule", "schedule must be a cron expression: " + err.Error()})
+	}
+
+	var startAt, endsAt *time.Time
+	if req.StartAt != "" {
+		t, err := time.Parse(time.RFC3339, req.StartAt)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"start_at", "start_at must be an RFC3339 timestamp"})
+		}
+		startAt = &t
+	}
+	if req.EndsAt != "" {
+		t, err := time.Parse(time.RFC3339, req.EndsAt)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"ends_at", "ends_at must be an RFC3339 timestamp"})
+		} else if !t.After(time.Now()) || (startAt != nil && !t.After(*startAt)) {
+			fieldErrors = append(fieldErrors, FieldError{"ends_at", "ends_at must be in the future and after start_at"})
+		}
+		t = t.UTC()
+		endsAt = &t
+	}
+
+	switch req.OnInsufficientFunds {
+	case "":
+		req.OnInsufficientFunds = models.InsufficientFundsSkip
+	case models.InsufficientFundsSkip, models.InsufficientFundsRetry:
+	default:
+		fieldErrors = append(fieldErrors, FieldError{"on_insufficient_funds", "on_insufficient_funds must be skip or retry"})
+	}
+	if req.MaxRetries < 0 || req.MaxRetries > maxStandingOrderRetries {
+		fieldErrors = append(fieldErrors, FieldError{"max_retries", "max_retries must be between 0 and " + strconv.Itoa(maxStandingOrderRetries)})
+	}
+	if req.RetryIntervalSeconds == 0 {
+		req.RetryIntervalSeconds = defaultStandingOrderDelay
+	} else if req.RetryIntervalSeconds < minStandingOrderRetryDelay {
+		fieldErrors = append(fieldErrors, FieldError{"retry_interval_seconds", "retry_interval_seconds must be at least " + strconv.Itoa(minStandingOrderRetryDelay)})
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	o, err := h.Service.CreateStandingOrder(models.StandingOrder{
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               amount,
+		Currency:             currency,
+		AllowConversion:      req.AllowConversion,
+		Schedule:             req.Schedule,
+		OnInsufficientFunds:  req.OnInsufficientFunds,
+		MaxRetries:           req.MaxRetries,
+		RetryIntervalSeconds: req.RetryIntervalSeconds,
+		EndsAt:               endsAt,
+	}, startAt)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/standing-orders/"+strconv.FormatInt(o.ID, 10))
+	WriteSuccessResponse(w, http.StatusCreated, "standing order created successfully", o)
+}
+
+func (h *StandingOrderHandler) ListStandingOrders(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStandingOrderFilter(r.URL.Query())
+	if err != nil {
+		WriteBadRequestError(w, r, err.Error())
+		return
+	}
+
+	orders, err := h.Service.ListStandingOrders(filter)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "standing orders retrieved successfully", orders)
+}
+
+func (h *StandingOrderHandler) GetStandingOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := standingOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	o, err := h.Service.GetStandingOrder(id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "standing order retrieved successfully", o)
+}
+
+func (h *StandingOrderHandler) PauseStandingOrder(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.Service.PauseStandingOrder, "standing order paused successfully")
+}
+
+func (h *StandingOrderHandler) ResumeStandingOrder(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.Service.ResumeStandingOrder, "standing order resumed successfully")
+}
+
+func (h *StandingOrderHandler) CancelStandingOrder(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.Service.CancelStandingOrder, "standing order cancelled successfully")
+}
+
+func (h *StandingOrderHandler) changeStatus(w http.ResponseWriter, r *http.Request, change func(int64) (*models.StandingOrder, error), message string) {
+	id, ok := standingOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	o, err := change(id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, message, o)
+}
+
+func (h *StandingOrderHandler) ListStandingOrderRuns(w http.ResponseWriter, r *http.Request) {
+	id, ok := standingOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > service.MaxTransactionPageSize {
+			WriteBadRequestError(w, r, "limit must be between 1 and "+strconv.Itoa(service.MaxTransactionPageSize))
+			return
+		}
+		limit = n
+	}
+
+	runs, err := h.Service.ListStandingOrderRuns(id, limit)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "standing order runs retrieved successfully", runs)
+}
+
+// parseStandingOrderFilter reads the listing query string: account_id,
+// status and limit.
+func parseStandingOrderFilter(q url.Values) (models.StandingOrderFilter, error) {
+	var f models.StandingOrderFilter
+
+	if v := q.Get("account_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return f, errors.New("account_id must be a positive integer")
+		}
+		f.AccountID = id
+	}
+
+	switch s := q.Get("status"); s {
+	case "", models.StandingOrderActive, models.StandingOrderPaused, models.StandingOrderCancelled, models.StandingOrderCompleted:
+		f.Status = s
+	default:
+		return f, errors.New("status must be active, paused, cancelled or completed")
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > service.MaxTransactionPageSize {
+			return f, errors.New("limit must be between 1 and " + strconv.Itoa(service.MaxTransactionPageSize))
+		}
+		f.Limit = n
+	}
+
+	return f, nil
+}
+
+// standingOrderID parses the {id} path variable, writing a 400 if invalid
+func standingOrderID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid standing order id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"transactions/config"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+type StatementHandler struct {
+	Service service.StatementServiceInterface
+}
+
+func NewStatementHandler(service service.StatementServiceInterface) *StatementHandler {
+	return &StatementHandler{Service: service}
+}
+
+// GetStatement streams an account's statement for the from and to query
+// parameters in the requested format: json (the default), csv or ofx.
+func (h *StatementHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["account_id"], 10, 64)
+	if err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: "+err.Error())
+		return
+	}
+
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	q := r.URL.Query()
+	from, err := parseStatementTime(q.Get("from"), false)
+	if err != nil {
+		fieldErrors = append(fieldErrors, FieldError{"from", "from must be a date (YYYY-MM-DD) or an RFC3339 timestamp"})
+	}
+	to, err := parseStatementTime(q.Get("to"), true)
+	if err != nil {
+		fieldErrors = append(fieldErrors, FieldError{"to", "to must be a date (YYYY-MM-DD) or an RFC3339 timestamp"})
+	}
+	if len(fieldErrors) == 0 && !to.After(from) {
+		fieldErrors = append(fieldErrors, FieldError{"to", "to must be after from"})
+	}
+	format := q.Get("format")
+	switch format {
+	case "":
+		format = models.StatementFormatJSON
+	case models.StatementFormatJSON, models.StatementFormatCSV, models.StatementFormatOFX:
+	default:
+		fieldErrors = append(fieldErrors, FieldError{"format", "format must be json, csv or ofx"})
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	sw := &trackedStatementWriter{StatementWriter: newStatementWriter(format, w)}
+	if err := h.Service.StreamStatement(accountID, from, to, sw); err != nil {
+		if !sw.opened {
+			WriteError(w, r, err)
+			return
+		}
+		// The status is already sent; cut the body short so the client
+		// sees an incomplete document rather than a truncated valid one
+		config.GetLogger().Printf("statement for account %d failed while streaming: %v", accountID, err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// parseStatementTime reads a statement bound. A date is the start of that
+// UTC day, or for the end bound the start of the next day, so both days
+// are included.
+func parseStatementTime(v string, end bool) (time.Time, error) {
+	if t, err := time.Parse(models.SnapshotDateFormat, v); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// trackedStatementWriter records whether the response has been started
+type trackedStatementWriter struct {
+	models.StatementWriter
+	opened bool
+}
+
+func (t *trackedStatementWriter) Open(s models.Statement) error {
+	t.opened = true
+	return t.StatementWriter.Open(s)
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"transactions/models"
+)
+
+// newStatementWriter returns the writer that streams a statement to w in
+// format. Nothing is written until its Open is called.
+func newStatementWriter(format string, w http.ResponseWriter) models.StatementWriter {
+	switch format {
+	case models.StatementFormatCSV:
+		return &csvStatementWriter{w: w}
+	case models.StatementFormatOFX:
+		return &ofxStatementWriter{w: w}
+	default:
+		return &jsonStatementWriter{w: w}
+	}
+}
+
+// statementFilename names the download of s in format
+func statementFilename(s models.Statement, format string) string {
+	return fmt.Sprintf("statement-%d-%s-%s.%s", s.AccountID, s.From.UTC().Format("20060102"), s.To.UTC().Format("20060102"), format)
+}
+
+// jsonStatementWriter writes the usual success envelope around the
+// statement, with its transactions as an array written one at a time.
+type jsonStatementWriter struct {
+	w     http.ResponseWriter
+	lines int
+}
+
+func (j *jsonStatementWriter) Open(s models.Statement) error {
+	j.w.Header().Set("Content-Type", "application/json")
+	j.w.WriteHeader(http.StatusOK)
+	header, err := json.Marshal(struct {
+		AccountID      int64           `json:"account_id"`
+		Currency       models.Currency `json:"currency"`
+		From           time.Time       `json:"from"`
+		To             time.Time       `json:"to"`
+		OpeningBalance models.Money    `json:"opening_balance"`
+	}{s.AccountID, s.Currency, s.From, s.To, s.OpeningBalance})
+	if err != nil {
+		return err
+	}
+	// Reopen the object to append the transactions and closing balance
+	_, err = fmt.Fprintf(j.w, `{"success":true,"message":"statement retrieved successfully","data":%s,"transactions":[`, header[:len(header)-1])
+	return err
+}
+
+func (j *jsonStatementWriter) Line(l models.StatementLine) error {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if j.lines > 0 {
+		if _, err := io.WriteString(j.w, ","); err != nil {
+			return err
+		}
+	}
+	j.lines++
+	_, err = j.w.Write(b)
+	return err
+}
+
+func (j *jsonStatementWriter) Close(s models.Statement) error {
+	closing, err := json.Marshal(s.ClosingBalance)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(j.w, `],"closing_balance":%s}}`+"\n", closing)
+	return err
+}
+
+// csvStatementWriter writes one row per transaction between an opening and
+// a closing balance row. Amounts are written to the currency's minor unit.
+type csvStatementWriter struct {
+	w        http.ResponseWriter
+	csv      *csv.Writer
+	currency models.Currency
+}
+
+func (c *csvStatementWriter) Open(s models.Statement) error {
+	c.currency = s.Currency
+	c.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	c.w.Header().Set("Content-Disposition", `attachment; filename="`+statementFilename(s, models.StatementFormatCSV)+`"`)
+	c.w.WriteHeader(http.StatusOK)
+	c.csv = csv.NewWriter(c.w)
+	c.csv.Write([]string{"date", "transaction_id", "description", "counterparty_account_id", "amount", "fee", "balance", "currency"})
+	return c.csv.Write([]string{s.From.UTC().Format(time.RFC3339), "", "Opening balance", "", "", "", c.amount(s.OpeningBalance), string(s.Currency)})
+}
+
+func (c *csvStatementWriter) Line(l models.StatementLine) error {
+	return c.csv.Write([]string{
+		l.CreatedAt.UTC().Format(time.RFC3339),
+		strconv.FormatInt(l.TransactionID, 10),
+		l.Description,
+		strconv.FormatInt(l.CounterpartyAccountID, 10),
+		c.amount(l.Amount),
+		c.amount(l.Fee),
+		c.amount(l.Balance),
+		string(c.currency),
+	})
+}
+
+func (c *csvStatementWriter) Close(s models.Statement) error {
+	c.csv.Write([]string{s.To.UTC().Format(time.RFC3339), "", "Closing balance", "", "", "", c.amount(s.ClosingBalance), string(s.Currency)})
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+func (c *csvStatementWriter) amount(m models.Money) string {
+	return m.StringFixed(c.currency.Scale())
+}
+
+// ofxBankID fills the BANKID OFX requires of a bank account
+const ofxBankID = "TRANSACTIONS"
+
+// ofxStatementWriter writes an OFX 2.2 bank statement. OFX has no opening
+// balance; the closing balance is the statement's LEDGERBAL.
+type ofxStatementWriter struct {
+	w        http.ResponseWriter
+	currency models.Currency
+}
+
+func (o *ofxStatementWriter) Open(s models.Statement) error {
+	o.currency = s.Currency
+	o.w.Header().Set("Content-Type", "application/x-ofx")
+	o.w.Header().Set("Content-Disposition", `attachment; filename="`+statementFilename(s, models.StatementFormatOFX)+`"`)
+	o.w.WriteHeader(http.StatusOK)
+	_, err := fmt.Fprintf(o.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS><CURDEF>%s</CURDEF>
+<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%d</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`, ofxTime(time.Now()), s.Currency, ofxBankID, s.AccountID, ofxTime(s.From), ofxTime(s.To))
+	return err
+}
+
+func (o *ofxStatementWriter) Line(l models.StatementLine) error {
+	trnType := "CREDIT"
+	if l.Direction == models.DirectionDebit {
+		trnType = "DEBIT"
+	}
+	if _, err := fmt.Fprintf(o.w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%d</FITID><MEMO>",
+		trnType, ofxTime(l.CreatedAt), l.Amount.StringFixed(o.currency.Scale()), l.TransactionID); err != nil {
+		return err
+	}
+	if err := xml.EscapeText(o.w, []byte(l.Description)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(o.w, "</MEMO></STMTTRN>\n")
+	return err
+}
+
+func (o *ofxStatementWriter) Close(s models.Statement) error {
+	_, err := fmt.Fprintf(o.w, `</BANKTRANLIST>
+<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`, s.ClosingBalance.StringFixed(o.currency.Scale()), ofxTime(s.To))
+	return err
+}
+
+// ofxTime formats t as an OFX datetime in UTC
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
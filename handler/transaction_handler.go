@@ -2,11 +2,25 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 	"transactions/models"
 	"transactions/service"
+
+	"github.com/gorilla/mux"
 )
 
+// IdempotencyKeyHeader lets clients retry POST /transactions safely.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
 type TransactionHandler struct {
 	Service service.TransactionServiceInterface
 }
@@ -16,41 +30,282 @@ func NewTransactionHandler(service service.TransactionServiceInterface) *Transac
 }
 
 func (h *TransactionHandler) SubmitTransaction(w http.ResponseWriter, r *http.Request) {
+	// Amount is decoded as a plain string so a malformed value is reported
+	// alongside the other invalid fields instead of failing the whole decode.
 	var req struct {
-		SourceAccountID      int64        `json:"source_account_id"`
-		DestinationAccountID int64        `json:"destination_account_id"`
-		Amount               models.Money `json:"amount"`
+		SourceAccountID      int64  `json:"source_account_id"`
+		DestinationAccountID int64  `json:"destination_account_id"`
+		Amount               string `json:"amount"`
+		Currency             string `json:"currency"`
+		AllowConversion      bool   `json:"allow_conversion"`
+		QuoteID              string `json:"quote_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		WriteErrorResponse(w, http.StatusBadRequest, "invalid request: could not decode JSON")
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
 		return
 	}
 
-	// Input validation
-	if req.SourceAccountID <= 0 || req.DestinationAccountID <= 0 {
-		WriteErrorResponse(w, http.StatusBadRequest, "source_account_id and destination_account_id must be positive integers")
-		return
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	if req.SourceAccountID <= 0 {
+		fieldErrors = append(fieldErrors, FieldError{"source_account_id", "source_account_id must be a positive integer"})
+	}
+	if req.DestinationAccountID <= 0 {
+		fieldErrors = append(fieldErrors, FieldError{"destination_account_id", "destination_account_id must be a positive integer"})
 	}
 
 	// Check if source and destination accounts are different
-	if req.SourceAccountID == req.DestinationAccountID {
-		WriteErrorResponse(w, http.StatusBadRequest, "source_account_id and destination_account_id must not be the same")
-		return
+	if req.SourceAccountID > 0 && req.SourceAccountID == req.DestinationAccountID {
+		fieldErrors = append(fieldErrors, FieldError{"destination_account_id", "source_account_id and destination_account_id must not be the same"})
 	}
 
 	// Check if amount is a valid positive number
-	if req.Amount.Decimal.LessThanOrEqual(models.Money{}.Decimal) {
-		WriteErrorResponse(w, http.StatusBadRequest, "amount must be a valid positive number")
+	amount, err := models.NewMoneyFromString(req.Amount)
+	if err != nil || !amount.IsPositive() {
+		fieldErrors = append(fieldErrors, FieldError{"amount", "amount must be a valid positive number"})
+	}
+
+	var currency models.Currency
+	if req.Currency != "" {
+		c, err := models.ParseCurrency(req.Currency)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{"currency", "currency must be a supported ISO 4217 code"})
+		}
+		currency = c
+	}
+
+	idempotencyKey := r.Header.Get(IdempotencyKeyHeader)
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		fieldErrors = append(fieldErrors, FieldError{IdempotencyKeyHeader, "Idempotency-Key must be at most 255 characters"})
+	}
+	for _, reserved := range []string{models.ScheduledIdempotencyKeyPrefix, models.StandingOrderIdempotencyKeyPrefix} {
+		if strings.HasPrefix(idempotencyKey, reserved) {
+			fieldErrors = append(fieldErrors, FieldError{IdempotencyKeyHeader, "Idempotency-Key must not start with " + reserved})
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
 		return
 	}
 
 	// Log the error for debugging purposes
-	if err := h.Service.SubmitTransaction(req.SourceAccountID, req.DestinationAccountID, req.Amount); err != nil {
-		WriteErrorResponse(w, http.StatusInternalServerError, "failed to submit transaction: "+err.Error())
+	t, err := h.Service.SubmitTransaction(models.TransferRequest{
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               amount,
+		Currency:             currency,
+		AllowConversion:      req.AllowConversion,
+		QuoteID:              req.QuoteID,
+		IdempotencyKey:       idempotencyKey,
+		Audit:                auditContext(r),
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	// If everything is successful, return the created transaction
+	w.Header().Set("Location", "/transactions/"+strconv.FormatInt(t.ID, 10))
+	WriteSuccessResponse(w, http.StatusCreated, "transaction submitted successfully", t)
+}
+
+func (h *TransactionHandler) SubmitBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Legs []struct {
+			SourceAccountID      int64  `json:"source_account_id"`
+			DestinationAccountID int64  `json:"destination_account_id"`
+			Amount               string `json:"amount"`
+		} `json:"legs"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	// Input validation, collecting every invalid field of every leg
+	var fieldErrors []FieldError
+	if len(req.Legs) == 0 || len(req.Legs) > service.MaxBatchLegs {
+		fieldErrors = append(fieldErrors, FieldError{"legs", fmt.Sprintf("legs must contain between 1 and %d transfers", service.MaxBatchLegs)})
+	}
+
+	legs := make([]models.TransferRequest, len(req.Legs))
+	for i, leg := range req.Legs {
+		prefix := fmt.Sprintf("legs[%d].", i)
+		if leg.SourceAccountID <= 0 {
+			fieldErrors = append(fieldErrors, FieldError{prefix + "source_account_id", prefix + "source_account_id must be a positive integer"})
+		}
+		if leg.DestinationAccountID <= 0 {
+			fieldErrors = append(fieldErrors, FieldError{prefix + "destination_account_id", prefix + "destination_account_id must be a positive integer"})
+		}
+		if leg.SourceAccountID > 0 && leg.SourceAccountID == leg.DestinationAccountID {
+			fieldErrors = append(fieldErrors, FieldError{prefix + "destination_account_id", prefix + "source_account_id and destination_account_id must not be the same"})
+		}
+		amount, err := models.NewMoneyFromString(leg.Amount)
+		if err != nil || !amount.IsPositive() {
+			fieldErrors = append(fieldErrors, FieldError{prefix + "amount", prefix + "amount must be a valid positive number"})
+		}
+		legs[i] = models.TransferRequest{
+			SourceAccountID:      leg.SourceAccountID,
+			DestinationAccountID: leg.DestinationAccountID,
+			Amount:               amount,
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	batch, err := h.Service.SubmitBatch(legs, auditContext(r))
+	if err != nil {
+		WriteError(w, r, err)
 		return
 	}
 
-	// If everything is successful, return a success response
-	WriteCreatedResponse(w, "Transaction submitted successfully")
+	WriteSuccessResponse(w, http.StatusCreated, "batch submitted successfully", batch)
+}
+
+func (h *TransactionHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid transaction id")
+		return
+	}
+
+	t, err := h.Service.GetTransaction(id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "transaction retrieved successfully", t)
+}
+
+// ReverseTransaction refunds a transaction in full or, when the optional body
+// carries an amount, in part.
+func (h *TransactionHandler) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid transaction id")
+		return
+	}
+
+	var req struct {
+		Amount string `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	var amount *models.Money
+	if req.Amount != "" {
+		m, err := models.NewMoneyFromString(req.Amount)
+		if err != nil || !m.IsPositive() {
+			WriteValidationErrors(w, r, []FieldError{{"amount", "amount must be a valid positive number"}})
+			return
+		}
+		amount = &m
+	}
+
+	t, err := h.Service.ReverseTransaction(id, amount, auditContext(r))
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/transactions/"+strconv.FormatInt(t.ID, 10))
+	WriteSuccessResponse(w, http.StatusCreated, "transaction reversed successfully", t)
+}
+
+func (h *TransactionHandler) ListAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(mux.Vars(r)["account_id"], 10, 64)
+	if err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid account id: "+err.Error())
+		return
+	}
+
+	filter, err := parseTransactionFilter(r.URL.Query())
+	if err != nil {
+		WriteBadRequestError(w, r, err.Error())
+		return
+	}
+
+	page, err := h.Service.ListAccountTransactions(accountID, filter)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "transactions retrieved successfully", page)
+}
+
+// parseTransactionFilter reads the history query string:
+// direction, min_amount, max_amount, from, to (RFC3339), cursor and limit.
+func parseTransactionFilter(q url.Values) (models.TransactionFilter, error) {
+	var f models.TransactionFilter
+
+	switch d := q.Get("direction"); d {
+	case "", models.DirectionDebit, models.DirectionCredit:
+		f.Direction = d
+	default:
+		return f, errors.New("direction must be debit or credit")
+	}
+
+	if v := q.Get("min_amount"); v != "" {
+		m, err := models.NewMoneyFromString(v)
+		if err != nil || m.IsNegative() {
+			return f, errors.New("min_amount must be a valid non-negative number")
+		}
+		f.MinAmount = &m
+	}
+	if v := q.Get("max_amount"); v != "" {
+		m, err := models.NewMoneyFromString(v)
+		if err != nil || m.IsNegative() {
+			return f, errors.New("max_amount must be a valid non-negative number")
+		}
+		f.MaxAmount = &m
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && f.MinAmount.GreaterThan(f.MaxAmount.Decimal) {
+		return f, errors.New("min_amount must not be greater than max_amount")
+	}
+
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, errors.New("from must be an RFC3339 timestamp")
+		}
+		f.CreatedFrom = &t
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return f, errors.New("to must be an RFC3339 timestamp")
+		}
+		f.CreatedTo = &t
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && !f.CreatedFrom.Before(*f.CreatedTo) {
+		return f, errors.New("from must be before to")
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		id, err := models.DecodeCursor(v)
+		if err != nil {
+			return f, err
+		}
+		f.Cursor = id
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > service.MaxTransactionPageSize {
+			return f, errors.New("limit must be between 1 and " + strconv.Itoa(service.MaxTransactionPageSize))
+		}
+		f.Limit = n
+	}
+
+	return f, nil
 }
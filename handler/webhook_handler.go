@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+// minWebhookSecretLength is the shortest secret a client may choose
+const minWebhookSecretLength = 16
+
+type WebhookHandler struct {
+	Service service.WebhookServiceInterface
+}
+
+func NewWebhookHandler(service service.WebhookServiceInterface) *WebhookHandler {
+	return &WebhookHandler{Service: service}
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL        string   `json:"url"`
+		Secret     string   `json:"secret"`
+		EventTypes []string `json:"event_types"`
+		AccountIDs []int64  `json:"account_ids"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	// Input validation, collecting every invalid field
+	var fieldErrors []FieldError
+	fieldErrors = append(fieldErrors, validateWebhookURL(req.URL)...)
+	fieldErrors = append(fieldErrors, validateWebhookEventTypes(req.EventTypes)...)
+	fieldErrors = append(fieldErrors, validateWebhookAccountIDs(req.AccountIDs)...)
+	if req.Secret != "" && len(req.Secret) < minWebhookSecretLength {
+		fieldErrors = append(fieldErrors, FieldError{"secret", "secret must be at least " + strconv.Itoa(minWebhookSecretLength) + " characters"})
+	}
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	sub, err := h.Service.CreateWebhook(models.WebhookSubscription{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		AccountIDs: req.AccountIDs,
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", "/webhooks/"+strconv.FormatInt(sub.ID, 10))
+	WriteSuccessResponse(w, http.StatusCreated, "webhook created successfully", sub)
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.Service.ListWebhooks()
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "webhooks retrieved successfully", subs)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookPathID(w, r, "invalid webhook id")
+	if !ok {
+		return
+	}
+
+	sub, err := h.Service.GetWebhook(id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "webhook retrieved successfully", sub)
+}
+
+// UpdateWebhook changes the fields present in the body. The secret cannot
+// be changed; create a new subscription to rotate it.
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookPathID(w, r, "invalid webhook id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		URL        *string  `json:"url"`
+		EventTypes []string `json:"event_types"`
+		AccountIDs []int64  `json:"account_ids"`
+		Active     *bool    `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteErrorResponse(w, r, http.StatusBadRequest, "invalid request: could not decode JSON")
+		return
+	}
+
+	if req.URL == nil && req.EventTypes == nil && req.AccountIDs == nil && req.Active == nil {
+		WriteBadRequestError(w, r, "at least one of url, event_types, account_ids or active is required")
+		return
+	}
+
+	var fieldErrors []FieldError
+	if req.URL != nil {
+		fieldErrors = append(fieldErrors, validateWebhookURL(*req.URL)...)
+	}
+	if req.EventTypes != nil {
+		fieldErrors = append(fieldErrors, validateWebhookEventTypes(req.EventTypes)...)
+	}
+	fieldErrors = append(fieldErrors, validateWebhookAccountIDs(req.AccountIDs)...)
+
+	if len(fieldErrors) > 0 {
+		WriteValidationErrors(w, r, fieldErrors)
+		return
+	}
+
+	sub, err := h.Service.UpdateWebhook(id, models.WebhookSubscriptionUpdate{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		AccountIDs: req.AccountIDs,
+		Active:     req.Active,
+	})
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "webhook updated successfully", sub)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookPathID(w, r, "invalid webhook id")
+	if !ok {
+		return
+	}
+
+	if err := h.Service.DeleteWebhook(id); err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "webhook deleted successfully", nil)
+}
+
+func (h *WebhookHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookPathID(w, r, "invalid webhook id")
+	if !ok {
+		return
+	}
+
+	filter, err := parseWebhookDeliveryFilter(r.URL.Query())
+	if err != nil {
+		WriteBadRequestError(w, r, err.Error())
+		return
+	}
+
+	deliveries, err := h.Service.ListWebhookDeliveries(id, filter)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "webhook deliveries retrieved successfully", deliveries)
+}
+
+func (h *WebhookHandler) GetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookPathID(w, r, "invalid webhook delivery id")
+	if !ok {
+		return
+	}
+
+	d, err := h.Service.GetWebhookDelivery(id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusOK, "webhook delivery retrieved successfully", d)
+}
+
+func (h *WebhookHandler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookPathID(w, r, "invalid webhook delivery id")
+	if !ok {
+		return
+	}
+
+	d, err := h.Service.RedeliverWebhook(id)
+	if err != nil {
+		WriteError(w, r, err)
+		return
+	}
+
+	WriteSuccessResponse(w, http.StatusAccepted, "webhook delivery queued for redelivery", d)
+}
+
+func validateWebhookURL(raw string) []FieldError {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return []FieldError{{"url", "url must be an absolute http or https URL"}}
+	}
+	return nil
+}
+
+func validateWebhookEventTypes(eventTypes []string) []FieldError {
+	if len(eventTypes) == 0 {
+		return []FieldError{{"event_types", "event_types must list at least one event type"}}
+	}
+	for _, t := range eventTypes {
+		known := false
+		for _, k := range models.WebhookEventTypes {
+			known = known || t == k
+		}
+		if !known {
+			return []FieldError{{"event_types", "unknown event type " + strconv.Quote(t)}}
+		}
+	}
+	return nil
+}
+
+func validateWebhookAccountIDs(accountIDs []int64) []FieldError {
+	for _, id := range accountIDs {
+		if id <= 0 {
+			return []FieldError{{"account_ids", "account_ids must be positive integers"}}
+		}
+	}
+	return nil
+}
+
+// parseWebhookDeliveryFilter reads the delivery listing query string:
+// status and limit.
+func parseWebhookDeliveryFilter(q url.Values) (models.WebhookDeliveryFilter, error) {
+	var f models.WebhookDeliveryFilter
+
+	switch s := q.Get("status"); s {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryDead:
+		f.Status = s
+	default:
+		return f, errors.New("status must be pending, delivered or dead")
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > service.MaxTransactionPageSize {
+			return f, errors.New("limit must be between 1 and " + strconv.Itoa(service.MaxTransactionPageSize))
+		}
+		f.Limit = n
+	}
+
+	return f, nil
+}
+
+// webhookPathID parses the {id} path variable, writing a 400 with message
+// if invalid
+func webhookPathID(w http.ResponseWriter, r *http.Request, message string) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		WriteErrorResponse(w, r, http.StatusBadRequest, message)
+		return 0, false
+	}
+	return id, true
+}
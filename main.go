@@ -1,10 +1,18 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"flag"
 	"net/http"
+	"os"
 	"transactions/config"
 	"transactions/db"
+	"transactions/events"
+	"transactions/fx"
 	"transactions/handler"
+	"transactions/models"
+	"transactions/payments"
 	"transactions/repository"
 	"transactions/router"
 	"transactions/service"
@@ -22,15 +30,160 @@ func main() {
 	}
 	defer db.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		code := reconcile(db, os.Args[2:])
+		db.Close()
+		os.Exit(code)
+	}
+
+	rates, err := fx.NewStaticRateProvider(nil)
+	if err != nil {
+		logger.Fatalf("failed to create rate provider: %v", err)
+	}
+	if cfg.FXRatesFile != "" {
+		if rates, err = fx.LoadRatesFile(cfg.FXRatesFile); err != nil {
+			logger.Fatalf("failed to load fx rates: %v", err)
+		}
+	}
+
 	accountRepo := repository.NewAccountRepository(db)
 	transactionRepo := repository.NewTransactionRepository(db)
+	fxRepo := repository.NewFXRepository(db)
+	pendingTransferRepo := repository.NewPendingTransferRepository(db)
+	limitRepo := repository.NewLimitRepository(db)
+	feeRepo := repository.NewFeeRepository(db)
+	scheduledTransferRepo := repository.NewScheduledTransferRepository(db)
+	standingOrderRepo := repository.NewStandingOrderRepository(db)
+	externalTransferRepo := repository.NewExternalTransferRepository(db)
+	balanceRepo := repository.NewBalanceRepository(db)
+	statementRepo := repository.NewStatementRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	reconciliationRepo := repository.NewReconciliationRepository(db)
+
+	var limitRules service.LimitRuleSource = limitRepo
+	if cfg.LimitRulesFile != "" {
+		if limitRules, err = service.LoadLimitRulesFile(cfg.LimitRulesFile); err != nil {
+			logger.Fatalf("failed to load limit rules: %v", err)
+		}
+	}
+
+	var feeSchedules service.FeeScheduleSource = feeRepo
+	if cfg.FeeSchedulesFile != "" {
+		if feeSchedules, err = service.LoadFeeSchedulesFile(cfg.FeeSchedulesFile); err != nil {
+			logger.Fatalf("failed to load fee schedules: %v", err)
+		}
+	}
+
+	var rail payments.PaymentRail
+	switch cfg.PaymentRail {
+	case "fake":
+		rail = payments.NewFakeRail()
+	default:
+		logger.Fatalf("unknown payment rail %q", cfg.PaymentRail)
+	}
+
+	var publisher events.Publisher
+	switch cfg.EventPublisher {
+	case "stdout":
+		publisher = events.NewStdoutPublisher()
+	case "file":
+		filePublisher, f, err := events.OpenFilePublisher(cfg.EventPublisherFile)
+		if err != nil {
+			logger.Fatalf("failed to open event publisher: %v", err)
+		}
+		defer f.Close()
+		publisher = filePublisher
+	default:
+		logger.Fatalf("unknown event publisher %q", cfg.EventPublisher)
+	}
+	if cfg.OutboxBatchSize <= 0 {
+		logger.Fatalf("OUTBOX_BATCH_SIZE must be positive")
+	}
+	if cfg.WebhookMaxAttempts <= 0 {
+		logger.Fatalf("WEBHOOK_MAX_ATTEMPTS must be positive")
+	}
+	if cfg.WebhookLease <= cfg.WebhookTimeout {
+		logger.Fatalf("WEBHOOK_LEASE must exceed WEBHOOK_TIMEOUT")
+	}
+
+	if cfg.FeeRevenueAccountID != 0 {
+		acc, err := accountRepo.GetAccount(cfg.FeeRevenueAccountID)
+		if err != nil {
+			logger.Fatalf("FEE_REVENUE_ACCOUNT_ID: %v", err)
+		}
+		if acc.Status != models.AccountStatusActive {
+			logger.Fatalf("FEE_REVENUE_ACCOUNT_ID: account %d is %s", acc.AccountID, acc.Status)
+		}
+	}
 
 	accountService := service.NewAccountService(accountRepo)
-	transactionService := service.NewTransactionService(transactionRepo)
+	fxService := service.NewFXService(fxRepo, rates, cfg.FXQuoteTTL)
+	limits := service.NewLimitEngine(limitRules, limitRepo)
+	fees := service.NewFeeEngine(feeSchedules, feeRepo, cfg.FeeRevenueAccountID)
+	transactionService := service.NewTransactionService(transactionRepo, fxService, limits, fees)
+	pendingTransferService := service.NewPendingTransferService(pendingTransferRepo, cfg.AuthorizationTTL, limits)
+	scheduledTransferService := service.NewScheduledTransferService(scheduledTransferRepo)
+	standingOrderService := service.NewStandingOrderService(standingOrderRepo)
+	externalTransferService := service.NewExternalTransferService(externalTransferRepo, rail, cfg.PaymentRailTimeout, limits)
+	balanceService := service.NewBalanceService(balanceRepo)
+	statementService := service.NewStatementService(statementRepo)
+	auditService := service.NewAuditService(auditRepo)
+	webhookService := service.NewWebhookService(webhookRepo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sweeper := service.NewIdempotencySweeper(transactionRepo, cfg.IdempotencyKeyTTL, cfg.IdempotencySweepInterval)
+	go sweeper.Run(ctx)
+
+	expirer := service.NewAuthorizationExpirer(pendingTransferRepo, cfg.AuthorizationExpiryInterval)
+	go expirer.Run(ctx)
+
+	scheduler := service.NewScheduledTransferWorker(scheduledTransferRepo, transactionService, cfg.ScheduledTransferInterval, cfg.ScheduledTransferLease)
+	go scheduler.Run(ctx)
+
+	standingOrders := service.NewStandingOrderWorker(standingOrderRepo, transactionService, cfg.ScheduledTransferInterval, cfg.ScheduledTransferLease)
+	go standingOrders.Run(ctx)
+
+	snapshotter := service.NewBalanceSnapshotter(balanceRepo, cfg.BalanceSnapshotInterval)
+	go snapshotter.Run(ctx)
+
+	reconciler := service.NewReconciler(reconciliationRepo, cfg.ReconciliationInterval)
+	go reconciler.Run(ctx)
+
+	// Every event is also turned into deliveries for the webhook worker
+	relay := service.NewOutboxRelay(outboxRepo, events.NewMultiPublisher(publisher, webhookService), cfg.OutboxRelayInterval, int(cfg.OutboxBatchSize), cfg.EventPublishTimeout)
+	go relay.Run(ctx)
 
-	h := handler.NewHandler(accountService, transactionService)
+	webhookClient := &http.Client{Timeout: cfg.WebhookTimeout}
+	webhooks := service.NewWebhookWorker(webhookRepo, webhookClient, cfg.WebhookInterval, cfg.WebhookLease, int(cfg.WebhookMaxAttempts), cfg.WebhookBackoffBase, cfg.WebhookBackoffMax)
+	go webhooks.Run(ctx)
+
+	h := handler.NewHandler(accountService, transactionService, fxService, pendingTransferService, scheduledTransferService, standingOrderService, externalTransferService, balanceService, statementService, auditService, webhookService)
 	r := router.NewRouter(h)
 
 	logger.Println("Server started at :8080")
 	logger.Fatal(http.ListenAndServe(":8080", r))
 }
+
+// reconcile runs "transactions reconcile [-record]": one reconciliation
+// whose findings are logged. It exits 0 when balanced, 1 on drift and 2 if
+// the reconciliation could not run.
+func reconcile(db *sql.DB, args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	record := flags.Bool("record", false, "store the findings in reconciliation_runs")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	report, err := service.NewReconciler(repository.NewReconciliationRepository(db), 0).Reconcile(*record)
+	if err != nil {
+		return 2
+	}
+	if !report.Balanced {
+		return 1
+	}
+	return 0
+}
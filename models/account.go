@@ -1,6 +1,68 @@
 package models
 
+import "time"
+
+// Account statuses. Frozen accounts cannot send money and receive it only
+// when AllowIncoming is set; closed accounts can do neither.
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+// StatusReasons are the reason codes accepted for a status change.
+var StatusReasons = map[string]bool{
+	"customer_request":  true,
+	"suspected_fraud":   true,
+	"compromised":       true,
+	"compliance_review": true,
+	"court_order":       true,
+	"dormant":           true,
+	"resolved":          true,
+	"other":             true,
+}
+
 type Account struct {
 	AccountID int64  `json:"account_id"`
 	Balance   string `json:"balance"`
+	// AvailableBalance is what the account can spend: Balance less the funds
+	// held by open authorizations, plus its OverdraftLimit
+	AvailableBalance string   `json:"available_balance"`
+	Currency         Currency `json:"currency"`
+	AccountType      string   `json:"account_type"`
+	OverdraftLimit   string   `json:"overdraft_limit"`
+	// InOverdraft is set while Balance is below zero
+	InOverdraft bool `json:"in_overdraft"`
+	// SystemCode identifies internal ledger accounts such as "equity:USD".
+	// It is empty for customer accounts.
+	SystemCode string `json:"system_code,omitempty"`
+
+	Status          string     `json:"status"`
+	StatusReason    string     `json:"status_reason,omitempty"`
+	AllowIncoming   bool       `json:"allow_incoming,omitempty"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+}
+
+// AccountStatusChange moves an account to Status. AllowIncoming only applies
+// to frozen accounts. Closing an account with money left in it requires
+// SweepToAccountID, which receives the remaining balance.
+type AccountStatusChange struct {
+	Status           string
+	Reason           string
+	AllowIncoming    bool
+	SweepToAccountID int64
+	Audit            AuditContext
+}
+
+// CanTransition reports whether an account may move from one status to
+// another. Closed is final.
+func CanTransition(from, to string) bool {
+	switch from {
+	case AccountStatusActive:
+		return to == AccountStatusFrozen || to == AccountStatusClosed
+	case AccountStatusFrozen:
+		return to == AccountStatusActive || to == AccountStatusFrozen || to == AccountStatusClosed
+	default:
+		return false
+	}
 }
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SnapshotDateFormat is how snapshot dates are written, e.g. "2025-01-31"
+const SnapshotDateFormat = "2006-01-02"
+
+// AccountBalance is an account's ledger balance as of At, counting every
+// posting made at or before it.
+type AccountBalance struct {
+	AccountID int64     `json:"account_id"`
+	Currency  Currency  `json:"currency"`
+	Balance   Money     `json:"balance"`
+	At        time.Time `json:"at"`
+}
+
+// BalanceSnapshot is an account's balance at the end of Date, a UTC day.
+type BalanceSnapshot struct {
+	AccountID int64  `json:"account_id"`
+	Date      string `json:"date"`
+	Balance   Money  `json:"balance"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency is an ISO 4217 alphabetic currency code.
+type Currency string
+
+const DefaultCurrency Currency = "USD"
+
+// currencyScales holds the number of minor-unit digits of each supported
+// currency, e.g. 2 for cents, 0 for yen.
+var currencyScales = map[Currency]int32{
+	"AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0,
+	"CNY": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2,
+	"HUF": 2, "INR": 2, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0,
+	"KWD": 3, "MXN": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PLN": 2,
+	"SEK": 2, "SGD": 2, "TND": 3, "TRY": 2, "USD": 2, "VND": 0,
+	"ZAR": 2,
+}
+
+// ParseCurrency validates and upper-cases an ISO 4217 code.
+func ParseCurrency(s string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(s)))
+	if _, ok := currencyScales[c]; !ok {
+		return "", fmt.Errorf("unsupported currency %q", s)
+	}
+	return c, nil
+}
+
+// Scale is the number of decimal places allowed for amounts in c.
+func (c Currency) Scale() int32 {
+	return currencyScales[c]
+}
+
+func (c Currency) String() string {
+	return string(c)
+}
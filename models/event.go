@@ -0,0 +1,36 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Domain event types written to the outbox
+const (
+	EventAccountCreated    = "AccountCreated"
+	EventTransferCompleted = "TransferCompleted"
+	EventTransferFailed    = "TransferFailed"
+)
+
+// Event is a domain event recorded in the outbox in the same DB transaction
+// as the change it describes. AccountIDs are the accounts it concerns;
+// events sharing an account are published in the order they were recorded.
+type Event struct {
+	ID         int64           `json:"id"`
+	Type       string          `json:"type"`
+	AccountIDs []int64         `json:"account_ids"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// TransferFailure is the payload of a TransferFailed event: the transfer
+// that was asked for and the error code it was rejected with.
+type TransferFailure struct {
+	SourceAccountID      int64    `json:"source_account_id"`
+	DestinationAccountID int64    `json:"destination_account_id"`
+	Amount               Money    `json:"amount"`
+	Currency             Currency `json:"currency,omitempty"`
+	IdempotencyKey       string   `json:"idempotency_key,omitempty"`
+	Code                 string   `json:"code"`
+	Reason               string   `json:"reason"`
+}
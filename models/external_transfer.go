@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	ExternalDeposit    = "deposit"
+	ExternalWithdrawal = "withdrawal"
+)
+
+const (
+	ExternalTransferPending   = "pending"
+	ExternalTransferCompleted = "completed"
+	ExternalTransferFailed    = "failed"
+)
+
+// ExternalTransfer moves money between an account and a funding source
+// outside the ledger over a payment rail. A deposit is credited once the
+// rail has collected the funds; a withdrawal is debited up front and
+// refunded by RefundTransactionID if the rail declines the payout.
+type ExternalTransfer struct {
+	ID                  int64     `json:"id"`
+	AccountID           int64     `json:"account_id"`
+	Direction           string    `json:"direction"`
+	Amount              Money     `json:"amount"`
+	Currency            Currency  `json:"currency"`
+	Rail                string    `json:"rail"`
+	FundingSource       string    `json:"funding_source"`
+	ExternalReference   string    `json:"external_reference"`
+	RailReference       string    `json:"rail_reference,omitempty"`
+	Status              string    `json:"status"`
+	FailureReason       string    `json:"failure_reason,omitempty"`
+	TransactionID       *int64    `json:"transaction_id,omitempty"`
+	RefundTransactionID *int64    `json:"refund_transaction_id,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// Fee schedule types
+const (
+	FeeFlat       = "flat"
+	FeePercentage = "percentage"
+	FeeTiered     = "tiered"
+)
+
+// DefaultAccountType is the type of accounts opened without one
+const DefaultAccountType = "standard"
+
+// FeeTier prices transfers up to UpTo (inclusive). The last tier may leave
+// UpTo unset to cover every larger amount.
+type FeeTier struct {
+	UpTo       *Money           `json:"up_to,omitempty"`
+	Flat       *Money           `json:"flat,omitempty"`
+	Percentage *decimal.Decimal `json:"percentage,omitempty"`
+}
+
+// FeeSchedule prices transfers from the accounts it applies to: one account
+// (AccountID), every account of a type (AccountType), or, with neither set,
+// all accounts. Percentages are fractions, so 0.015 is 1.5%.
+type FeeSchedule struct {
+	Name        string           `json:"name"`
+	Type        string           `json:"type"`
+	AccountID   int64            `json:"account_id,omitempty"`
+	AccountType string           `json:"account_type,omitempty"`
+	Currency    Currency         `json:"currency,omitempty"`
+	Flat        *Money           `json:"flat,omitempty"`
+	Percentage  *decimal.Decimal `json:"percentage,omitempty"`
+	Tiers       []FeeTier        `json:"tiers,omitempty"`
+	MinFee      *Money           `json:"min_fee,omitempty"`
+	MaxFee      *Money           `json:"max_fee,omitempty"`
+}
+
+// Fee prices a transfer of amount in currency, capped by MinFee and MaxFee
+// and rounded to the currency's minor unit.
+func (s FeeSchedule) Fee(amount Money, currency Currency) Money {
+	flat, pct := s.Flat, s.Percentage
+	if s.Type == FeeTiered {
+		flat, pct = nil, nil
+		for _, tier := range s.Tiers {
+			if tier.UpTo == nil || amount.LessThanOrEqual(tier.UpTo.Decimal) {
+				flat, pct = tier.Flat, tier.Percentage
+				break
+			}
+		}
+	}
+
+	fee := decimal.Zero
+	if flat != nil {
+		fee = fee.Add(flat.Decimal)
+	}
+	if pct != nil {
+		fee = fee.Add(amount.Mul(*pct))
+	}
+	if s.MinFee != nil && fee.LessThan(s.MinFee.Decimal) {
+		fee = s.MinFee.Decimal
+	}
+	if s.MaxFee != nil && fee.GreaterThan(s.MaxFee.Decimal) {
+		fee = s.MaxFee.Decimal
+	}
+	return Money{Decimal: fee.Round(currency.Scale())}
+}
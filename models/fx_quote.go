@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FXQuote locks an exchange rate until ExpiresAt. It can be redeemed by a
+// single transfer whose accounts match its currencies.
+type FXQuote struct {
+	ID                  string          `json:"id"`
+	SourceCurrency      Currency        `json:"source_currency"`
+	DestinationCurrency Currency        `json:"destination_currency"`
+	Rate                decimal.Decimal `json:"rate"`
+	// SourceAmount, when set, is the only amount the quote can be redeemed for
+	SourceAmount      *Money     `json:"source_amount,omitempty"`
+	DestinationAmount *Money     `json:"destination_amount,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         time.Time  `json:"expires_at"`
+	RedeemedAt        *time.Time `json:"redeemed_at,omitempty"`
+	TransactionID     *int64     `json:"transaction_id,omitempty"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Journal entry kinds
+const (
+	EntryKindOpeningBalance = "opening_balance"
+	EntryKindTransfer       = "transfer"
+	EntryKindReversal       = "reversal"
+	EntryKindDeposit        = "deposit"
+	EntryKindWithdrawal     = "withdrawal"
+	// EntryKindAccountSweep moves a closing account's balance out
+	EntryKindAccountSweep = "account_sweep"
+)
+
+// System account codes are prefixes completed with a currency, e.g.
+// "equity:USD".
+const (
+	SystemAccountEquity = "equity"
+	SystemAccountFX     = "fx"
+	SystemAccountFees   = "fees"
+	// SystemAccountWorld is the settlement account money enters and leaves
+	// the ledger through on deposits and withdrawals.
+	SystemAccountWorld = "world"
+	// SystemAccountSuspense holds deposits the rail collected for an
+	// account that was closed before they could be credited.
+	SystemAccountSuspense = "suspense"
+)
+
+// JournalEntry groups postings that move money atomically. The postings of
+// an entry always sum to zero per currency.
+type JournalEntry struct {
+	ID            int64     `json:"id"`
+	TransactionID *int64    `json:"transaction_id,omitempty"`
+	Kind          string    `json:"kind"`
+	CreatedAt     time.Time `json:"created_at"`
+	Postings      []Posting `json:"postings"`
+}
+
+// Posting is a signed movement on one account: negative amounts debit it,
+// positive amounts credit it.
+type Posting struct {
+	AccountID int64    `json:"account_id"`
+	Amount    Money    `json:"amount"`
+	Currency  Currency `json:"currency"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Limit rule types
+const (
+	LimitMaxAmount       = "max_amount"
+	LimitDailyOutgoing   = "daily_outgoing"
+	LimitMonthlyOutgoing = "monthly_outgoing"
+	LimitHourlyCount     = "hourly_count"
+)
+
+// LimitRule is a risk control on outgoing transfers. Amount rules use Amount
+// and count rules use MaxCount. A rule with an AccountID applies only to that
+// account, and one with a Currency only to accounts in that currency.
+type LimitRule struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	AccountID int64    `json:"account_id,omitempty"`
+	Currency  Currency `json:"currency,omitempty"`
+	Amount    *Money   `json:"amount,omitempty"`
+	MaxCount  int      `json:"max_count,omitempty"`
+}
+
+// OutgoingUsage is what an account has already sent in the windows the limit
+// rules look at: its transfers and withdrawals with their fees, and the
+// authorizations still holding funds. Reversals and the sweep of a closing
+// account are not counted. Amounts are in the account's currency.
+type OutgoingUsage struct {
+	Currency  Currency
+	Today     Money
+	ThisMonth Money
+	LastHour  int
+}
+
+// UsageWindows are the start times of the windows OutgoingUsage covers:
+// the current UTC day and month, and the last rolling hour.
+type UsageWindows struct {
+	DayStart   time.Time
+	MonthStart time.Time
+	HourStart  time.Time
+}
+
+// NewUsageWindows returns the usage windows as of now.
+func NewUsageWindows(now time.Time) UsageWindows {
+	now = now.UTC()
+	return UsageWindows{
+		DayStart:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		MonthStart: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		HourStart:  now.Add(-time.Hour),
+	}
+}
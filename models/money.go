@@ -35,3 +35,18 @@ func NewMoneyFromString(s string) (Money, error) {
 	}
 	return Money{d}, nil
 }
+
+// CheckScale reports an error when m has more decimal places than the minor
+// unit of c allows, e.g. 10.005 USD or 1.5 JPY. Trailing zeros are fine.
+func (m Money) CheckScale(c Currency) error {
+	scale := c.Scale()
+	if !m.Decimal.Equal(m.Decimal.Truncate(scale)) {
+		return fmt.Errorf("amount %s has more than %d decimal places for %s", m.String(), scale, c)
+	}
+	return nil
+}
+
+// Convert applies rate to m and rounds the result to the minor unit of to.
+func (m Money) Convert(rate decimal.Decimal, to Currency) Money {
+	return Money{m.Decimal.Mul(rate).Round(to.Scale())}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// OverdraftLimitChange records one change of an account's overdraft limit.
+type OverdraftLimitChange struct {
+	ID            int64     `json:"id"`
+	AccountID     int64     `json:"account_id"`
+	PreviousLimit Money     `json:"previous_limit"`
+	NewLimit      Money     `json:"new_limit"`
+	Reason        string    `json:"reason"`
+	ChangedBy     string    `json:"changed_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	PendingTransferPending  = "pending"
+	PendingTransferCaptured = "captured"
+	PendingTransferVoided   = "voided"
+	PendingTransferExpired  = "expired"
+)
+
+// PendingTransfer is an authorization: Amount is held on the source account
+// until it is captured, voided or expires. A capture may settle less than
+// the authorized amount; the remainder is released.
+type PendingTransfer struct {
+	ID                   int64     `json:"id"`
+	SourceAccountID      int64     `json:"source_account_id"`
+	DestinationAccountID int64     `json:"destination_account_id"`
+	Amount               Money     `json:"amount"`
+	Currency             Currency  `json:"currency"`
+	Status               string    `json:"status"`
+	CapturedAmount       *Money    `json:"captured_amount,omitempty"`
+	TransactionID        *int64    `json:"transaction_id,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+	ExpiresAt            time.Time `json:"expires_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// ReconciliationReport compares every account's cached balance with the
+// sum of its postings. Balanced means no account drifts and money is
+// conserved: in each currency both the postings and the cached balances
+// sum to zero.
+type ReconciliationReport struct {
+	RunID           *int64          `json:"run_id,omitempty"`
+	StartedAt       time.Time       `json:"started_at"`
+	FinishedAt      time.Time       `json:"finished_at"`
+	AccountsChecked int             `json:"accounts_checked"`
+	Drifts          []AccountDrift  `json:"drifts"`
+	Currencies      []CurrencyTotal `json:"currencies"`
+	Balanced        bool            `json:"balanced"`
+}
+
+// AccountDrift is an account whose cached Balance differs from
+// LedgerBalance, the sum of its postings. Drift is Balance less
+// LedgerBalance.
+type AccountDrift struct {
+	AccountID     int64    `json:"account_id"`
+	Currency      Currency `json:"currency"`
+	Balance       Money    `json:"balance"`
+	LedgerBalance Money    `json:"ledger_balance"`
+	Drift         Money    `json:"drift"`
+}
+
+// CurrencyTotal sums one currency across all accounts, system accounts
+// included. Both totals are zero when money is conserved.
+type CurrencyTotal struct {
+	Currency      Currency `json:"currency"`
+	BalancesTotal Money    `json:"balances_total"`
+	PostingsTotal Money    `json:"postings_total"`
+}
+
+// Conserved reports whether no money was created or destroyed in c's
+// currency.
+func (c CurrencyTotal) Conserved() bool {
+	return c.BalancesTotal.IsZero() && c.PostingsTotal.IsZero()
+}
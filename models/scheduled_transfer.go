@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	ScheduledTransferScheduled  = "scheduled"
+	ScheduledTransferProcessing = "processing"
+	ScheduledTransferExecuted   = "executed"
+	ScheduledTransferFailed     = "failed"
+	ScheduledTransferCancelled  = "cancelled"
+)
+
+// ScheduledIdempotencyKeyPrefix marks the idempotency keys under which
+// scheduled transfers are submitted. Clients may not use it.
+const ScheduledIdempotencyKeyPrefix = "scheduled-"
+
+// ScheduledTransfer is a transfer to be submitted at ExecuteAt. Once run it
+// references the recorded transaction or carries the reason it failed.
+type ScheduledTransfer struct {
+	ID                   int64     `json:"id"`
+	SourceAccountID      int64     `json:"source_account_id"`
+	DestinationAccountID int64     `json:"destination_account_id"`
+	Amount               Money     `json:"amount"`
+	Currency             Currency  `json:"currency,omitempty"`
+	AllowConversion      bool      `json:"allow_conversion"`
+	ExecuteAt            time.Time `json:"execute_at"`
+	Status               string    `json:"status"`
+	Attempts             int       `json:"attempts"`
+	TransactionID        *int64    `json:"transaction_id,omitempty"`
+	FailureCode          string    `json:"failure_code,omitempty"`
+	FailureReason        string    `json:"failure_reason,omitempty"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+
+	// SubmittedTransactionID is set on a claimed transfer when an earlier
+	// attempt already recorded its transaction but not the outcome.
+	SubmittedTransactionID *int64 `json:"-"`
+}
+
+// IdempotencyKey is the key the transfer is submitted under, so a retried
+// attempt can never apply it twice.
+func (s ScheduledTransfer) IdempotencyKey() string {
+	return ScheduledIdempotencyKeyPrefix + strconv.FormatInt(s.ID, 10)
+}
+
+// TransferRequest is the transfer to submit for s.
+func (s ScheduledTransfer) TransferRequest() TransferRequest {
+	return TransferRequest{
+		SourceAccountID:      s.SourceAccountID,
+		DestinationAccountID: s.DestinationAccountID,
+		Amount:               s.Amount,
+		Currency:             s.Currency,
+		AllowConversion:      s.AllowConversion,
+		IdempotencyKey:       s.IdempotencyKey(),
+		Audit:                AuditContext{Actor: AuditActorScheduledTransfers, RequestID: s.IdempotencyKey()},
+	}
+}
+
+// ScheduledTransferFilter narrows a listing of scheduled transfers. Zero
+// values mean "no filter".
+type ScheduledTransferFilter struct {
+	AccountID int64
+	Status    string
+	Limit     int
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	StandingOrderActive    = "active"
+	StandingOrderPaused    = "paused"
+	StandingOrderCancelled = "cancelled"
+	StandingOrderCompleted = "completed"
+)
+
+// What a standing order does when a run finds insufficient funds
+const (
+	InsufficientFundsSkip  = "skip"
+	InsufficientFundsRetry = "retry"
+)
+
+// Outcomes of a standing order run
+const (
+	StandingOrderRunExecuted = "executed"
+	StandingOrderRunRetrying = "retrying"
+	StandingOrderRunSkipped  = "skipped"
+	StandingOrderRunFailed   = "failed"
+)
+
+// StandingOrderIdempotencyKeyPrefix marks the idempotency keys standing
+// order runs are submitted under. Clients may not use it.
+const StandingOrderIdempotencyKeyPrefix = "standing-"
+
+// StandingOrder repeats a transfer on a cron Schedule, evaluated in UTC.
+// NextRunAt is the next occurrence; while a run short of funds is being
+// retried, RetryAt is when the next attempt is due and Retries counts the
+// attempts made so far.
+type StandingOrder struct {
+	ID                   int64      `json:"id"`
+	SourceAccountID      int64      `json:"source_account_id"`
+	DestinationAccountID int64      `json:"destination_account_id"`
+	Amount               Money      `json:"amount"`
+	Currency             Currency   `json:"currency,omitempty"`
+	AllowConversion      bool       `json:"allow_conversion"`
+	Schedule             string     `json:"schedule"`
+	OnInsufficientFunds  string     `json:"on_insufficient_funds"`
+	MaxRetries           int        `json:"max_retries"`
+	RetryIntervalSeconds int        `json:"retry_interval_seconds"`
+	Status               string     `json:"status"`
+	NextRunAt            time.Time  `json:"next_run_at"`
+	RetryAt              *time.Time `json:"retry_at,omitempty"`
+	Retries              int        `json:"retries"`
+	EndsAt               *time.Time `json:"ends_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+
+	// SubmittedTransactionID is set on a claimed order when an earlier
+	// attempt already recorded the run's transaction but not its outcome.
+	SubmittedTransactionID *int64 `json:"-"`
+}
+
+// IdempotencyKey is the key the run due at NextRunAt is submitted under, so
+// retries of one occurrence can never apply it twice.
+func (o StandingOrder) IdempotencyKey() string {
+	return fmt.Sprintf("%s%d-%s", StandingOrderIdempotencyKeyPrefix, o.ID, o.NextRunAt.UTC().Format("200601021504"))
+}
+
+// TransferRequest is the transfer one run of o submits.
+func (o StandingOrder) TransferRequest() TransferRequest {
+	return TransferRequest{
+		SourceAccountID:      o.SourceAccountID,
+		DestinationAccountID: o.DestinationAccountID,
+		Amount:               o.Amount,
+		Currency:             o.Currency,
+		AllowConversion:      o.AllowConversion,
+		IdempotencyKey:       o.IdempotencyKey(),
+		Audit:                AuditContext{Actor: AuditActorStandingOrders, RequestID: o.IdempotencyKey()},
+	}
+}
+
+// StandingOrderRun is one attempt at one occurrence of a standing order.
+type StandingOrderRun struct {
+	ID              int64     `json:"id"`
+	StandingOrderID int64     `json:"standing_order_id"`
+	ScheduledFor    time.Time `json:"scheduled_for"`
+	Attempt         int       `json:"attempt"`
+	Status          string    `json:"status"`
+	TransactionID   *int64    `json:"transaction_id,omitempty"`
+	FailureCode     string    `json:"failure_code,omitempty"`
+	FailureReason   string    `json:"failure_reason,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// StandingOrderFilter narrows a listing of standing orders. Zero values mean
+// "no filter".
+type StandingOrderFilter struct {
+	AccountID int64
+	Status    string
+	Limit     int
+}
+
+// CanChangeOrderStatus reports whether a standing order may move from one
+// status to another. Cancelled and completed are final.
+func CanChangeOrderStatus(from, to string) bool {
+	switch from {
+	case StandingOrderActive:
+		return to == StandingOrderPaused || to == StandingOrderCancelled
+	case StandingOrderPaused:
+		return to == StandingOrderActive || to == StandingOrderCancelled
+	default:
+		return false
+	}
+}
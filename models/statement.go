@@ -0,0 +1,100 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Statement formats
+const (
+	StatementFormatJSON = "json"
+	StatementFormatCSV  = "csv"
+	StatementFormatOFX  = "ofx"
+)
+
+// Statement covers an account's transactions made from From up to, but not
+// including, To. OpeningBalance is the balance at From and ClosingBalance
+// the balance at To.
+type Statement struct {
+	AccountID      int64     `json:"account_id"`
+	Currency       Currency  `json:"currency"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	OpeningBalance Money     `json:"opening_balance"`
+	ClosingBalance Money     `json:"closing_balance"`
+}
+
+// StatementLine is one transaction on a statement. Amount is signed like
+// AccountTransaction.SignedAmount and Balance is the running balance once
+// the transaction was applied. TransactionID is zero for an opening
+// balance, which is posted without a transaction.
+type StatementLine struct {
+	TransactionID         int64     `json:"transaction_id"`
+	CreatedAt             time.Time `json:"created_at"`
+	Direction             string    `json:"direction"`
+	CounterpartyAccountID int64     `json:"counterparty_account_id"`
+	Description           string    `json:"description"`
+	Amount                Money     `json:"amount"`
+	Fee                   Money     `json:"fee"`
+	Balance               Money     `json:"balance"`
+}
+
+// StatementWriter receives a statement as it is read: Open with the
+// opening balance, Line once per transaction, oldest first, then Close with
+// the closing balance set.
+type StatementWriter interface {
+	Open(s Statement) error
+	Line(l StatementLine) error
+	Close(s Statement) error
+}
+
+// NewStatementLine describes at with the running balance once it was
+// applied.
+func NewStatementLine(at AccountTransaction, balance Money) StatementLine {
+	l := StatementLine{
+		TransactionID: at.ID,
+		CreatedAt:     at.CreatedAt,
+		Direction:     at.Direction,
+		Amount:        at.SignedAmount,
+		Balance:       balance,
+	}
+	if at.Direction == DirectionDebit {
+		l.CounterpartyAccountID = at.DestinationAccountID
+		l.Fee = at.Fee
+	} else {
+		l.CounterpartyAccountID = at.SourceAccountID
+	}
+
+	// System accounts have negative ids; the only ones a customer transfers
+	// with directly are the world accounts of deposits and withdrawals
+	switch {
+	case at.ReversesTransactionID != nil:
+		l.Description = fmt.Sprintf("Reversal of transaction %d", *at.ReversesTransactionID)
+	case l.CounterpartyAccountID < 0 && at.Direction == DirectionCredit:
+		l.Description = "Deposit"
+	case l.CounterpartyAccountID < 0:
+		l.Description = "Withdrawal"
+	case at.Direction == DirectionDebit:
+		l.Description = fmt.Sprintf("Transfer to account %d", l.CounterpartyAccountID)
+	default:
+		l.Description = fmt.Sprintf("Transfer from account %d", l.CounterpartyAccountID)
+	}
+	return l
+}
+
+// NewOpeningBalanceLine describes the opening balance an account was
+// created with, credited from counterpartyID at createdAt.
+func NewOpeningBalanceLine(createdAt time.Time, counterpartyID int64, amount, balance Money) StatementLine {
+	l := StatementLine{
+		CreatedAt:             createdAt,
+		Direction:             DirectionCredit,
+		CounterpartyAccountID: counterpartyID,
+		Description:           "Opening balance",
+		Amount:                amount,
+		Balance:               balance,
+	}
+	if amount.IsNegative() {
+		l.Direction = DirectionDebit
+	}
+	return l
+}
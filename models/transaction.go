@@ -1,7 +1,162 @@
 package models
 
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DirectionDebit  = "debit"
+	DirectionCredit = "credit"
+)
+
+// Reversal states of a transaction that is not itself a reversal
+const (
+	ReversalStatusNone     = "none"
+	ReversalStatusPartial  = "partially_reversed"
+	ReversalStatusReversed = "reversed"
+)
+
+// Transaction is a recorded transfer. Amount is debited from the source in
+// Currency; DestinationAmount is credited in DestinationCurrency. The two
+// only differ for conversions, which also carry the applied FXRate.
 type Transaction struct {
-	SourceAccountID      int64 `json:"source_account_id"`
-	DestinationAccountID int64 `json:"destination_account_id"`
-	Amount               Money `json:"amount"`
+	ID                   int64            `json:"id"`
+	SourceAccountID      int64            `json:"source_account_id"`
+	DestinationAccountID int64            `json:"destination_account_id"`
+	Amount               Money            `json:"amount"`
+	Currency             Currency         `json:"currency"`
+	DestinationAmount    Money            `json:"destination_amount"`
+	DestinationCurrency  Currency         `json:"destination_currency"`
+	FXRate               *decimal.Decimal `json:"fx_rate,omitempty"`
+	FXQuoteID            string           `json:"fx_quote_id,omitempty"`
+	BatchID              *int64           `json:"batch_id,omitempty"`
+	CreatedAt            time.Time        `json:"created_at"`
+
+	// Fee is charged to the source on top of Amount, in Currency.
+	// TotalDebited is Amount plus Fee.
+	Fee          Money  `json:"fee"`
+	FeeSchedule  string `json:"fee_schedule,omitempty"`
+	TotalDebited Money  `json:"total_debited"`
+
+	// ReversesTransactionID is set on a reversal and names the transaction
+	// it compensates. A reversal moves money from the original destination
+	// back to the original source.
+	ReversesTransactionID *int64 `json:"reverses_transaction_id,omitempty"`
+	// ReversedAmount is how much of Amount has been reversed so far, and
+	// Reversals lists the reversing transactions, oldest first.
+	ReversedAmount *Money  `json:"reversed_amount,omitempty"`
+	ReversalStatus string  `json:"reversal_status,omitempty"`
+	Reversals      []int64 `json:"reversals,omitempty"`
+
+	// Balances of both accounts right after the transfer was applied.
+	SourceBalanceAfter      *Money `json:"source_balance_after,omitempty"`
+	DestinationBalanceAfter *Money `json:"destination_balance_after,omitempty"`
+}
+
+// TransferRequest is a transfer to be applied by SubmitTransaction.
+type TransferRequest struct {
+	SourceAccountID      int64
+	DestinationAccountID int64
+	Amount               Money
+	// Currency, when set, must match the source account's currency.
+	Currency Currency
+	// AllowConversion permits a transfer between accounts whose currencies
+	// differ. Without it such transfers are rejected.
+	AllowConversion bool
+	// QuoteID redeems a previously requested FX quote
+	QuoteID        string
+	IdempotencyKey string
+
+	// LockedQuoteID is a quote obtained by the service on the client's
+	// behalf. It is not part of the idempotency fingerprint.
+	LockedQuoteID string
+
+	// Fee is charged on top of Amount and credited to FeeAccountID, or to
+	// the fee revenue system account of the currency when that is zero. It
+	// is priced by the service and not part of the idempotency fingerprint.
+	Fee          Money
+	FeeSchedule  string
+	FeeAccountID int64
+
+	// Audit is recorded with the transfer. It is not part of the
+	// idempotency fingerprint.
+	Audit AuditContext
+}
+
+// TransferBatch is a set of transfers applied atomically: either every leg
+// is recorded or none is.
+type TransferBatch struct {
+	ID           int64         `json:"id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// AccountTransaction is a transaction seen from one of its two accounts.
+// SignedAmount is negative when money left the account, including any fee
+// the source paid, and BalanceAfter is
+// that account's balance once the transaction was applied.
+type AccountTransaction struct {
+	Transaction
+	Direction    string `json:"direction"`
+	SignedAmount Money  `json:"signed_amount"`
+	BalanceAfter *Money `json:"balance_after,omitempty"`
+}
+
+// NewAccountTransaction views t from accountID. The counterparty's balance is
+// dropped so history listings never expose another account's balance.
+func NewAccountTransaction(t Transaction, accountID int64) AccountTransaction {
+	at := AccountTransaction{Transaction: t}
+	if t.SourceAccountID == accountID {
+		at.Direction = DirectionDebit
+		at.SignedAmount = Money{Decimal: t.Amount.Add(t.Fee.Decimal).Neg()}
+		at.BalanceAfter = t.SourceBalanceAfter
+	} else {
+		at.Direction = DirectionCredit
+		at.SignedAmount = t.DestinationAmount
+		at.BalanceAfter = t.DestinationBalanceAfter
+	}
+	at.SourceBalanceAfter = nil
+	at.DestinationBalanceAfter = nil
+	return at
+}
+
+// TransactionFilter narrows an account's transaction history. Zero values
+// mean "no filter". Cursor is the id of the last transaction already seen.
+type TransactionFilter struct {
+	Direction   string
+	MinAmount   *Money
+	MaxAmount   *Money
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Cursor      int64
+	Limit       int
+}
+
+// TransactionPage is one page of an account's history, newest first.
+type TransactionPage struct {
+	Transactions []AccountTransaction `json:"transactions"`
+	NextCursor   string               `json:"next_cursor,omitempty"`
+}
+
+// EncodeCursor turns a transaction id into an opaque pagination cursor.
+func EncodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+// DecodeCursor reverses EncodeCursor.
+func DecodeCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("invalid cursor")
+	}
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return id, nil
 }
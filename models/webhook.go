@@ -0,0 +1,99 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Delivery states. A pending delivery is retried with exponential backoff
+// until it is delivered or runs out of attempts and becomes dead.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// WebhookEventTypes are the events partners can subscribe to
+var WebhookEventTypes = []string{EventAccountCreated, EventTransferCompleted, EventTransferFailed}
+
+// WebhookSubscription sends the events of EventTypes to URL. With
+// AccountIDs set only events concerning one of those accounts are sent.
+// Secret signs the deliveries; it is only returned when the subscription is
+// created.
+type WebhookSubscription struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	AccountIDs []int64   `json:"account_ids"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// WebhookSubscriptionUpdate changes the fields that are set.
+type WebhookSubscriptionUpdate struct {
+	URL        *string
+	EventTypes []string
+	AccountIDs []int64
+	Active     *bool
+}
+
+// WebhookDelivery is one event sent to one subscription. Attempts counts
+// the tries since it was created or last redelivered.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID int64           `json:"subscription_id"`
+	EventID        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	// AttemptLog is filled in when a single delivery is fetched
+	AttemptLog []WebhookAttempt `json:"attempt_log,omitempty"`
+
+	// URL and Secret of the subscription, for the delivery worker
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// WebhookAttempt is one HTTP request made for a delivery. StatusCode is
+// nil when no response was received.
+type WebhookAttempt struct {
+	ID          int64     `json:"id"`
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int      `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMS  int64     `json:"duration_ms"`
+}
+
+// WebhookDeliveryFilter narrows a subscription's deliveries
+type WebhookDeliveryFilter struct {
+	Status string
+	Limit  int
+}
+
+// WebhookSignature signs a delivery: the hex HMAC-SHA256, keyed with
+// secret, of the Unix timestamp, a dot and the body. It is sent as
+// "t=<timestamp>,v1=<signature>" so receivers can reject old deliveries.
+func WebhookSignature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,90 @@
+// Package payments connects deposits and withdrawals to the payment rails
+// that move money in and out of external funding sources.
+package payments
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"transactions/apperrors"
+	"transactions/models"
+)
+
+// Payment is one movement a rail is asked to make. Reference is stable
+// across retries of the same deposit or withdrawal, so a rail can use it to
+// avoid moving money twice.
+type Payment struct {
+	Reference     string
+	AccountID     int64
+	Amount        models.Money
+	Currency      models.Currency
+	FundingSource string
+}
+
+// PaymentRail moves money between the ledger and external funding sources.
+// Both calls return the rail's own reference for the payment. A rail
+// reports a definite refusal by wrapping apperrors.ErrPaymentDeclined; any
+// other error means the outcome is unknown.
+type PaymentRail interface {
+	Name() string
+	// Collect pulls a deposit from the funding source.
+	Collect(ctx context.Context, p Payment) (string, error)
+	// Payout pushes a withdrawal to the funding source.
+	Payout(ctx context.Context, p Payment) (string, error)
+}
+
+// FakeRail is an in-process rail for tests and local development. It
+// accepts every payment except those for declined funding sources, and
+// answers a repeated reference with the rail reference it first issued.
+type FakeRail struct {
+	mu       sync.Mutex
+	declined map[string]bool
+	issued   map[string]string
+	payments []Payment
+}
+
+func NewFakeRail() *FakeRail {
+	return &FakeRail{declined: map[string]bool{}, issued: map[string]string{}}
+}
+
+func (f *FakeRail) Name() string {
+	return "fake"
+}
+
+// Decline makes every later payment for fundingSource fail.
+func (f *FakeRail) Decline(fundingSource string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.declined[fundingSource] = true
+}
+
+// Payments returns the payments the rail has accepted, oldest first.
+func (f *FakeRail) Payments() []Payment {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Payment(nil), f.payments...)
+}
+
+func (f *FakeRail) Collect(ctx context.Context, p Payment) (string, error) {
+	return f.move("collect", p)
+}
+
+func (f *FakeRail) Payout(ctx context.Context, p Payment) (string, error) {
+	return f.move("payout", p)
+}
+
+func (f *FakeRail) move(op string, p Payment) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.declined[p.FundingSource] {
+		return "", fmt.Errorf("%w: funding source %s", apperrors.ErrPaymentDeclined, p.FundingSource)
+	}
+	key := op + ":" + p.Reference
+	if ref, ok := f.issued[key]; ok {
+		return ref, nil
+	}
+	f.payments = append(f.payments, p)
+	ref := fmt.Sprintf("fake-%s-%d", op, len(f.payments))
+	f.issued[key] = ref
+	return ref, nil
+}
@@ -0,0 +1,186 @@
+// Package recurrence parses the cron expressions standing orders repeat on
+// and computes their run times.
+package recurrence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSearchDays bounds how far ahead Next looks for a matching day
+const maxSearchDays = 5 * 366
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var dayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+// Schedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week. Each field is a bit set of the values it
+// matches.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// A restricted day of month and day of week match if either does, as
+	// in cron; with one of them "*" only the other applies.
+	domStar, dowStar bool
+}
+
+// Parse reads a cron expression such as "0 9 1 * *" (09:00 on the 1st of
+// every month) or "30 8 * * FRI". Fields accept "*", lists, ranges and
+// steps; months and weekdays also accept three-letter names, and 7 is
+// Sunday. "L" in the day of month means the last day. A day of month past
+// the end of a short month, such as 31 in April, falls on its last day.
+// The @yearly, @monthly, @weekly, @daily and @hourly shorthands are
+// recognized.
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if m, ok := macros[strings.ToLower(expr)]; ok {
+		expr = m
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	var s Schedule
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if s.dom, err = parseField(strings.ReplaceAll(strings.ToUpper(fields[2]), "L", "31"), 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+	s.domStar, s.dowStar = fields[2] == "*", fields[4] == "*"
+	return &s, nil
+}
+
+// parseField turns one cron field into a bit set of the values in
+// [min, max] it matches.
+func parseField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseValue(from, min, max, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseValue(to, min, max, names); err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("invalid range %q", rangePart)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("value %q must be between %d and %d", s, min, max)
+	}
+	return v, nil
+}
+
+// Next returns the first run time strictly after after, to the minute, in
+// after's location. It returns the zero time if nothing matches within
+// five years.
+func (s *Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	loc := t.Location()
+	for i := 0; i < maxSearchDays; i++ {
+		if s.dayMatches(t) {
+			for h := t.Hour(); h < 24; h++ {
+				if s.hour&(1<<uint(h)) == 0 {
+					continue
+				}
+				m := 0
+				if h == t.Hour() {
+					m = t.Minute()
+				}
+				for ; m < 60; m++ {
+					if s.minute&(1<<uint(m)) != 0 {
+						return time.Date(t.Year(), t.Month(), t.Day(), h, m, 0, 0, loc)
+					}
+				}
+			}
+		}
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	if s.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	domMatch := s.domMatches(t)
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// domMatches treats the last day of a month as every later day of month
+// the schedule names, so "31" runs on April 30th and February 28th.
+func (s *Schedule) domMatches(t time.Time) bool {
+	day := t.Day()
+	if s.dom&(1<<uint(day)) != 0 {
+		return true
+	}
+	last := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	return day == last && s.dom>>uint(last+1) != 0
+}
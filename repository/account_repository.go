@@ -2,12 +2,18 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
+	"transactions/apperrors"
 	"transactions/models"
 )
 
 type AccountRepositoryInterface interface {
-	CreateAccount(accountID int64, initialBalance string) error
+	CreateAccount(accountID int64, initialBalance string, currency models.Currency, accountType string, audit models.AuditContext) error
 	GetAccount(accountID int64) (*models.Account, error)
+	UpdateAccountStatus(accountID int64, change models.AccountStatusChange) (*models.Account, error)
+	SetOverdraftLimit(accountID int64, limit models.Money, reason, changedBy string, audit models.AuditContext) (*models.Account, error)
+	ListOverdraftLimitChanges(accountID int64) ([]models.OverdraftLimitChange, error)
+	ListOverdrawnAccounts() ([]models.Account, error)
 }
 
 type AccountRepository struct {
@@ -18,16 +24,292 @@ func NewAccountRepository(db *sql.DB) *AccountRepository {
 	return &AccountRepository{DB: db}
 }
 
-func (r *AccountRepository) CreateAccount(accountID int64, initialBalance string) error {
-	_, err := r.DB.Exec("INSERT INTO accounts (account_id, balance) VALUES ($1, $2)", accountID, initialBalance)
-	return err
+// CreateAccount opens an account. A non-zero initial balance is posted to
+// the ledger from the opening-balance equity account of its currency. The
+// new account is recorded in the audit log and an AccountCreated event is
+// added to the outbox.
+func (r *AccountRepository) CreateAccount(accountID int64, initialBalance string, currency models.Currency, accountType string, audit models.AuditContext) error {
+	err := r.createAccount(accountID, initialBalance, currency, accountType, audit)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: account %d", apperrors.ErrDuplicateAccount, accountID)
+	}
+	return translateError(err)
 }
 
+func (r *AccountRepository) createAccount(accountID int64, initialBalance string, currency models.Currency, accountType string, audit models.AuditContext) error {
+	opening, err := models.NewMoneyFromString(initialBalance)
+	if err != nil {
+		return fmt.Errorf("%w: %v", apperrors.ErrInvalidAmount, err)
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("INSERT INTO accounts (account_id, balance, currency, account_type) VALUES ($1, 0, $2, $3)", accountID, currency, accountType)
+	if err != nil {
+		return err
+	}
+
+	if !opening.IsZero() {
+		equityID, err := systemAccountID(tx, models.SystemAccountEquity, currency)
+		if err != nil {
+			return err
+		}
+		_, err = postJournalEntry(tx, models.EntryKindOpeningBalance, nil, []models.Posting{
+			debit(equityID, opening, currency),
+			credit(accountID, opening, currency),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	acc, err := scanAccount(tx.QueryRow("SELECT "+accountColumns+" FROM accounts WHERE account_id = $1", accountID))
+	if err != nil {
+		return err
+	}
+	if err := writeEvent(tx, models.EventAccountCreated, []int64{accountID}, acc); err != nil {
+		return err
+	}
+	if err := writeAudit(tx, audit, models.AuditActionAccountCreate, models.AuditEntityAccount, auditID(accountID), nil, acc); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+const accountColumns = "account_id, balance, balance - held_balance + overdraft_limit, currency, account_type, overdraft_limit, balance < 0 AND system_code IS NULL, system_code, status, status_reason, allow_incoming, status_changed_at"
+
 func (r *AccountRepository) GetAccount(accountID int64) (*models.Account, error) {
-	row := r.DB.QueryRow("SELECT account_id, balance FROM accounts WHERE account_id = $1", accountID)
+	acc, err := scanAccount(r.DB.QueryRow("SELECT "+accountColumns+" FROM accounts WHERE account_id = $1", accountID))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrAccountNotFound, "account %d", accountID)
+	}
+	return acc, nil
+}
+
+// UpdateAccountStatus applies a status transition. Closing an account that
+// still holds money sweeps the balance to change.SweepToAccountID in the same
+// DB transaction; an account with pending holds cannot be closed.
+func (r *AccountRepository) UpdateAccountStatus(accountID int64, change models.AccountStatusChange) (*models.Account, error) {
+	acc, err := withRetry(func() (*models.Account, error) {
+		return r.updateAccountStatus(accountID, change)
+	})
+	return acc, translateError(err)
+}
+
+func (r *AccountRepository) updateAccountStatus(accountID int64, change models.AccountStatusChange) (*models.Account, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := []int64{accountID}
+	if change.SweepToAccountID != 0 {
+		ids = append(ids, change.SweepToAccountID)
+	}
+	accounts, err := lockAccounts(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if err := accounts[id].checkNotSystem(id); err != nil {
+			return nil, err
+		}
+	}
+	acc := accounts[accountID]
+	before, err := scanAccount(tx.QueryRow("SELECT "+accountColumns+" FROM accounts WHERE account_id = $1", accountID))
+	if err != nil {
+		return nil, err
+	}
+	if !models.CanTransition(acc.Status, change.Status) {
+		return nil, fmt.Errorf("%w: account %d is %s", apperrors.ErrInvalidTransition, accountID, acc.Status)
+	}
+
+	var sweep *models.Transaction
+	if change.Status == models.AccountStatusClosed {
+		if !acc.Held.IsZero() {
+			return nil, fmt.Errorf("%w: account %d", apperrors.ErrAccountHasHolds, accountID)
+		}
+		if !acc.Balance.IsZero() {
+			if change.SweepToAccountID == 0 || acc.Balance.IsNegative() {
+				return nil, fmt.Errorf("%w: account %d holds %s %s", apperrors.ErrBalanceNotZero, accountID, acc.Balance, acc.Currency)
+			}
+			target := accounts[change.SweepToAccountID]
+			if target.Currency != acc.Currency {
+				return nil, fmt.Errorf("%w: account %d holds %s, sweep account %d holds %s", apperrors.ErrCurrencyMismatch, accountID, acc.Currency, change.SweepToAccountID, target.Currency)
+			}
+			if err := target.checkCanReceive(change.SweepToAccountID); err != nil {
+				return nil, err
+			}
+			// The sweep is the account's last debit, allowed even when frozen
+			sweep, err = postTransfer(tx, models.EntryKindAccountSweep, accountID, change.SweepToAccountID, models.Money{Decimal: acc.Balance}, acc.Currency, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	allowIncoming := change.Status == models.AccountStatusFrozen && change.AllowIncoming
+	updated, err := scanAccount(tx.QueryRow(`UPDATE accounts
+		SET status = $1, status_reason = $2, allow_incoming = $3, status_changed_at = NOW()
+		WHERE account_id = $4 RETURNING `+accountColumns,
+		change.Status, change.Reason, allowIncoming, accountID))
+	if err != nil {
+		return nil, err
+	}
+	if sweep != nil {
+		if err := writeAudit(tx, change.Audit, models.AuditActionTransactionCreate, models.AuditEntityTransaction, auditID(sweep.ID), nil, sweep); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeAudit(tx, change.Audit, models.AuditActionAccountStatus, models.AuditEntityAccount, auditID(accountID), before, updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// SetOverdraftLimit changes how far below zero an account may go and records
+// the change. Lowering the limit never moves money; an account already beyond
+// the new limit simply cannot be debited until it is back within it.
+func (r *AccountRepository) SetOverdraftLimit(accountID int64, limit models.Money, reason, changedBy string, audit models.AuditContext) (*models.Account, error) {
+	acc, err := withRetry(func() (*models.Account, error) {
+		return r.setOverdraftLimit(accountID, limit, reason, changedBy, audit)
+	})
+	return acc, translateError(err)
+}
+
+func (r *AccountRepository) setOverdraftLimit(accountID int64, limit models.Money, reason, changedBy string, audit models.AuditContext) (*models.Account, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	accounts, err := lockAccounts(tx, []int64{accountID})
+	if err != nil {
+		return nil, err
+	}
+	acc := accounts[accountID]
+	if err := acc.checkNotSystem(accountID); err != nil {
+		return nil, err
+	}
+	if acc.Status == models.AccountStatusClosed {
+		return nil, fmt.Errorf("%w: account %d", apperrors.ErrAccountClosed, accountID)
+	}
+	if err := limit.CheckScale(acc.Currency); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidAmount, err)
+	}
+	before, err := scanAccount(tx.QueryRow("SELECT "+accountColumns+" FROM accounts WHERE account_id = $1", accountID))
+	if err != nil {
+		return nil, err
+	}
+
+	var changedByArg interface{}
+	if changedBy != "" {
+		changedByArg = changedBy
+	}
+	_, err = tx.Exec(`INSERT INTO overdraft_limit_changes (account_id, previous_limit, new_limit, reason, changed_by)
+		VALUES ($1, $2, $3, $4, $5)`, accountID, acc.Overdraft.String(), limit.String(), reason, changedByArg)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := scanAccount(tx.QueryRow("UPDATE accounts SET overdraft_limit = $1 WHERE account_id = $2 RETURNING "+accountColumns,
+		limit.String(), accountID))
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAudit(tx, audit, models.AuditActionAccountOverdraftLimit, models.AuditEntityAccount, auditID(accountID), before, updated); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// ListOverdraftLimitChanges returns an account's limit changes, oldest first.
+func (r *AccountRepository) ListOverdraftLimitChanges(accountID int64) ([]models.OverdraftLimitChange, error) {
+	changes, err := r.listOverdraftLimitChanges(accountID)
+	return changes, translateError(err)
+}
+
+func (r *AccountRepository) listOverdraftLimitChanges(accountID int64) ([]models.OverdraftLimitChange, error) {
+	var exists bool
+	if err := r.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM accounts WHERE account_id = $1)", accountID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: account %d", apperrors.ErrAccountNotFound, accountID)
+	}
+
+	rows, err := r.DB.Query(`SELECT id, account_id, previous_limit, new_limit, reason, changed_by, created_at
+		FROM overdraft_limit_changes WHERE account_id = $1 ORDER BY id`, accountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.OverdraftLimitChange{}
+	for rows.Next() {
+		var c models.OverdraftLimitChange
+		var changedBy sql.NullString
+		if err := rows.Scan(&c.ID, &c.AccountID, &c.PreviousLimit.Decimal, &c.NewLimit.Decimal, &c.Reason, &changedBy, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.ChangedBy = changedBy.String
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// ListOverdrawnAccounts returns the customer accounts whose balance is
+// currently below zero.
+func (r *AccountRepository) ListOverdrawnAccounts() ([]models.Account, error) {
+	accounts, err := r.listOverdrawnAccounts()
+	return accounts, translateError(err)
+}
+
+func (r *AccountRepository) listOverdrawnAccounts() ([]models.Account, error) {
+	rows, err := r.DB.Query("SELECT " + accountColumns + " FROM accounts WHERE balance < 0 AND system_code IS NULL ORDER BY account_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []models.Account{}
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, *acc)
+	}
+	return accounts, rows.Err()
+}
+
+func scanAccount(row rowScanner) (*models.Account, error) {
 	var acc models.Account
-	if err := row.Scan(&acc.AccountID, &acc.Balance); err != nil {
+	var systemCode, reason sql.NullString
+	var changedAt sql.NullTime
+	if err := row.Scan(&acc.AccountID, &acc.Balance, &acc.AvailableBalance, &acc.Currency, &acc.AccountType, &acc.OverdraftLimit, &acc.InOverdraft, &systemCode,
+		&acc.Status, &reason, &acc.AllowIncoming, &changedAt); err != nil {
 		return nil, err
 	}
+	acc.SystemCode = systemCode.String
+	acc.StatusReason = reason.String
+	if changedAt.Valid {
+		acc.StatusChangedAt = &changedAt.Time
+	}
 	return &acc, nil
 }
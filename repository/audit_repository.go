@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"transactions/models"
+)
+
+// auditLogLockKey is the advisory lock that serializes appends to the audit
+// log, so every entry links to the one committed before it.
+const auditLogLockKey = 7301
+
+const auditColumns = "id, occurred_at, actor, request_id, source_ip, action, entity_type, entity_id, before, after, prev_hash, hash"
+
+type AuditRepositoryInterface interface {
+	ListAuditEntries(filter models.AuditFilter) ([]models.AuditEntry, error)
+	VerifyAuditChain() (*models.AuditVerification, error)
+}
+
+type AuditRepository struct {
+	DB *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{DB: db}
+}
+
+// writeAudit appends an entry for a change made in tx. before and after are
+// snapshots of the entity, nil when it did not exist. Call it once the
+// change is otherwise complete: the chain lock it takes is held until tx
+// ends, so anything done after it delays every other audited write.
+func writeAudit(tx *sql.Tx, audit models.AuditContext, action, entityType, entityID string, before, after interface{}) error {
+	e := models.AuditEntry{
+		OccurredAt: time.Now().UTC().Truncate(time.Microsecond),
+		Actor:      audit.Actor,
+		RequestID:  audit.RequestID,
+		SourceIP:   audit.SourceIP,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	if e.Actor == "" {
+		e.Actor = models.AuditActorAnonymous
+	}
+	var err error
+	if e.Before, err = auditSnapshot(before); err != nil {
+		return err
+	}
+	if e.After, err = auditSnapshot(after); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", auditLogLockKey); err != nil {
+		return err
+	}
+	err = tx.QueryRow("SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1").Scan(&e.PrevHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		e.PrevHash = models.AuditGenesisHash
+	} else if err != nil {
+		return err
+	}
+	e.Hash = e.ComputeHash()
+
+	_, err = tx.Exec(`INSERT INTO audit_log (occurred_at, actor, request_id, source_ip, action, entity_type, entity_id, before, after, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		e.OccurredAt, e.Actor, e.RequestID, e.SourceIP, e.Action, e.EntityType, e.EntityID,
+		nullJSON(e.Before), nullJSON(e.After), e.PrevHash, e.Hash)
+	return err
+}
+
+// auditID is the entity id of an account or transaction
+func auditID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+func auditSnapshot(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("audit snapshot: %w", err)
+	}
+	return b, nil
+}
+
+func nullJSON(b json.RawMessage) interface{} {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
+
+// ListAuditEntries returns up to filter.Limit entries, newest first,
+// starting after filter.Cursor.
+func (r *AuditRepository) ListAuditEntries(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	entries, err := r.listAuditEntries(filter)
+	return entries, translateError(err)
+}
+
+func (r *AuditRepository) listAuditEntries(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	var args []interface{}
+	conds := []string{"TRUE"}
+	addCond := func(expr string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(expr, len(args)))
+	}
+	if filter.Cursor > 0 {
+		addCond("id < $%d", filter.Cursor)
+	}
+	if filter.Actor != "" {
+		addCond("actor = $%d", filter.Actor)
+	}
+	if filter.Action != "" {
+		addCond("action = $%d", filter.Action)
+	}
+	if filter.EntityType != "" {
+		addCond("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		addCond("entity_id = $%d", filter.EntityID)
+	}
+	if filter.RequestID != "" {
+		addCond("request_id = $%d", filter.RequestID)
+	}
+	if filter.From != nil {
+		addCond("occurred_at >= $%d::timestamp", filter.From.UTC())
+	}
+	if filter.To != nil {
+		addCond("occurred_at < $%d::timestamp", filter.To.UTC())
+	}
+	args = append(args, filter.Limit)
+
+	query := fmt.Sprintf("SELECT "+auditColumns+" FROM audit_log WHERE %s ORDER BY id DESC LIMIT $%d", strings.Join(conds, " AND "), len(args))
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, rows.Err()
+}
+
+// VerifyAuditChain recomputes every entry's hash, oldest first, and checks
+// that each links to its predecessor. Rows removed from the end of the log
+// leave a valid chain; compare LastHash with one kept elsewhere to detect
+// that.
+func (r *AuditRepository) VerifyAuditChain() (*models.AuditVerification, error) {
+	v, err := r.verifyAuditChain()
+	return v, translateError(err)
+}
+
+func (r *AuditRepository) verifyAuditChain() (*models.AuditVerification, error) {
+	tx, err := r.DB.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT " + auditColumns + " FROM audit_log ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	v := &models.AuditVerification{Valid: true}
+	prevHash := models.AuditGenesisHash
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		v.EntriesChecked++
+		if e.PrevHash != prevHash || e.ComputeHash() != e.Hash {
+			v.Valid = false
+			v.FirstInvalidID = &e.ID
+			return v, nil
+		}
+		prevHash = e.Hash
+		v.LastHash = e.Hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return v, tx.Commit()
+}
+
+func scanAuditEntry(row rowScanner) (*models.AuditEntry, error) {
+	var e models.AuditEntry
+	var before, after []byte
+	if err := row.Scan(&e.ID, &e.OccurredAt, &e.Actor, &e.RequestID, &e.SourceIP, &e.Action, &e.EntityType, &e.EntityID,
+		&before, &after, &e.PrevHash, &e.Hash); err != nil {
+		return nil, err
+	}
+	if before != nil {
+		e.Before = json.RawMessage(before)
+	}
+	if after != nil {
+		e.After = json.RawMessage(after)
+	}
+	return &e, nil
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+)
+
+type BalanceRepositoryInterface interface {
+	GetBalanceAt(accountID int64, at time.Time) (*models.AccountBalance, error)
+	ListBalanceSnapshots(accountID int64, from, to time.Time) ([]models.BalanceSnapshot, error)
+	NextSnapshotDate() (*time.Time, error)
+	SnapshotBalances(day time.Time) (int64, error)
+}
+
+type BalanceRepository struct {
+	DB *sql.DB
+}
+
+func NewBalanceRepository(db *sql.DB) *BalanceRepository {
+	return &BalanceRepository{DB: db}
+}
+
+// GetBalanceAt returns an account's balance as of at: its latest snapshot
+// taken by then plus the postings made after the snapshot, up to and
+// including at. Without a snapshot every posting up to at is added up.
+func (r *BalanceRepository) GetBalanceAt(accountID int64, at time.Time) (*models.AccountBalance, error) {
+	b := models.AccountBalance{AccountID: accountID, At: at}
+	var err error
+	b.Currency, b.Balance, err = balanceAt(r.DB, accountID, at, "<=")
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrAccountNotFound, "account %d", accountID)
+	}
+	return &b, nil
+}
+
+// queryRower is implemented by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// balanceAt adds up an account's postings whose time compares to at by op,
+// "<" or "<=", starting from the latest snapshot that ended by at.
+func balanceAt(q queryRower, accountID int64, at time.Time, op string) (models.Currency, models.Money, error) {
+	var currency models.Currency
+	var balance models.Money
+	err := q.QueryRow(`SELECT a.currency, COALESCE(s.balance, 0) + COALESCE((
+			SELECT SUM(p.amount) FROM postings p
+			WHERE p.account_id = a.account_id
+				AND p.created_at >= COALESCE(s.snapshot_date + 1, '-infinity'::timestamp)
+				AND p.created_at `+op+` $2::timestamp
+		), 0)
+		FROM accounts a
+		LEFT JOIN LATERAL (
+			SELECT snapshot_date, balance FROM balance_snapshots
+			WHERE account_id = a.account_id AND snapshot_date + 1 <= $2::timestamp
+			ORDER BY snapshot_date DESC LIMIT 1
+		) s ON TRUE
+		WHERE a.account_id = $1`, accountID, at.UTC()).Scan(&currency, &balance.Decimal)
+	return currency, balance, err
+}
+
+// ListBalanceSnapshots returns an account's end-of-day balances for the
+// days from through to, oldest first. Days before the account's first
+// posting have no snapshot.
+func (r *BalanceRepository) ListBalanceSnapshots(accountID int64, from, to time.Time) ([]models.BalanceSnapshot, error) {
+	var exists bool
+	err := r.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM accounts WHERE account_id = $1)", accountID).Scan(&exists)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if !exists {
+		return nil, notFound(sql.ErrNoRows, apperrors.ErrAccountNotFound, "account %d", accountID)
+	}
+
+	rows, err := r.DB.Query(`SELECT snapshot_date, balance FROM balance_snapshots
+		WHERE account_id = $1 AND snapshot_date BETWEEN $2::date AND $3::date
+		ORDER BY snapshot_date`,
+		accountID, from.Format(models.SnapshotDateFormat), to.Format(models.SnapshotDateFormat))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	snapshots := []models.BalanceSnapshot{}
+	for rows.Next() {
+		s := models.BalanceSnapshot{AccountID: accountID}
+		var date time.Time
+		if err := rows.Scan(&date, &s.Balance.Decimal); err != nil {
+			return nil, translateError(err)
+		}
+		s.Date = date.Format(models.SnapshotDateFormat)
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, translateError(rows.Err())
+}
+
+// NextSnapshotDate returns the first day without snapshots: the day after
+// the latest snapshot, or the day of the first posting when there are none
+// yet. It returns nil while the ledger is empty.
+func (r *BalanceRepository) NextSnapshotDate() (*time.Time, error) {
+	var next sql.NullTime
+	err := r.DB.QueryRow(`SELECT COALESCE(
+		(SELECT MAX(snapshot_date) + 1 FROM balance_snapshots),
+		(SELECT MIN(created_at)::date FROM postings))`).Scan(&next)
+	if err != nil || !next.Valid {
+		return nil, translateError(err)
+	}
+	return &next.Time, nil
+}
+
+// SnapshotBalances records the end-of-day balance on day of every account
+// with postings by then. Each balance is the previous snapshot plus the
+// postings since, so history is scanned once however many days are taken.
+// Days already taken are left as they are. It returns how many snapshots
+// were written.
+func (r *BalanceRepository) SnapshotBalances(day time.Time) (int64, error) {
+	res, err := r.DB.Exec(`INSERT INTO balance_snapshots (account_id, snapshot_date, balance)
+		SELECT a.account_id, $1::date, COALESCE(prev.balance, 0) + COALESCE(delta.amount, 0)
+		FROM accounts a
+		LEFT JOIN LATERAL (
+			SELECT snapshot_date, balance FROM balance_snapshots
+			WHERE account_id = a.account_id AND snapshot_date < $1::date
+			ORDER BY snapshot_date DESC LIMIT 1
+		) prev ON TRUE
+		LEFT JOIN LATERAL (
+			SELECT SUM(p.amount) AS amount FROM postings p
+			WHERE p.account_id = a.account_id
+				AND p.created_at >= COALESCE(prev.snapshot_date + 1, '-infinity'::timestamp)
+				AND p.created_at < $1::date + 1
+		) delta ON TRUE
+		WHERE prev.balance IS NOT NULL OR delta.amount IS NOT NULL
+		ON CONFLICT (account_id, snapshot_date) DO NOTHING`, day.Format(models.SnapshotDateFormat))
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return res.RowsAffected()
+}
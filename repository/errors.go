@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"transactions/apperrors"
+
+	"github.com/lib/pq"
+)
+
+// Postgres SQLSTATE codes the repositories translate into domain errors.
+const (
+	pqUniqueViolation           = "23505"
+	pqForeignKeyViolation       = "23503"
+	pqNumericValueOutOfRange    = "22003"
+	pqInvalidTextRepresentation = "22P02"
+)
+
+// translateError maps driver-level failures onto apperrors so the handlers
+// never see lib/pq types. Errors that are already domain errors, and nil,
+// pass through unchanged.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := apperrors.As(err); ok {
+		return err
+	}
+	if isUnavailable(err) {
+		return fmt.Errorf("%w: %w", apperrors.ErrDatabaseUnavailable, err)
+	}
+	if isRetryable(err) {
+		return fmt.Errorf("%w: %w", apperrors.ErrConcurrentUpdate, err)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqForeignKeyViolation:
+			return fmt.Errorf("%w: %s", apperrors.ErrAccountNotFound, pqErr.Detail)
+		case pqNumericValueOutOfRange, pqInvalidTextRepresentation:
+			return fmt.Errorf("%w: %s", apperrors.ErrInvalidAmount, pqErr.Message)
+		}
+	}
+	return err
+}
+
+// notFound turns sql.ErrNoRows into target and translates anything else.
+func notFound(err error, target error, format string, args ...interface{}) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: "+format, append([]interface{}{target}, args...)...)
+	}
+	return translateError(err)
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation
+}
+
+// isUnavailable reports whether err means the database could not be reached
+// rather than that the statement itself failed.
+func isUnavailable(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "53", "57": // connection exception, insufficient resources, operator intervention
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
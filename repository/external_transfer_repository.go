@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"transactions/apperrors"
+	"transactions/models"
+)
+
+type ExternalTransferRepositoryInterface interface {
+	CreateDeposit(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error)
+	CreateWithdrawal(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error)
+	CompleteExternalTransfer(id int64, railReference string, audit models.AuditContext) (*models.ExternalTransfer, error)
+	FailExternalTransfer(id int64, reason string, audit models.AuditContext) (*models.ExternalTransfer, error)
+	GetExternalTransfer(id int64) (*models.ExternalTransfer, error)
+	FindExternalTransfer(direction, externalReference string) (*models.ExternalTransfer, error)
+}
+
+type ExternalTransferRepository struct {
+	DB *sql.DB
+}
+
+func NewExternalTransferRepository(db *sql.DB) *ExternalTransferRepository {
+	return &ExternalTransferRepository{DB: db}
+}
+
+const externalTransferColumns = "id, account_id, direction, amount, currency, rail, funding_source, external_reference, rail_reference, status, failure_reason, transaction_id, refund_transaction_id, created_at, updated_at"
+
+// CreateDeposit records a pending deposit into an account that may receive
+// money. Nothing is posted until the rail has collected the funds. A
+// repeated external reference returns the deposit it first created.
+func (r *ExternalTransferRepository) CreateDeposit(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	d, err := withRetry(func() (*models.ExternalTransfer, error) {
+		return r.createExternalTransfer(t, audit)
+	})
+	return d, translateError(err)
+}
+
+// CreateWithdrawal records a pending withdrawal and debits it to the world
+// account straight away, so the funds cannot be spent while the payout is
+// in flight. A repeated external reference returns the withdrawal it first
+// created.
+func (r *ExternalTransferRepository) CreateWithdrawal(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	w, err := withRetry(func() (*models.ExternalTransfer, error) {
+		return r.createExternalTransfer(t, audit)
+	})
+	return w, translateError(err)
+}
+
+func (r *ExternalTransferRepository) createExternalTransfer(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	if !t.Amount.IsPositive() {
+		return nil, apperrors.ErrInvalidAmount
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	accounts, err := lockAccounts(tx, []int64{t.AccountID})
+	if err != nil {
+		return nil, err
+	}
+	acc := accounts[t.AccountID]
+	// Money only enters and leaves the ledger's own accounts as a side
+	// effect of other movements
+	if err := acc.checkNotSystem(t.AccountID); err != nil {
+		return nil, err
+	}
+
+	// A retry of a recorded transfer is answered before any check that may
+	// have changed since, such as the balance it already spent
+	created, err := scanExternalTransfer(tx.QueryRow(`INSERT INTO external_transfers (account_id, direction, amount, currency, rail, funding_source, external_reference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (direction, external_reference) DO NOTHING RETURNING `+externalTransferColumns,
+		t.AccountID, t.Direction, t.Amount.String(), acc.Currency, t.Rail, t.FundingSource, t.ExternalReference))
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, err := scanExternalTransfer(tx.QueryRow("SELECT "+externalTransferColumns+" FROM external_transfers WHERE direction = $1 AND external_reference = $2",
+			t.Direction, t.ExternalReference))
+		if err != nil {
+			return nil, err
+		}
+		if existing.AccountID != t.AccountID || !existing.Amount.Equal(t.Amount.Decimal) || existing.FundingSource != t.FundingSource ||
+			(t.Currency != "" && t.Currency != existing.Currency) {
+			return nil, fmt.Errorf("%w: %s %q", apperrors.ErrExternalReferenceReused, t.Direction, t.ExternalReference)
+		}
+		return existing, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Currency != "" && t.Currency != acc.Currency {
+		return nil, fmt.Errorf("%w: amount is in %s but account %d holds %s", apperrors.ErrCurrencyMismatch, t.Currency, t.AccountID, acc.Currency)
+	}
+	if err := t.Amount.CheckScale(acc.Currency); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidAmount, err)
+	}
+
+	var posted *models.Transaction
+	if t.Direction == models.ExternalDeposit {
+		if err := acc.checkCanReceive(t.AccountID); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := acc.checkCanSend(t.AccountID); err != nil {
+			return nil, err
+		}
+		if acc.Available().LessThan(t.Amount.Decimal) {
+			return nil, fmt.Errorf("%w: account %d", apperrors.ErrInsufficientFunds, t.AccountID)
+		}
+		worldID, err := systemAccountID(tx, models.SystemAccountWorld, acc.Currency)
+		if err != nil {
+			return nil, err
+		}
+		posted, err = postTransfer(tx, models.EntryKindWithdrawal, t.AccountID, worldID, t.Amount, acc.Currency, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		created, err = scanExternalTransfer(tx.QueryRow("UPDATE external_transfers SET transaction_id = $1 WHERE id = $2 RETURNING "+externalTransferColumns,
+			posted.ID, created.ID))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if posted != nil {
+		if err := writeAudit(tx, audit, models.AuditActionTransactionCreate, models.AuditEntityTransaction, auditID(posted.ID), nil, posted); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeAudit(tx, audit, models.AuditActionExternalTransferCreate, models.AuditEntityExternalTransfer, auditID(created.ID), nil, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// CompleteExternalTransfer records that the rail moved the money. A deposit
+// is credited from the world account at this point, or to the suspense
+// account if its account has been closed. Completing a transfer
+// that is already completed returns it unchanged.
+func (r *ExternalTransferRepository) CompleteExternalTransfer(id int64, railReference string, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	t, err := withRetry(func() (*models.ExternalTransfer, error) {
+		return r.completeExternalTransfer(id, railReference, audit)
+	})
+	return t, translateError(err)
+}
+
+func (r *ExternalTransferRepository) completeExternalTransfer(id int64, railReference string, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := lockExternalTransfer(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status == models.ExternalTransferCompleted {
+		return t, nil
+	}
+	if t.Status != models.ExternalTransferPending {
+		return nil, fmt.Errorf("%w: external transfer %d is %s", apperrors.ErrExternalTransferSettled, id, t.Status)
+	}
+
+	transactionID := t.TransactionID
+	var posted *models.Transaction
+	if t.Direction == models.ExternalDeposit {
+		// The funds have left the funding source, so the credit is posted
+		// even if the account was frozen in the meantime. An account closed
+		// in the meantime must stay empty, so the funds are parked in the
+		// suspense account instead, to be returned to the customer.
+		accounts, err := lockAccounts(tx, []int64{t.AccountID})
+		if err != nil {
+			return nil, err
+		}
+		creditID := t.AccountID
+		if accounts[t.AccountID].Status == models.AccountStatusClosed {
+			if creditID, err = systemAccountID(tx, models.SystemAccountSuspense, t.Currency); err != nil {
+				return nil, err
+			}
+		}
+		worldID, err := systemAccountID(tx, models.SystemAccountWorld, t.Currency)
+		if err != nil {
+			return nil, err
+		}
+		posted, err = postTransfer(tx, models.EntryKindDeposit, worldID, creditID, t.Amount, t.Currency, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		transactionID = &posted.ID
+	}
+
+	completed, err := scanExternalTransfer(tx.QueryRow(`UPDATE external_transfers
+		SET status = $1, rail_reference = $2, transaction_id = $3, updated_at = NOW()
+		WHERE id = $4 RETURNING `+externalTransferColumns,
+		models.ExternalTransferCompleted, railReference, transactionID, id))
+	if err != nil {
+		return nil, err
+	}
+	if posted != nil {
+		if err := writeAudit(tx, audit, models.AuditActionTransactionCreate, models.AuditEntityTransaction, auditID(posted.ID), nil, posted); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeAudit(tx, audit, models.AuditActionExternalTransferComplete, models.AuditEntityExternalTransfer, auditID(id), t, completed); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return completed, nil
+}
+
+// FailExternalTransfer records that the rail declined the transfer. A
+// withdrawal's debit is refunded from the world account.
+func (r *ExternalTransferRepository) FailExternalTransfer(id int64, reason string, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	t, err := withRetry(func() (*models.ExternalTransfer, error) {
+		return r.failExternalTransfer(id, reason, audit)
+	})
+	return t, translateError(err)
+}
+
+func (r *ExternalTransferRepository) failExternalTransfer(id int64, reason string, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := lockExternalTransfer(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != models.ExternalTransferPending {
+		return nil, fmt.Errorf("%w: external transfer %d is %s", apperrors.ErrExternalTransferSettled, id, t.Status)
+	}
+
+	var refund, debit, refunded *models.Transaction
+	if t.Direction == models.ExternalWithdrawal && t.TransactionID != nil {
+		if _, err := lockAccounts(tx, []int64{t.AccountID}); err != nil {
+			return nil, err
+		}
+		worldID, err := systemAccountID(tx, models.SystemAccountWorld, t.Currency)
+		if err != nil {
+			return nil, err
+		}
+		debit, err = scanTransaction(tx.QueryRow(selectTransactionSQL+" WHERE id = $1", *t.TransactionID))
+		if err != nil {
+			return nil, err
+		}
+		refund, err = postTransfer(tx, models.EntryKindReversal, worldID, t.AccountID, t.Amount, t.Currency, nil, t.TransactionID)
+		if err != nil {
+			return nil, err
+		}
+		refunded, err = scanTransaction(tx.QueryRow("UPDATE transactions SET reversed_amount = amount WHERE id = $1 RETURNING "+transactionColumns, *t.TransactionID))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var refundID *int64
+	if refund != nil {
+		refundID = &refund.ID
+	}
+	failed, err := scanExternalTransfer(tx.QueryRow(`UPDATE external_transfers
+		SET status = $1, failure_reason = $2, refund_transaction_id = $3, updated_at = NOW()
+		WHERE id = $4 RETURNING `+externalTransferColumns,
+		models.ExternalTransferFailed, reason, refundID, id))
+	if err != nil {
+		return nil, err
+	}
+	if refund != nil {
+		if err := writeAudit(tx, audit, models.AuditActionTransactionCreate, models.AuditEntityTransaction, auditID(refund.ID), nil, refund); err != nil {
+			return nil, err
+		}
+		if err := writeAudit(tx, audit, models.AuditActionTransactionReverse, models.AuditEntityTransaction, auditID(debit.ID), debit, refunded); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeAudit(tx, audit, models.AuditActionExternalTransferFail, models.AuditEntityExternalTransfer, auditID(id), t, failed); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return failed, nil
+}
+
+func (r *ExternalTransferRepository) GetExternalTransfer(id int64) (*models.ExternalTransfer, error) {
+	t, err := scanExternalTransfer(r.DB.QueryRow("SELECT "+externalTransferColumns+" FROM external_transfers WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrExternalTransferNotFound, "external transfer %d", id)
+	}
+	return t, nil
+}
+
+// FindExternalTransfer returns the transfer recorded under an external
+// reference, or nil when there is none.
+func (r *ExternalTransferRepository) FindExternalTransfer(direction, externalReference string) (*models.ExternalTransfer, error) {
+	t, err := scanExternalTransfer(r.DB.QueryRow("SELECT "+externalTransferColumns+" FROM external_transfers WHERE direction = $1 AND external_reference = $2",
+		direction, externalReference))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return t, translateError(err)
+}
+
+// lockExternalTransfer locks a transfer before its account, the same order
+// as creating one takes them after the account lock is released.
+func lockExternalTransfer(tx *sql.Tx, id int64) (*models.ExternalTransfer, error) {
+	t, err := scanExternalTransfer(tx.QueryRow("SELECT "+externalTransferColumns+" FROM external_transfers WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrExternalTransferNotFound, "external transfer %d", id)
+	}
+	return t, nil
+}
+
+func scanExternalTransfer(row rowScanner) (*models.ExternalTransfer, error) {
+	var t models.ExternalTransfer
+	var railReference, failureReason sql.NullString
+	var transactionID, refundID sql.NullInt64
+	if err := row.Scan(&t.ID, &t.AccountID, &t.Direction, &t.Amount.Decimal, &t.Currency, &t.Rail, &t.FundingSource,
+		&t.ExternalReference, &railReference, &t.Status, &failureReason, &transactionID, &refundID,
+		&t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	t.RailReference, t.FailureReason = railReference.String, failureReason.String
+	if transactionID.Valid {
+		t.TransactionID = &transactionID.Int64
+	}
+	if refundID.Valid {
+		t.RefundTransactionID = &refundID.Int64
+	}
+	return &t, nil
+}
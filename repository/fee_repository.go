@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"transactions/apperrors"
+	"transactions/models"
+
+	"github.com/shopspring/decimal"
+)
+
+type FeeRepositoryInterface interface {
+	FeeSchedules() ([]models.FeeSchedule, error)
+	AccountFeeProfile(accountID int64) (models.Currency, string, error)
+}
+
+type FeeRepository struct {
+	DB *sql.DB
+}
+
+func NewFeeRepository(db *sql.DB) *FeeRepository {
+	return &FeeRepository{DB: db}
+}
+
+// FeeSchedules returns the enabled schedules from fee_schedules.
+func (r *FeeRepository) FeeSchedules() ([]models.FeeSchedule, error) {
+	schedules, err := r.feeSchedules()
+	return schedules, translateError(err)
+}
+
+func (r *FeeRepository) feeSchedules() ([]models.FeeSchedule, error) {
+	rows, err := r.DB.Query(`SELECT name, fee_type, account_id, account_type, currency, flat_amount, percentage, tiers, min_fee, max_fee
+		FROM fee_schedules WHERE enabled ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []models.FeeSchedule
+	for rows.Next() {
+		var s models.FeeSchedule
+		var accountID sql.NullInt64
+		var accountType, currency sql.NullString
+		var flat, percentage, minFee, maxFee decimal.NullDecimal
+		var tiers []byte
+		if err := rows.Scan(&s.Name, &s.Type, &accountID, &accountType, &currency, &flat, &percentage, &tiers, &minFee, &maxFee); err != nil {
+			return nil, err
+		}
+		s.AccountID = accountID.Int64
+		s.AccountType = accountType.String
+		s.Currency = models.Currency(currency.String)
+		s.Flat = nullMoney(flat)
+		if percentage.Valid {
+			s.Percentage = &percentage.Decimal
+		}
+		s.MinFee = nullMoney(minFee)
+		s.MaxFee = nullMoney(maxFee)
+		if tiers != nil {
+			if err := json.Unmarshal(tiers, &s.Tiers); err != nil {
+				return nil, err
+			}
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// AccountFeeProfile returns what fee schedules are selected by: the
+// account's currency and type.
+func (r *FeeRepository) AccountFeeProfile(accountID int64) (models.Currency, string, error) {
+	var currency models.Currency
+	var accountType string
+	err := r.DB.QueryRow("SELECT currency, account_type FROM accounts WHERE account_id = $1", accountID).Scan(&currency, &accountType)
+	if err != nil {
+		return "", "", notFound(err, apperrors.ErrAccountNotFound, "account %d", accountID)
+	}
+	return currency, accountType, nil
+}
+
+func nullMoney(d decimal.NullDecimal) *models.Money {
+	if !d.Valid {
+		return nil
+	}
+	return &models.Money{Decimal: d.Decimal}
+}
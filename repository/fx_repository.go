@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+
+	"github.com/shopspring/decimal"
+)
+
+type FXRepositoryInterface interface {
+	CreateQuote(quote *models.FXQuote, ttl time.Duration) (*models.FXQuote, error)
+	GetQuote(id string) (*models.FXQuote, error)
+}
+
+type FXRepository struct {
+	DB *sql.DB
+}
+
+func NewFXRepository(db *sql.DB) *FXRepository {
+	return &FXRepository{DB: db}
+}
+
+const quoteColumns = "id, source_currency, destination_currency, rate, source_amount, created_at, expires_at, redeemed_at, transaction_id"
+
+// CreateQuote stores quote with an expiry ttl from now, measured on the
+// database clock so it agrees with redemption checks.
+func (r *FXRepository) CreateQuote(quote *models.FXQuote, ttl time.Duration) (*models.FXQuote, error) {
+	var sourceAmount interface{}
+	if quote.SourceAmount != nil {
+		sourceAmount = quote.SourceAmount.String()
+	}
+	q, err := scanQuote(r.DB.QueryRow(`INSERT INTO fx_quotes (id, source_currency, destination_currency, rate, source_amount, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW() + $6 * INTERVAL '1 millisecond') RETURNING `+quoteColumns,
+		quote.ID, quote.SourceCurrency, quote.DestinationCurrency, quote.Rate.String(), sourceAmount, ttl.Milliseconds()))
+	return q, translateError(err)
+}
+
+func (r *FXRepository) GetQuote(id string) (*models.FXQuote, error) {
+	q, err := scanQuote(r.DB.QueryRow("SELECT "+quoteColumns+" FROM fx_quotes WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrQuoteNotFound, "quote %s", id)
+	}
+	return q, nil
+}
+
+// redeemQuote marks a quote as used inside tx and returns its rate. The quote
+// must be unexpired, unredeemed, for the given currency pair and, if it was
+// issued for a fixed amount, for exactly amount.
+func redeemQuote(tx *sql.Tx, id string, amount models.Money, source, dest models.Currency) (decimal.Decimal, error) {
+	q, err := scanQuote(tx.QueryRow(`UPDATE fx_quotes SET redeemed_at = NOW()
+		WHERE id = $1 AND redeemed_at IS NULL AND expires_at > NOW() RETURNING `+quoteColumns, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		var redeemed bool
+		err = tx.QueryRow("SELECT redeemed_at IS NOT NULL FROM fx_quotes WHERE id = $1", id).Scan(&redeemed)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return decimal.Decimal{}, fmt.Errorf("%w: quote %s", apperrors.ErrQuoteNotFound, id)
+		case err != nil:
+			return decimal.Decimal{}, err
+		case redeemed:
+			return decimal.Decimal{}, fmt.Errorf("%w: quote %s", apperrors.ErrQuoteRedeemed, id)
+		default:
+			return decimal.Decimal{}, fmt.Errorf("%w: quote %s", apperrors.ErrQuoteExpired, id)
+		}
+	}
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	if q.SourceCurrency != source || q.DestinationCurrency != dest {
+		return decimal.Decimal{}, fmt.Errorf("%w: quote %s is for %s to %s, accounts hold %s and %s",
+			apperrors.ErrCurrencyMismatch, id, q.SourceCurrency, q.DestinationCurrency, source, dest)
+	}
+	if q.SourceAmount != nil && !q.SourceAmount.Equal(amount.Decimal) {
+		return decimal.Decimal{}, fmt.Errorf("%w: quote %s was issued for %s %s", apperrors.ErrInvalidAmount, id, q.SourceAmount, q.SourceCurrency)
+	}
+	return q.Rate, nil
+}
+
+func scanQuote(row rowScanner) (*models.FXQuote, error) {
+	var q models.FXQuote
+	var sourceAmount decimal.NullDecimal
+	var redeemedAt sql.NullTime
+	var transactionID sql.NullInt64
+	if err := row.Scan(&q.ID, &q.SourceCurrency, &q.DestinationCurrency, &q.Rate, &sourceAmount, &q.CreatedAt, &q.ExpiresAt, &redeemedAt, &transactionID); err != nil {
+		return nil, err
+	}
+	if sourceAmount.Valid {
+		q.SourceAmount = &models.Money{Decimal: sourceAmount.Decimal}
+		dest := q.SourceAmount.Convert(q.Rate, q.DestinationCurrency)
+		q.DestinationAmount = &dest
+	}
+	if redeemedAt.Valid {
+		q.RedeemedAt = &redeemedAt.Time
+	}
+	if transactionID.Valid {
+		q.TransactionID = &transactionID.Int64
+	}
+	return &q, nil
+}
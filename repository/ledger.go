@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"transactions/apperrors"
+	"transactions/models"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// systemAccountID returns the id of the system account code:currency,
+// creating it on first use. System accounts take negative ids from their own
+// sequence.
+func systemAccountID(tx *sql.Tx, code string, currency models.Currency) (int64, error) {
+	systemCode := code + ":" + string(currency)
+	_, err := tx.Exec(`INSERT INTO accounts (account_id, balance, currency, system_code)
+		VALUES (nextval('system_account_id_seq'), 0, $1, $2) ON CONFLICT (system_code) DO NOTHING`, currency, systemCode)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = tx.QueryRow("SELECT account_id FROM accounts WHERE system_code = $1", systemCode).Scan(&id)
+	return id, err
+}
+
+// lockedAccount is the state of an account row held FOR UPDATE
+type lockedAccount struct {
+	Balance       decimal.Decimal
+	Held          decimal.Decimal
+	Overdraft     decimal.Decimal
+	Currency      models.Currency
+	Status        string
+	AllowIncoming bool
+	// System is set on the ledger's own accounts, which clients may not
+	// move money in or out of directly
+	System bool
+}
+
+// Available is what the account can spend: the balance not reserved by open
+// authorizations, plus its overdraft limit
+func (a lockedAccount) Available() decimal.Decimal {
+	return a.Balance.Sub(a.Held).Add(a.Overdraft)
+}
+
+// checkNotSystem fails if account id is a system account
+func (a lockedAccount) checkNotSystem(id int64) error {
+	if a.System {
+		return fmt.Errorf("%w: account %d", apperrors.ErrSystemAccount, id)
+	}
+	return nil
+}
+
+// checkCanSend fails unless account id may be debited
+func (a lockedAccount) checkCanSend(id int64) error {
+	switch a.Status {
+	case models.AccountStatusFrozen:
+		return fmt.Errorf("%w: account %d cannot send", apperrors.ErrAccountFrozen, id)
+	case models.AccountStatusClosed:
+		return fmt.Errorf("%w: account %d", apperrors.ErrAccountClosed, id)
+	}
+	return nil
+}
+
+// checkCanReceive fails unless account id may be credited
+func (a lockedAccount) checkCanReceive(id int64) error {
+	switch {
+	case a.Status == models.AccountStatusFrozen && !a.AllowIncoming:
+		return fmt.Errorf("%w: account %d cannot receive", apperrors.ErrAccountFrozen, id)
+	case a.Status == models.AccountStatusClosed:
+		return fmt.Errorf("%w: account %d", apperrors.ErrAccountClosed, id)
+	}
+	return nil
+}
+
+// checkTransfer fails unless money may move from sourceID to destID
+func checkTransfer(accounts map[int64]lockedAccount, sourceID, destID int64) error {
+	if err := accounts[sourceID].checkCanSend(sourceID); err != nil {
+		return err
+	}
+	return accounts[destID].checkCanReceive(destID)
+}
+
+// lockAccounts locks every account in ids with a single statement, in
+// ascending account id order, so that concurrent callers touching the same
+// accounts can never wait on each other in a cycle. It fails with
+// ErrAccountNotFound naming the first missing id. The optional accounts are
+// locked in the same statement when they exist and left out of the result
+// when they do not.
+func lockAccounts(tx *sql.Tx, ids []int64, optional ...int64) (map[int64]lockedAccount, error) {
+	rows, err := tx.Query(`SELECT account_id, balance, held_balance, overdraft_limit, currency, status, allow_incoming, system_code IS NOT NULL FROM accounts
+		WHERE account_id = ANY($1) ORDER BY account_id FOR UPDATE`, pq.Array(append(ids[:len(ids):len(ids)], optional...)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	locked := make(map[int64]lockedAccount, len(ids))
+	for rows.Next() {
+		var id int64
+		var acc lockedAccount
+		if err := rows.Scan(&id, &acc.Balance, &acc.Held, &acc.Overdraft, &acc.Currency, &acc.Status, &acc.AllowIncoming, &acc.System); err != nil {
+			return nil, err
+		}
+		locked[id] = acc
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if _, ok := locked[id]; !ok {
+			return nil, fmt.Errorf("%w: account %d", apperrors.ErrAccountNotFound, id)
+		}
+	}
+	return locked, nil
+}
+
+// postJournalEntry records a journal entry with its postings and applies
+// them to the cached account balances. It returns each touched account's new
+// balance. The database rejects the commit if the postings do not balance;
+// the same check runs here first so a bug fails fast with a clear message.
+func postJournalEntry(tx *sql.Tx, kind string, transactionID *int64, postings []models.Posting) (map[int64]decimal.Decimal, error) {
+	sums := map[models.Currency]decimal.Decimal{}
+	for _, p := range postings {
+		sums[p.Currency] = sums[p.Currency].Add(p.Amount.Decimal)
+	}
+	for currency, sum := range sums {
+		if !sum.IsZero() {
+			return nil, fmt.Errorf("journal entry %s does not balance in %s: %s", kind, currency, sum)
+		}
+	}
+
+	var entryID int64
+	err := tx.QueryRow("INSERT INTO journal_entries (transaction_id, kind) VALUES ($1, $2) RETURNING id", transactionID, kind).Scan(&entryID)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas := map[int64]decimal.Decimal{}
+	for _, p := range postings {
+		_, err := tx.Exec("INSERT INTO postings (entry_id, account_id, amount, currency) VALUES ($1, $2, $3, $4)",
+			entryID, p.AccountID, p.Amount.String(), p.Currency)
+		if err != nil {
+			return nil, err
+		}
+		deltas[p.AccountID] = deltas[p.AccountID].Add(p.Amount.Decimal)
+	}
+
+	// Update balances in account id order so concurrent entries touching the
+	// same system accounts always lock them in the same order.
+	accountIDs := make([]int64, 0, len(deltas))
+	for id := range deltas {
+		accountIDs = append(accountIDs, id)
+	}
+	sort.Slice(accountIDs, func(i, j int) bool { return accountIDs[i] < accountIDs[j] })
+
+	balances := make(map[int64]decimal.Decimal, len(deltas))
+	for _, id := range accountIDs {
+		var balance decimal.Decimal
+		err := tx.QueryRow("UPDATE accounts SET balance = balance + $1 WHERE account_id = $2 RETURNING balance",
+			deltas[id].String(), id).Scan(&balance)
+		if err != nil {
+			return nil, err
+		}
+		balances[id] = balance
+	}
+	return balances, nil
+}
+
+// debit and credit build the two sides of a posting pair.
+func debit(accountID int64, amount models.Money, currency models.Currency) models.Posting {
+	return models.Posting{AccountID: accountID, Amount: models.Money{Decimal: amount.Neg()}, Currency: currency}
+}
+
+func credit(accountID int64, amount models.Money, currency models.Currency) models.Posting {
+	return models.Posting{AccountID: accountID, Amount: amount, Currency: currency}
+}
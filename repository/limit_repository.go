@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"database/sql"
+	"transactions/apperrors"
+	"transactions/models"
+
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+type LimitRepositoryInterface interface {
+	LimitRules() ([]models.LimitRule, error)
+	OutgoingUsage(accountID int64, windows models.UsageWindows) (*models.OutgoingUsage, error)
+}
+
+type LimitRepository struct {
+	DB *sql.DB
+}
+
+func NewLimitRepository(db *sql.DB) *LimitRepository {
+	return &LimitRepository{DB: db}
+}
+
+// LimitRules returns the enabled rules from transfer_limit_rules.
+func (r *LimitRepository) LimitRules() ([]models.LimitRule, error) {
+	rules, err := r.limitRules()
+	return rules, translateError(err)
+}
+
+func (r *LimitRepository) limitRules() ([]models.LimitRule, error) {
+	rows, err := r.DB.Query(`SELECT name, rule_type, account_id, currency, amount, max_count
+		FROM transfer_limit_rules WHERE enabled ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.LimitRule
+	for rows.Next() {
+		var rule models.LimitRule
+		var accountID sql.NullInt64
+		var currency sql.NullString
+		var amount decimal.NullDecimal
+		var maxCount sql.NullInt64
+		if err := rows.Scan(&rule.Name, &rule.Type, &accountID, &currency, &amount, &maxCount); err != nil {
+			return nil, err
+		}
+		rule.AccountID = accountID.Int64
+		rule.Currency = models.Currency(currency.String)
+		if amount.Valid {
+			rule.Amount = &models.Money{Decimal: amount.Decimal}
+		}
+		rule.MaxCount = int(maxCount.Int64)
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// outgoingEntryKinds are the journal entries that count as money an account
+// chose to send. Reversals, refunds and the sweep of a closing account do
+// not.
+var outgoingEntryKinds = []string{models.EntryKindTransfer, models.EntryKindWithdrawal}
+
+// OutgoingUsage sums what accountID has sent since each window start,
+// including the fees it was charged and authorizations still holding
+// funds. The windows are UTC, like the stored timestamps.
+func (r *LimitRepository) OutgoingUsage(accountID int64, windows models.UsageWindows) (*models.OutgoingUsage, error) {
+	var u models.OutgoingUsage
+	err := r.DB.QueryRow(`WITH outgoing AS (
+			SELECT t.amount + t.fee AS amount, t.created_at FROM transactions t
+			JOIN journal_entries j ON j.transaction_id = t.id
+			WHERE t.source_account_id = $1 AND j.kind = ANY($5) AND t.created_at >= LEAST($2::timestamp, $3::timestamp, $4::timestamp)
+			UNION ALL
+			SELECT p.amount, p.created_at FROM pending_transfers p
+			WHERE p.source_account_id = $1 AND p.status = $6 AND p.created_at >= LEAST($2::timestamp, $3::timestamp, $4::timestamp)
+		)
+		SELECT a.currency,
+			COALESCE(SUM(o.amount) FILTER (WHERE o.created_at >= $2::timestamp), 0),
+			COALESCE(SUM(o.amount) FILTER (WHERE o.created_at >= $3::timestamp), 0),
+			COUNT(o.amount) FILTER (WHERE o.created_at >= $4::timestamp)
+		FROM accounts a
+		LEFT JOIN outgoing o ON TRUE
+		WHERE a.account_id = $1
+		GROUP BY a.currency`,
+		accountID, windows.DayStart.UTC(), windows.MonthStart.UTC(), windows.HourStart.UTC(),
+		pq.Array(outgoingEntryKinds), models.PendingTransferPending).
+		Scan(&u.Currency, &u.Today.Decimal, &u.ThisMonth.Decimal, &u.LastHour)
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrAccountNotFound, "account %d", accountID)
+	}
+	return &u, nil
+}
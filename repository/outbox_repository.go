@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"transactions/models"
+
+	"github.com/lib/pq"
+)
+
+// outboxRelayLockKey is the advisory lock held by the relay pass in
+// progress, so two instances never publish out of order.
+const outboxRelayLockKey = 7302
+
+type OutboxRepositoryInterface interface {
+	RelayOutbox(limit int, publish func(events []models.Event) map[int64]error) (int, error)
+}
+
+type OutboxRepository struct {
+	DB *sql.DB
+}
+
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{DB: db}
+}
+
+// writeEvent records an event in the outbox as part of tx, so it is
+// published if and only if tx commits.
+func writeEvent(tx *sql.Tx, eventType string, accountIDs []int64, payload interface{}) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("event payload: %w", err)
+	}
+	_, err = tx.Exec("INSERT INTO outbox (event_type, account_ids, payload) VALUES ($1, $2, $3)",
+		eventType, pq.Array(accountIDs), string(b))
+	return err
+}
+
+// RelayOutbox hands unpublished events to publish, oldest first, in pages
+// of up to limit, until publish has attempted limit events or none are
+// left. publish returns the outcome of each event it attempted:
+// nil when published. Events it leaves out stay pending untouched, and a
+// nil result ends the pass. Paging on past the events publish holds back
+// keeps one stuck account from starving the rest. It returns how many were
+// published. When another relay holds the outbox the pass does nothing.
+//
+// Events are marked only after publish returns, so a crash in between
+// publishes them again: delivery is at least once.
+func (r *OutboxRepository) RelayOutbox(limit int, publish func(events []models.Event) map[int64]error) (int, error) {
+	n, err := r.relayOutbox(limit, publish)
+	return n, translateError(err)
+}
+
+func (r *OutboxRepository) relayOutbox(limit int, publish func(events []models.Event) map[int64]error) (int, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRow("SELECT pg_try_advisory_xact_lock($1)", outboxRelayLockKey).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	published, attempted := 0, 0
+	var after int64
+	for attempted < limit {
+		events, err := listUnpublishedEvents(tx, after, limit)
+		if err != nil {
+			return 0, err
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		outcomes := publish(events)
+		if outcomes == nil {
+			break
+		}
+		for id, publishErr := range outcomes {
+			if publishErr != nil {
+				_, err = tx.Exec("UPDATE outbox SET attempts = attempts + 1, last_error = $1 WHERE id = $2", publishErr.Error(), id)
+			} else {
+				_, err = tx.Exec("UPDATE outbox SET attempts = attempts + 1, last_error = NULL, published_at = NOW() WHERE id = $1", id)
+				published++
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		attempted += len(outcomes)
+
+		if len(events) < limit {
+			break
+		}
+		after = events[len(events)-1].ID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return published, nil
+}
+
+// listUnpublishedEvents returns up to limit unpublished events with ids
+// after after, oldest first.
+func listUnpublishedEvents(tx *sql.Tx, after int64, limit int) ([]models.Event, error) {
+	rows, err := tx.Query(`SELECT id, event_type, account_ids, payload, occurred_at FROM outbox
+		WHERE published_at IS NULL AND id > $1 ORDER BY id LIMIT $2`, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		var payload []byte
+		if err := rows.Scan(&e.ID, &e.Type, pq.Array(&e.AccountIDs), &payload, &e.OccurredAt); err != nil {
+			return nil, err
+		}
+		e.Payload = json.RawMessage(payload)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
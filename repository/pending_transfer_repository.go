@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+
+	"github.com/shopspring/decimal"
+)
+
+type PendingTransferRepositoryInterface interface {
+	AuthorizeTransfer(req models.TransferRequest, ttl time.Duration) (*models.PendingTransfer, error)
+	CaptureTransfer(id int64, amount *models.Money, audit models.AuditContext) (*models.PendingTransfer, error)
+	VoidTransfer(id int64, audit models.AuditContext) (*models.PendingTransfer, error)
+	GetPendingTransfer(id int64) (*models.PendingTransfer, error)
+	ExpirePendingTransfers(limit int, audit models.AuditContext) (int64, error)
+}
+
+type PendingTransferRepository struct {
+	DB *sql.DB
+}
+
+func NewPendingTransferRepository(db *sql.DB) *PendingTransferRepository {
+	return &PendingTransferRepository{DB: db}
+}
+
+const pendingTransferColumns = "id, source_account_id, destination_account_id, amount, currency, status, captured_amount, transaction_id, created_at, expires_at, updated_at"
+
+// AuthorizeTransfer holds req.Amount on the source account until the
+// transfer is captured, voided or ttl elapses. Authorizations are
+// same-currency only and audited with req.Audit.
+func (r *PendingTransferRepository) AuthorizeTransfer(req models.TransferRequest, ttl time.Duration) (*models.PendingTransfer, error) {
+	p, err := withRetry(func() (*models.PendingTransfer, error) {
+		return r.authorizeTransfer(req, ttl)
+	})
+	return p, translateError(err)
+}
+
+func (r *PendingTransferRepository) authorizeTransfer(req models.TransferRequest, ttl time.Duration) (*models.PendingTransfer, error) {
+	sourceID, destID := req.SourceAccountID, req.DestinationAccountID
+	if !req.Amount.IsPositive() {
+		return nil, apperrors.ErrInvalidAmount
+	}
+
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	accounts, err := lockAccounts(tx, []int64{sourceID, destID})
+	if err != nil {
+		return nil, err
+	}
+	source, dest := accounts[sourceID], accounts[destID]
+	if err := checkTransfer(accounts, sourceID, destID); err != nil {
+		return nil, err
+	}
+	if source.Currency != dest.Currency || (req.Currency != "" && req.Currency != source.Currency) {
+		return nil, fmt.Errorf("%w: source account %d holds %s, destination account %d holds %s", apperrors.ErrCurrencyMismatch, sourceID, source.Currency, destID, dest.Currency)
+	}
+	if err := req.Amount.CheckScale(source.Currency); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidAmount, err)
+	}
+	if source.Available().LessThan(req.Amount.Decimal) {
+		return nil, fmt.Errorf("%w: account %d", apperrors.ErrInsufficientFunds, sourceID)
+	}
+
+	if err := adjustHold(tx, sourceID, req.Amount.Decimal); err != nil {
+		return nil, err
+	}
+	p, err := scanPendingTransfer(tx.QueryRow(`INSERT INTO pending_transfers (source_account_id, destination_account_id, amount, currency, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + $5 * INTERVAL '1 millisecond') RETURNING `+pendingTransferColumns,
+		sourceID, destID, req.Amount.String(), source.Currency, ttl.Milliseconds()))
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAudit(tx, req.Audit, models.AuditActionPendingTransferAuthorize, models.AuditEntityPendingTransfer, auditID(p.ID), nil, p); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// CaptureTransfer settles a pending transfer for amount, or for the full
+// authorized amount when amount is nil, and releases the rest of the hold.
+func (r *PendingTransferRepository) CaptureTransfer(id int64, amount *models.Money, audit models.AuditContext) (*models.PendingTransfer, error) {
+	p, err := withRetry(func() (*models.PendingTransfer, error) {
+		return r.captureTransfer(id, amount, audit)
+	})
+	return p, translateError(err)
+}
+
+func (r *PendingTransferRepository) captureTransfer(id int64, amount *models.Money, audit models.AuditContext) (*models.PendingTransfer, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	p, expired, err := lockPendingTransfer(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		return nil, fmt.Errorf("%w: transfer %d", apperrors.ErrAuthorizationExpired, id)
+	}
+
+	capture := p.Amount
+	if amount != nil {
+		if !amount.IsPositive() || amount.GreaterThan(p.Amount.Decimal) {
+			return nil, fmt.Errorf("%w: capture must be between 0 and the authorized %s %s", apperrors.ErrInvalidAmount, p.Amount, p.Currency)
+		}
+		if err := amount.CheckScale(p.Currency); err != nil {
+			return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidAmount, err)
+		}
+		capture = *amount
+	}
+
+	// The pending row is always locked before its accounts, here and when
+	// voiding or expiring, so these paths cannot deadlock with each other.
+	accounts, err := lockAccounts(tx, []int64{p.SourceAccountID, p.DestinationAccountID})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTransfer(accounts, p.SourceAccountID, p.DestinationAccountID); err != nil {
+		return nil, err
+	}
+	if err := adjustHold(tx, p.SourceAccountID, p.Amount.Neg()); err != nil {
+		return nil, err
+	}
+	t, err := postTransfer(tx, models.EntryKindTransfer, p.SourceAccountID, p.DestinationAccountID, capture, p.Currency, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	captured, err := scanPendingTransfer(tx.QueryRow(`UPDATE pending_transfers
+		SET status = $1, captured_amount = $2, transaction_id = $3, updated_at = NOW()
+		WHERE id = $4 RETURNING `+pendingTransferColumns,
+		models.PendingTransferCaptured, capture.String(), t.ID, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAudit(tx, audit, models.AuditActionTransactionCreate, models.AuditEntityTransaction, auditID(t.ID), nil, t); err != nil {
+		return nil, err
+	}
+	if err := writeAudit(tx, audit, models.AuditActionPendingTransferCapture, models.AuditEntityPendingTransfer, auditID(id), p, captured); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return captured, nil
+}
+
+// VoidTransfer cancels a pending transfer and releases its hold.
+func (r *PendingTransferRepository) VoidTransfer(id int64, audit models.AuditContext) (*models.PendingTransfer, error) {
+	p, err := withRetry(func() (*models.PendingTransfer, error) {
+		return r.releaseTransfer(id, models.PendingTransferVoided, audit)
+	})
+	return p, translateError(err)
+}
+
+// ExpirePendingTransfers releases up to limit authorizations whose TTL has
+// elapsed and returns how many were expired. Each is audited with audit.
+func (r *PendingTransferRepository) ExpirePendingTransfers(limit int, audit models.AuditContext) (int64, error) {
+	rows, err := r.DB.Query(`SELECT id FROM pending_transfers
+		WHERE status = $1 AND expires_at <= NOW() ORDER BY expires_at LIMIT $2`, models.PendingTransferPending, limit)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, translateError(err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, translateError(err)
+	}
+
+	var expired int64
+	for _, id := range ids {
+		_, err := withRetry(func() (*models.PendingTransfer, error) {
+			return r.releaseTransfer(id, models.PendingTransferExpired, audit)
+		})
+		if _, ok := apperrors.As(err); ok {
+			// Captured or voided since it was listed
+			continue
+		}
+		if err != nil {
+			return expired, translateError(err)
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// releaseTransfer moves a pending transfer to status and releases its hold
+// without moving any money.
+func (r *PendingTransferRepository) releaseTransfer(id int64, status string, audit models.AuditContext) (*models.PendingTransfer, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	p, expired, err := lockPendingTransfer(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	// Voiding needs a live authorization; expiring needs an elapsed one
+	if expired != (status == models.PendingTransferExpired) {
+		if expired {
+			return nil, fmt.Errorf("%w: transfer %d", apperrors.ErrAuthorizationExpired, id)
+		}
+		return nil, fmt.Errorf("%w: transfer %d has not expired", apperrors.ErrTransferNotPending, id)
+	}
+	if _, err := lockAccounts(tx, []int64{p.SourceAccountID}); err != nil {
+		return nil, err
+	}
+	if err := adjustHold(tx, p.SourceAccountID, p.Amount.Neg()); err != nil {
+		return nil, err
+	}
+
+	released, err := scanPendingTransfer(tx.QueryRow(`UPDATE pending_transfers SET status = $1, updated_at = NOW()
+		WHERE id = $2 RETURNING `+pendingTransferColumns, status, id))
+	if err != nil {
+		return nil, err
+	}
+	action := models.AuditActionPendingTransferVoid
+	if status == models.PendingTransferExpired {
+		action = models.AuditActionPendingTransferExpire
+	}
+	if err := writeAudit(tx, audit, action, models.AuditEntityPendingTransfer, auditID(id), p, released); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return released, nil
+}
+
+func (r *PendingTransferRepository) GetPendingTransfer(id int64) (*models.PendingTransfer, error) {
+	p, err := scanPendingTransfer(r.DB.QueryRow("SELECT "+pendingTransferColumns+" FROM pending_transfers WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrTransferNotFound, "pending transfer %d", id)
+	}
+	return p, nil
+}
+
+// lockPendingTransfer locks a transfer that is still pending and reports
+// whether its TTL has elapsed, which may be before the expiry worker has
+// released it.
+func lockPendingTransfer(tx *sql.Tx, id int64) (*models.PendingTransfer, bool, error) {
+	var expired bool
+	p, err := scanPendingTransfer(tx.QueryRow("SELECT "+pendingTransferColumns+", expires_at <= NOW() FROM pending_transfers WHERE id = $1 FOR UPDATE", id), &expired)
+	if err != nil {
+		return nil, false, notFound(err, apperrors.ErrTransferNotFound, "pending transfer %d", id)
+	}
+	if p.Status != models.PendingTransferPending {
+		return nil, false, fmt.Errorf("%w: transfer %d is %s", apperrors.ErrTransferNotPending, id, p.Status)
+	}
+	return p, expired, nil
+}
+
+// adjustHold changes the funds held on an account by delta
+func adjustHold(tx *sql.Tx, accountID int64, delta decimal.Decimal) error {
+	_, err := tx.Exec("UPDATE accounts SET held_balance = held_balance + $1 WHERE account_id = $2", delta.String(), accountID)
+	return err
+}
+
+func scanPendingTransfer(row rowScanner, extra ...interface{}) (*models.PendingTransfer, error) {
+	var p models.PendingTransfer
+	var captured decimal.NullDecimal
+	var transactionID sql.NullInt64
+	dest := append([]interface{}{&p.ID, &p.SourceAccountID, &p.DestinationAccountID, &p.Amount.Decimal, &p.Currency,
+		&p.Status, &captured, &transactionID, &p.CreatedAt, &p.ExpiresAt, &p.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if captured.Valid {
+		p.CapturedAmount = &models.Money{Decimal: captured.Decimal}
+	}
+	if transactionID.Valid {
+		p.TransactionID = &transactionID.Int64
+	}
+	return &p, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"transactions/models"
+)
+
+type ReconciliationRepositoryInterface interface {
+	Reconcile() (*models.ReconciliationReport, error)
+	RecordReconciliationRun(report models.ReconciliationReport) (int64, error)
+}
+
+type ReconciliationRepository struct {
+	DB *sql.DB
+}
+
+func NewReconciliationRepository(db *sql.DB) *ReconciliationRepository {
+	return &ReconciliationRepository{DB: db}
+}
+
+// Reconcile recomputes every account's balance from its postings and
+// totals each currency. It reads one snapshot, so transfers committed
+// meanwhile cannot show up as drift.
+func (r *ReconciliationRepository) Reconcile() (*models.ReconciliationReport, error) {
+	report, err := r.reconcile()
+	return report, translateError(err)
+}
+
+func (r *ReconciliationRepository) reconcile() (*models.ReconciliationReport, error) {
+	report := models.ReconciliationReport{StartedAt: time.Now(), Drifts: []models.AccountDrift{}, Currencies: []models.CurrencyTotal{}}
+
+	tx, err := r.DB.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&report.AccountsChecked); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`SELECT a.account_id, a.currency, a.balance, COALESCE(p.total, 0)
+		FROM accounts a
+		LEFT JOIN (SELECT account_id, SUM(amount) AS total FROM postings GROUP BY account_id) p
+			ON p.account_id = a.account_id
+		WHERE a.balance <> COALESCE(p.total, 0)
+		ORDER BY a.account_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d models.AccountDrift
+		if err := rows.Scan(&d.AccountID, &d.Currency, &d.Balance.Decimal, &d.LedgerBalance.Decimal); err != nil {
+			return nil, err
+		}
+		d.Drift = models.Money{Decimal: d.Balance.Sub(d.LedgerBalance.Decimal)}
+		report.Drifts = append(report.Drifts, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = tx.Query(`SELECT COALESCE(b.currency, p.currency), COALESCE(b.total, 0), COALESCE(p.total, 0)
+		FROM (SELECT currency, SUM(balance) AS total FROM accounts GROUP BY currency) b
+		FULL JOIN (SELECT currency, SUM(amount) AS total FROM postings GROUP BY currency) p
+			ON p.currency = b.currency
+		ORDER BY 1`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	report.Balanced = len(report.Drifts) == 0
+	for rows.Next() {
+		var c models.CurrencyTotal
+		if err := rows.Scan(&c.Currency, &c.BalancesTotal.Decimal, &c.PostingsTotal.Decimal); err != nil {
+			return nil, err
+		}
+		report.Balanced = report.Balanced && c.Conserved()
+		report.Currencies = append(report.Currencies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	report.FinishedAt = time.Now()
+	return &report, nil
+}
+
+// RecordReconciliationRun stores report in reconciliation_runs, with one
+// finding per drifted account and per currency, and returns the run's id.
+func (r *ReconciliationRepository) RecordReconciliationRun(report models.ReconciliationReport) (int64, error) {
+	id, err := r.recordReconciliationRun(report)
+	return id, translateError(err)
+}
+
+func (r *ReconciliationRepository) recordReconciliationRun(report models.ReconciliationReport) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(`INSERT INTO reconciliation_runs (started_at, finished_at, accounts_checked, drifted_accounts, balanced)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		report.StartedAt.UTC(), report.FinishedAt.UTC(), report.AccountsChecked, len(report.Drifts), report.Balanced).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range report.Drifts {
+		_, err := tx.Exec("INSERT INTO reconciliation_findings (run_id, account_id, currency, recorded, computed) VALUES ($1, $2, $3, $4, $5)",
+			id, d.AccountID, d.Currency, d.Balance.String(), d.LedgerBalance.String())
+		if err != nil {
+			return 0, err
+		}
+	}
+	for _, c := range report.Currencies {
+		_, err := tx.Exec("INSERT INTO reconciliation_findings (run_id, currency, recorded, computed) VALUES ($1, $2, $3, $4)",
+			id, c.Currency, c.BalancesTotal.String(), c.PostingsTotal.String())
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
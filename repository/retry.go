@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres aborts one side of a deadlock or a serialization conflict; the
+// aborted DB transaction is safe to run again from the start.
+const (
+	pqSerializationFailure = "40001"
+	pqDeadlockDetected     = "40P01"
+)
+
+const (
+	maxTxAttempts  = 5
+	retryBaseDelay = 10 * time.Millisecond
+)
+
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == pqSerializationFailure || pqErr.Code == pqDeadlockDetected
+}
+
+// withRetry runs fn, which must open and finish its own DB transaction, and
+// runs it again with jittered exponential backoff while Postgres reports a
+// deadlock or serialization failure. It gives up after maxTxAttempts.
+func withRetry[T any](fn func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil || !isRetryable(err) {
+			return result, err
+		}
+		if attempt == maxTxAttempts {
+			return result, err
+		}
+		backoff := retryBaseDelay << (attempt - 1)
+		time.Sleep(backoff/2 + time.Duration(rand.Int63n(int64(backoff/2))))
+	}
+}
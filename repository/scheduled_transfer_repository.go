@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+)
+
+type ScheduledTransferRepositoryInterface interface {
+	CreateScheduledTransfer(req models.TransferRequest, executeAt time.Time) (*models.ScheduledTransfer, error)
+	GetScheduledTransfer(id int64) (*models.ScheduledTransfer, error)
+	ListScheduledTransfers(filter models.ScheduledTransferFilter) ([]models.ScheduledTransfer, error)
+	CancelScheduledTransfer(id int64) (*models.ScheduledTransfer, error)
+	ClaimDueScheduledTransfers(limit int, lease time.Duration) ([]models.ScheduledTransfer, error)
+	CompleteScheduledTransfer(id, transactionID int64) error
+	FailScheduledTransfer(id int64, code, reason string) error
+}
+
+type ScheduledTransferRepository struct {
+	DB *sql.DB
+}
+
+func NewScheduledTransferRepository(db *sql.DB) *ScheduledTransferRepository {
+	return &ScheduledTransferRepository{DB: db}
+}
+
+const scheduledTransferColumns = "id, source_account_id, destination_account_id, amount, currency, allow_conversion, execute_at, status, attempts, transaction_id, failure_code, failure_reason, created_at, updated_at"
+
+// CreateScheduledTransfer records req to be submitted at executeAt. Funds
+// and account status are only checked when it runs.
+func (r *ScheduledTransferRepository) CreateScheduledTransfer(req models.TransferRequest, executeAt time.Time) (*models.ScheduledTransfer, error) {
+	var currency interface{}
+	if req.Currency != "" {
+		currency = req.Currency
+	}
+	s, err := scanScheduledTransfer(r.DB.QueryRow(`INSERT INTO scheduled_transfers (source_account_id, destination_account_id, amount, currency, allow_conversion, execute_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+scheduledTransferColumns,
+		req.SourceAccountID, req.DestinationAccountID, req.Amount.String(), currency, req.AllowConversion, executeAt.UTC()))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return s, nil
+}
+
+func (r *ScheduledTransferRepository) GetScheduledTransfer(id int64) (*models.ScheduledTransfer, error) {
+	s, err := scanScheduledTransfer(r.DB.QueryRow("SELECT "+scheduledTransferColumns+" FROM scheduled_transfers WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrScheduleNotFound, "scheduled transfer %d", id)
+	}
+	return s, nil
+}
+
+// ListScheduledTransfers returns scheduled transfers in execution order.
+func (r *ScheduledTransferRepository) ListScheduledTransfers(filter models.ScheduledTransferFilter) ([]models.ScheduledTransfer, error) {
+	var conds []string
+	var args []interface{}
+	addCond := func(format string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(format, len(args)))
+	}
+	if filter.AccountID != 0 {
+		addCond("(source_account_id = $%[1]d OR destination_account_id = $%[1]d)", filter.AccountID)
+	}
+	if filter.Status != "" {
+		addCond("status = $%d", filter.Status)
+	}
+
+	query := "SELECT " + scheduledTransferColumns + " FROM scheduled_transfers"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY execute_at, id LIMIT $%d", len(args))
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	transfers := []models.ScheduledTransfer{}
+	for rows.Next() {
+		s, err := scanScheduledTransfer(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		transfers = append(transfers, *s)
+	}
+	return transfers, translateError(rows.Err())
+}
+
+// CancelScheduledTransfer cancels a transfer that has not been claimed yet.
+func (r *ScheduledTransferRepository) CancelScheduledTransfer(id int64) (*models.ScheduledTransfer, error) {
+	s, err := scanScheduledTransfer(r.DB.QueryRow(`UPDATE scheduled_transfers SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3 RETURNING `+scheduledTransferColumns,
+		models.ScheduledTransferCancelled, id, models.ScheduledTransferScheduled))
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, err := r.GetScheduledTransfer(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: scheduled transfer %d is %s", apperrors.ErrScheduleNotScheduled, id, existing.Status)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return s, nil
+}
+
+// ClaimDueScheduledTransfers moves up to limit due transfers to processing
+// and returns them. Rows locked by another worker are skipped, so several
+// instances can poll concurrently. A transfer claimed more than lease ago
+// whose outcome was never recorded is claimed again.
+func (r *ScheduledTransferRepository) ClaimDueScheduledTransfers(limit int, lease time.Duration) ([]models.ScheduledTransfer, error) {
+	rows, err := r.DB.Query(`UPDATE scheduled_transfers s
+		SET status = $1, claimed_at = NOW(), attempts = attempts + 1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM scheduled_transfers
+			WHERE (status = $2 AND execute_at <= NOW() AT TIME ZONE 'UTC')
+			   OR (status = $1 AND claimed_at <= NOW() - $3 * INTERVAL '1 millisecond')
+			ORDER BY execute_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED)
+		RETURNING `+scheduledTransferColumns+`,
+			(SELECT transaction_id FROM idempotency_keys WHERE idempotency_key = $5::text || s.id)`,
+		models.ScheduledTransferProcessing, models.ScheduledTransferScheduled, lease.Milliseconds(), limit,
+		models.ScheduledIdempotencyKeyPrefix)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var claimed []models.ScheduledTransfer
+	for rows.Next() {
+		var submitted sql.NullInt64
+		s, err := scanScheduledTransfer(rows, &submitted)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		if submitted.Valid {
+			s.SubmittedTransactionID = &submitted.Int64
+		}
+		claimed = append(claimed, *s)
+	}
+	return claimed, translateError(rows.Err())
+}
+
+// CompleteScheduledTransfer records the transaction a claimed transfer
+// produced.
+func (r *ScheduledTransferRepository) CompleteScheduledTransfer(id, transactionID int64) error {
+	_, err := r.DB.Exec(`UPDATE scheduled_transfers SET status = $1, transaction_id = $2, updated_at = NOW()
+		WHERE id = $3 AND status = $4`,
+		models.ScheduledTransferExecuted, transactionID, id, models.ScheduledTransferProcessing)
+	return translateError(err)
+}
+
+// FailScheduledTransfer records why a claimed transfer was rejected.
+func (r *ScheduledTransferRepository) FailScheduledTransfer(id int64, code, reason string) error {
+	_, err := r.DB.Exec(`UPDATE scheduled_transfers SET status = $1, failure_code = $2, failure_reason = $3, updated_at = NOW()
+		WHERE id = $4 AND status = $5`,
+		models.ScheduledTransferFailed, code, reason, id, models.ScheduledTransferProcessing)
+	return translateError(err)
+}
+
+func scanScheduledTransfer(row rowScanner, extra ...interface{}) (*models.ScheduledTransfer, error) {
+	var s models.ScheduledTransfer
+	var currency, failureCode, failureReason sql.NullString
+	var transactionID sql.NullInt64
+	dest := append([]interface{}{&s.ID, &s.SourceAccountID, &s.DestinationAccountID, &s.Amount.Decimal, &currency,
+		&s.AllowConversion, &s.ExecuteAt, &s.Status, &s.Attempts, &transactionID, &failureCode, &failureReason,
+		&s.CreatedAt, &s.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	s.Currency = models.Currency(currency.String)
+	s.FailureCode, s.FailureReason = failureCode.String, failureReason.String
+	if transactionID.Valid {
+		s.TransactionID = &transactionID.Int64
+	}
+	return &s, nil
+}
@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+)
+
+type StandingOrderRepositoryInterface interface {
+	CreateStandingOrder(o models.StandingOrder) (*models.StandingOrder, error)
+	GetStandingOrder(id int64) (*models.StandingOrder, error)
+	ListStandingOrders(filter models.StandingOrderFilter) ([]models.StandingOrder, error)
+	UpdateStandingOrderStatus(id int64, status string, nextRunAt *time.Time) (*models.StandingOrder, error)
+	ListStandingOrderRuns(id int64, limit int) ([]models.StandingOrderRun, error)
+	ClaimDueStandingOrders(limit int, lease time.Duration) ([]models.StandingOrder, error)
+	RecordStandingOrderRun(run models.StandingOrderRun, nextRunAt, retryAt *time.Time) error
+}
+
+type StandingOrderRepository struct {
+	DB *sql.DB
+}
+
+func NewStandingOrderRepository(db *sql.DB) *StandingOrderRepository {
+	return &StandingOrderRepository{DB: db}
+}
+
+const standingOrderColumns = "id, source_account_id, destination_account_id, amount, currency, allow_conversion, schedule, on_insufficient_funds, max_retries, retry_interval_seconds, status, next_run_at, retry_at, retries, ends_at, created_at, updated_at"
+
+const standingOrderRunColumns = "id, standing_order_id, scheduled_for, attempt, status, transaction_id, failure_code, failure_reason, created_at"
+
+// CreateStandingOrder stores o with its first occurrence in o.NextRunAt.
+func (r *StandingOrderRepository) CreateStandingOrder(o models.StandingOrder) (*models.StandingOrder, error) {
+	var currency interface{}
+	if o.Currency != "" {
+		currency = o.Currency
+	}
+	created, err := scanStandingOrder(r.DB.QueryRow(`INSERT INTO standing_orders (source_account_id, destination_account_id, amount, currency,
+		allow_conversion, schedule, on_insufficient_funds, max_retries, retry_interval_seconds, next_run_at, ends_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING `+standingOrderColumns,
+		o.SourceAccountID, o.DestinationAccountID, o.Amount.String(), currency, o.AllowConversion, o.Schedule,
+		o.OnInsufficientFunds, o.MaxRetries, o.RetryIntervalSeconds, o.NextRunAt.UTC(), o.EndsAt))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return created, nil
+}
+
+func (r *StandingOrderRepository) GetStandingOrder(id int64) (*models.StandingOrder, error) {
+	o, err := scanStandingOrder(r.DB.QueryRow("SELECT "+standingOrderColumns+" FROM standing_orders WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrStandingOrderNotFound, "standing order %d", id)
+	}
+	return o, nil
+}
+
+// ListStandingOrders returns standing orders, oldest first.
+func (r *StandingOrderRepository) ListStandingOrders(filter models.StandingOrderFilter) ([]models.StandingOrder, error) {
+	var conds []string
+	var args []interface{}
+	addCond := func(format string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(format, len(args)))
+	}
+	if filter.AccountID != 0 {
+		addCond("(source_account_id = $%[1]d OR destination_account_id = $%[1]d)", filter.AccountID)
+	}
+	if filter.Status != "" {
+		addCond("status = $%d", filter.Status)
+	}
+
+	query := "SELECT " + standingOrderColumns + " FROM standing_orders"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id LIMIT $%d", len(args))
+
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	orders := []models.StandingOrder{}
+	for rows.Next() {
+		o, err := scanStandingOrder(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		orders = append(orders, *o)
+	}
+	return orders, translateError(rows.Err())
+}
+
+// UpdateStandingOrderStatus pauses, resumes or cancels a standing order.
+// Resuming requires nextRunAt and drops any pending retry.
+func (r *StandingOrderRepository) UpdateStandingOrderStatus(id int64, status string, nextRunAt *time.Time) (*models.StandingOrder, error) {
+	o, err := withRetry(func() (*models.StandingOrder, error) {
+		return r.updateStandingOrderStatus(id, status, nextRunAt)
+	})
+	return o, translateError(err)
+}
+
+func (r *StandingOrderRepository) updateStandingOrderStatus(id int64, status string, nextRunAt *time.Time) (*models.StandingOrder, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRow("SELECT status FROM standing_orders WHERE id = $1 FOR UPDATE", id).Scan(&current)
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrStandingOrderNotFound, "standing order %d", id)
+	}
+	if !models.CanChangeOrderStatus(current, status) {
+		return nil, fmt.Errorf("%w: standing order %d is %s", apperrors.ErrInvalidOrderTransition, id, current)
+	}
+
+	var o *models.StandingOrder
+	if status == models.StandingOrderActive {
+		if nextRunAt == nil {
+			return nil, fmt.Errorf("resuming standing order %d needs its next run time", id)
+		}
+		o, err = scanStandingOrder(tx.QueryRow(`UPDATE standing_orders
+			SET status = $1, next_run_at = $2, retry_at = NULL, retries = 0, updated_at = NOW()
+			WHERE id = $3 RETURNING `+standingOrderColumns, status, nextRunAt.UTC(), id))
+	} else {
+		o, err = scanStandingOrder(tx.QueryRow(`UPDATE standing_orders SET status = $1, updated_at = NOW()
+			WHERE id = $2 RETURNING `+standingOrderColumns, status, id))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// ListStandingOrderRuns returns the latest runs of a standing order, newest
+// first.
+func (r *StandingOrderRepository) ListStandingOrderRuns(id int64, limit int) ([]models.StandingOrderRun, error) {
+	if _, err := r.GetStandingOrder(id); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.DB.Query("SELECT "+standingOrderRunColumns+" FROM standing_order_runs WHERE standing_order_id = $1 ORDER BY id DESC LIMIT $2", id, limit)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	runs := []models.StandingOrderRun{}
+	for rows.Next() {
+		run, err := scanStandingOrderRun(rows)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		runs = append(runs, *run)
+	}
+	return runs, translateError(rows.Err())
+}
+
+// ClaimDueStandingOrders marks up to limit active orders whose run or retry
+// is due as claimed and returns them. Rows locked by another worker are
+// skipped, and an order claimed more than lease ago without a recorded run
+// is claimed again.
+func (r *StandingOrderRepository) ClaimDueStandingOrders(limit int, lease time.Duration) ([]models.StandingOrder, error) {
+	rows, err := r.DB.Query(`UPDATE standing_orders s SET claimed_at = NOW()
+		WHERE id IN (
+			SELECT id FROM standing_orders
+			WHERE status = $1 AND COALESCE(retry_at, next_run_at) <= NOW() AT TIME ZONE 'UTC'
+			  AND (claimed_at IS NULL OR claimed_at <= NOW() - $2 * INTERVAL '1 millisecond')
+			ORDER BY COALESCE(retry_at, next_run_at)
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED)
+		RETURNING `+standingOrderColumns+`,
+			(SELECT transaction_id FROM idempotency_keys
+			 WHERE idempotency_key = $4::text || s.id || '-' || to_char(s.next_run_at, 'YYYYMMDDHH24MI'))`,
+		models.StandingOrderActive, lease.Milliseconds(), limit, models.StandingOrderIdempotencyKeyPrefix)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var claimed []models.StandingOrder
+	for rows.Next() {
+		var submitted sql.NullInt64
+		o, err := scanStandingOrder(rows, &submitted)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		if submitted.Valid {
+			o.SubmittedTransactionID = &submitted.Int64
+		}
+		claimed = append(claimed, *o)
+	}
+	return claimed, translateError(rows.Err())
+}
+
+// RecordStandingOrderRun stores the outcome of a claimed run and releases
+// the claim. With retryAt the same occurrence is attempted again then;
+// otherwise the order moves on to nextRunAt, or completes when that is nil.
+func (r *StandingOrderRepository) RecordStandingOrderRun(run models.StandingOrderRun, nextRunAt, retryAt *time.Time) error {
+	_, err := withRetry(func() (struct{}, error) {
+		return struct{}{}, r.recordStandingOrderRun(run, nextRunAt, retryAt)
+	})
+	return translateError(err)
+}
+
+func (r *StandingOrderRepository) recordStandingOrderRun(run models.StandingOrderRun, nextRunAt, retryAt *time.Time) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var failureCode, failureReason interface{}
+	if run.FailureCode != "" {
+		failureCode, failureReason = run.FailureCode, run.FailureReason
+	}
+	_, err = tx.Exec(`INSERT INTO standing_order_runs (standing_order_id, scheduled_for, attempt, status, transaction_id, failure_code, failure_reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		run.StandingOrderID, run.ScheduledFor, run.Attempt, run.Status, run.TransactionID, failureCode, failureReason)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case retryAt != nil:
+		_, err = tx.Exec(`UPDATE standing_orders SET retry_at = $1, retries = retries + 1, claimed_at = NULL, updated_at = NOW()
+			WHERE id = $2`, retryAt.UTC(), run.StandingOrderID)
+	case nextRunAt != nil:
+		_, err = tx.Exec(`UPDATE standing_orders SET next_run_at = $1, retry_at = NULL, retries = 0, claimed_at = NULL, updated_at = NOW()
+			WHERE id = $2`, nextRunAt.UTC(), run.StandingOrderID)
+	default:
+		// The status is only moved on if the order was not paused or
+		// cancelled while running
+		_, err = tx.Exec(`UPDATE standing_orders SET status = CASE WHEN status = $1 THEN $2 ELSE status END,
+			retry_at = NULL, retries = 0, claimed_at = NULL, updated_at = NOW()
+			WHERE id = $3`, models.StandingOrderActive, models.StandingOrderCompleted, run.StandingOrderID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func scanStandingOrder(row rowScanner, extra ...interface{}) (*models.StandingOrder, error) {
+	var o models.StandingOrder
+	var currency sql.NullString
+	var retryAt, endsAt sql.NullTime
+	dest := append([]interface{}{&o.ID, &o.SourceAccountID, &o.DestinationAccountID, &o.Amount.Decimal, &currency,
+		&o.AllowConversion, &o.Schedule, &o.OnInsufficientFunds, &o.MaxRetries, &o.RetryIntervalSeconds, &o.Status,
+		&o.NextRunAt, &retryAt, &o.Retries, &endsAt, &o.CreatedAt, &o.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	o.Currency = models.Currency(currency.String)
+	if retryAt.Valid {
+		o.RetryAt = &retryAt.Time
+	}
+	if endsAt.Valid {
+		o.EndsAt = &endsAt.Time
+	}
+	return &o, nil
+}
+
+func scanStandingOrderRun(row rowScanner) (*models.StandingOrderRun, error) {
+	var run models.StandingOrderRun
+	var transactionID sql.NullInt64
+	var failureCode, failureReason sql.NullString
+	if err := row.Scan(&run.ID, &run.StandingOrderID, &run.ScheduledFor, &run.Attempt, &run.Status, &transactionID,
+		&failureCode, &failureReason, &run.CreatedAt); err != nil {
+		return nil, err
+	}
+	run.FailureCode, run.FailureReason = failureCode.String, failureReason.String
+	if transactionID.Valid {
+		run.TransactionID = &transactionID.Int64
+	}
+	return &run, nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+)
+
+type StatementRepositoryInterface interface {
+	StreamStatement(accountID int64, from, to time.Time, w models.StatementWriter) error
+}
+
+type StatementRepository struct {
+	DB *sql.DB
+}
+
+func NewStatementRepository(db *sql.DB) *StatementRepository {
+	return &StatementRepository{DB: db}
+}
+
+// StreamStatement reads an account's statement from from up to to and
+// hands it to w one transaction at a time, so no range is held in memory.
+// The opening balance an account was created with has no transaction; it
+// gets a line of its own so the running balance adds up to the closing
+// balance.
+// Everything is read from one snapshot, so the balances and lines agree
+// even while transfers are being made. Errors from w are
+// returned as they are.
+func (r *StatementRepository) StreamStatement(accountID int64, from, to time.Time, w models.StatementWriter) error {
+	return translateError(r.streamStatement(accountID, from, to, w))
+}
+
+func (r *StatementRepository) streamStatement(accountID int64, from, to time.Time, w models.StatementWriter) error {
+	tx, err := r.DB.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	s := models.Statement{AccountID: accountID, From: from, To: to}
+	s.Currency, s.OpeningBalance, err = balanceAt(tx, accountID, from, "<")
+	if err != nil {
+		return notFound(err, apperrors.ErrAccountNotFound, "account %d", accountID)
+	}
+	if err := w.Open(s); err != nil {
+		return err
+	}
+
+	openings, err := openingBalanceLines(tx, accountID, from, to)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(selectTransactionSQL+` WHERE (source_account_id = $1 OR destination_account_id = $1)
+		AND created_at >= $2 AND created_at < $3 ORDER BY id`, accountID, from.UTC(), to.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	balance := s.OpeningBalance
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return err
+		}
+		at := models.NewAccountTransaction(*t, accountID)
+		for len(openings) > 0 && !openings[0].CreatedAt.After(t.CreatedAt) {
+			if balance, err = writeOpeningBalanceLine(w, openings[0], balance); err != nil {
+				return err
+			}
+			openings = openings[1:]
+		}
+		// Transactions recorded before balances were kept on them carry on
+		// from the previous line
+		if at.BalanceAfter != nil {
+			balance = *at.BalanceAfter
+		} else {
+			balance = models.Money{Decimal: balance.Add(at.SignedAmount.Decimal)}
+		}
+		if err := w.Line(models.NewStatementLine(at, balance)); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, l := range openings {
+		if balance, err = writeOpeningBalanceLine(w, l, balance); err != nil {
+			return err
+		}
+	}
+
+	if _, s.ClosingBalance, err = balanceAt(tx, accountID, to, "<"); err != nil {
+		return err
+	}
+	if err := w.Close(s); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// openingBalanceLines reads the opening balance entries posted to
+// accountID from from up to to, oldest first, without their running
+// balance. There is at most one per account, so they are read up front.
+func openingBalanceLines(tx *sql.Tx, accountID int64, from, to time.Time) ([]models.StatementLine, error) {
+	rows, err := tx.Query(`SELECT p.created_at, p.amount,
+			COALESCE((SELECT o.account_id FROM postings o WHERE o.entry_id = j.id AND o.account_id <> $1 LIMIT 1), 0)
+		FROM journal_entries j
+		JOIN postings p ON p.entry_id = j.id AND p.account_id = $1
+		WHERE j.transaction_id IS NULL AND p.created_at >= $2 AND p.created_at < $3
+		ORDER BY j.id`, accountID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lines []models.StatementLine
+	for rows.Next() {
+		var createdAt time.Time
+		var amount models.Money
+		var counterpartyID int64
+		if err := rows.Scan(&createdAt, &amount.Decimal, &counterpartyID); err != nil {
+			return nil, err
+		}
+		lines = append(lines, models.NewOpeningBalanceLine(createdAt, counterpartyID, amount, models.Money{}))
+	}
+	return lines, rows.Err()
+}
+
+// writeOpeningBalanceLine hands l to w with the balance it leaves, which it
+// returns.
+func writeOpeningBalanceLine(w models.StatementWriter, l models.StatementLine, balance models.Money) (models.Money, error) {
+	l.Balance = models.Money{Decimal: balance.Add(l.Amount.Decimal)}
+	return l.Balance, w.Line(l)
+}
@@ -1,17 +1,34 @@
 package repository
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"strings"
+	"time"
+	"transactions/apperrors"
 	"transactions/models"
 
 	"github.com/shopspring/decimal"
 )
 
 type TransactionRepositoryInterface interface {
-	SubmitTransaction(sourceID, destID int64, amount models.Money) error
+	SubmitTransaction(req models.TransferRequest) (*models.Transaction, error)
+	GetIdempotentTransaction(req models.TransferRequest) (*models.Transaction, error)
+	DeleteExpiredIdempotencyKeys(ttl time.Duration, audit models.AuditContext) (int64, error)
+	GetTransaction(id int64) (*models.Transaction, error)
+	GetAccountCurrency(accountID int64) (models.Currency, error)
+	RecordTransferFailed(req models.TransferRequest, cause error) error
+	SubmitBatch(legs []models.TransferRequest, audit models.AuditContext) (*models.TransferBatch, error)
+	ReverseTransaction(id int64, amount *models.Money, audit models.AuditContext) (*models.Transaction, error)
+	ListAccountTransactions(accountID int64, filter models.TransactionFilter) ([]models.AccountTransaction, error)
 }
 
+const transactionColumns = "id, source_account_id, destination_account_id, amount, currency, destination_amount, destination_currency, fx_rate, fx_quote_id, batch_id, created_at, source_balance_after, destination_balance_after, reverses_transaction_id, reversed_amount, fee, fee_schedule"
+
+const selectTransactionSQL = "SELECT " + transactionColumns + " FROM transactions"
+
 type TransactionRepository struct {
 	DB *sql.DB
 }
@@ -20,61 +37,720 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 	return &TransactionRepository{DB: db}
 }
 
-func (r *TransactionRepository) SubmitTransaction(sourceID, destID int64, amount models.Money) error {
+// SubmitTransaction moves req.Amount from the source to the destination
+// account and returns the recorded transaction. When req.IdempotencyKey is
+// set it is stored in the same DB transaction as the transfer, so a retry with
+// the same key and payload returns the original transaction instead of
+// debiting twice. The transfer is recorded in the audit log with
+// req.Audit, and a TransferCompleted event is added to the outbox; replays
+// record neither. Deadlocks and serialization failures are retried.
+func (r *TransactionRepository) SubmitTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	t, err := withRetry(func() (*models.Transaction, error) {
+		return r.submitTransaction(req)
+	})
+	return t, translateError(err)
+}
+
+func (r *TransactionRepository) submitTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	sourceID, destID := req.SourceAccountID, req.DestinationAccountID
+
 	tx, err := r.DB.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
-	amt := amount.Decimal
+	amt := req.Amount.Decimal
 	if amt.LessThanOrEqual(decimal.Zero) {
-		return fmt.Errorf("amount must be positive")
+		return nil, apperrors.ErrInvalidAmount
 	}
 
-	// Check source balance
-	var sourceBalanceStr string
-	err = tx.QueryRow("SELECT balance FROM accounts WHERE account_id = $1 FOR UPDATE", sourceID).Scan(&sourceBalanceStr)
+	// Claim the idempotency key. A concurrent request holding the same key
+	// blocks here until it commits or rolls back.
+	if req.IdempotencyKey != "" {
+		requestHash := hashTransferRequest(req)
+		res, err := tx.Exec(`INSERT INTO idempotency_keys (idempotency_key, request_hash) VALUES ($1, $2) ON CONFLICT (idempotency_key) DO NOTHING`, req.IdempotencyKey, requestHash)
+		if err != nil {
+			return nil, err
+		}
+		claimed, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if claimed == 0 {
+			var storedHash string
+			var transactionID sql.NullInt64
+			err = tx.QueryRow("SELECT request_hash, transaction_id FROM idempotency_keys WHERE idempotency_key = $1", req.IdempotencyKey).Scan(&storedHash, &transactionID)
+			if err != nil {
+				return nil, err
+			}
+			if storedHash != requestHash {
+				return nil, apperrors.ErrIdempotencyKeyReused
+			}
+			if !transactionID.Valid {
+				return nil, fmt.Errorf("idempotency key %q has no recorded transaction", req.IdempotencyKey)
+			}
+			// Replay of a transfer that already committed
+			return scanTransaction(tx.QueryRow(selectTransactionSQL+" WHERE id = $1", transactionID.Int64))
+		}
+	}
+
+	// Lock both accounts in one statement, in ascending id order, so that
+	// opposing transfers between the same pair cannot deadlock. A configured
+	// fee revenue account is locked with them if it still exists.
+	var optional []int64
+	if req.Fee.IsPositive() && req.FeeAccountID != 0 {
+		optional = append(optional, req.FeeAccountID)
+	}
+	accounts, err := lockAccounts(tx, []int64{sourceID, destID}, optional...)
 	if err != nil {
-		return fmt.Errorf("source account not found or error: %w", err)
+		return nil, err
+	}
+	sourceAvailable, sourceCurrency := accounts[sourceID].Available(), accounts[sourceID].Currency
+	destCurrency := accounts[destID].Currency
+	if err := checkTransfer(accounts, sourceID, destID); err != nil {
+		return nil, err
+	}
+
+	// The amount is always expressed in the source account's currency
+	if req.Currency != "" && req.Currency != sourceCurrency {
+		return nil, fmt.Errorf("%w: amount is in %s but source account %d holds %s", apperrors.ErrCurrencyMismatch, req.Currency, sourceID, sourceCurrency)
+	}
+	if err := req.Amount.CheckScale(sourceCurrency); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidAmount, err)
 	}
-	sourceBalance, err := decimal.NewFromString(sourceBalanceStr)
+
+	// Apply an FX quote when one was given or locked for this transfer
+	destAmount := req.Amount
+	var fxRate *decimal.Decimal
+	quoteID := req.QuoteID
+	if quoteID == "" {
+		quoteID = req.LockedQuoteID
+	}
+	if quoteID != "" {
+		rate, err := redeemQuote(tx, quoteID, req.Amount, sourceCurrency, destCurrency)
+		if err != nil {
+			return nil, err
+		}
+		fxRate = &rate
+		destAmount = req.Amount.Convert(rate, destCurrency)
+		if !destAmount.IsPositive() {
+			return nil, fmt.Errorf("%w: converted amount rounds to zero %s", apperrors.ErrInvalidAmount, destCurrency)
+		}
+	} else if sourceCurrency != destCurrency {
+		if !req.AllowConversion {
+			return nil, fmt.Errorf("%w: source account %d holds %s, destination account %d holds %s", apperrors.ErrCurrencyMismatch, sourceID, sourceCurrency, destID, destCurrency)
+		}
+		return nil, fmt.Errorf("%w: %s to %s", apperrors.ErrConversionUnavailable, sourceCurrency, destCurrency)
+	}
+
+	if req.Fee.IsNegative() {
+		return nil, fmt.Errorf("%w: negative fee", apperrors.ErrInvalidAmount)
+	}
+
+	// Funds held by open authorizations cannot be spent; the overdraft
+	// limit can
+	if sourceAvailable.LessThan(amt.Add(req.Fee.Decimal)) {
+		return nil, fmt.Errorf("%w: account %d", apperrors.ErrInsufficientFunds, sourceID)
+	}
+
+	// Log transaction
+	var rateArg, quoteArg interface{}
+	if quoteID != "" {
+		rateArg, quoteArg = fxRate.String(), quoteID
+	}
+	var feeSchedule interface{}
+	if req.FeeSchedule != "" {
+		feeSchedule = req.FeeSchedule
+	}
+	var transactionID int64
+	err = tx.QueryRow(`INSERT INTO transactions (source_account_id, destination_account_id, amount, currency,
+		destination_amount, destination_currency, fx_rate, fx_quote_id, fee, fee_schedule)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		sourceID, destID, req.Amount.String(), sourceCurrency, destAmount.String(), destCurrency,
+		rateArg, quoteArg, req.Fee.String(), feeSchedule).Scan(&transactionID)
 	if err != nil {
-		return fmt.Errorf("invalid source account balance: %w", err)
+		return nil, err
 	}
-	if sourceBalance.LessThan(amt) {
-		return fmt.Errorf("insufficient funds")
+
+	postings, err := transferPostings(tx, sourceID, req.Amount, sourceCurrency, destID, destAmount, destCurrency)
+	if err != nil {
+		return nil, err
 	}
 
-	// Deduct from source
-	newSourceBalance := sourceBalance.Sub(amt)
-	_, err = tx.Exec("UPDATE accounts SET balance = $1 WHERE account_id = $2", newSourceBalance.String(), sourceID)
+	// The fee is posted in the same entry, so it is collected if and only
+	// if the transfer is applied. A revenue account that has since been
+	// removed, closed or frozen does not fail the customer's transfer; the
+	// fee goes to the system account instead.
+	if req.Fee.IsPositive() {
+		feeAccountID := req.FeeAccountID
+		if fee, ok := accounts[feeAccountID]; !ok || fee.Currency != sourceCurrency || fee.checkCanReceive(feeAccountID) != nil {
+			if feeAccountID, err = systemAccountID(tx, models.SystemAccountFees, sourceCurrency); err != nil {
+				return nil, err
+			}
+		}
+		postings = append(postings, debit(sourceID, req.Fee, sourceCurrency), credit(feeAccountID, req.Fee, sourceCurrency))
+	}
+	balances, err := postJournalEntry(tx, models.EntryKindTransfer, &transactionID, postings)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Add to destination
-	var destBalanceStr string
-	err = tx.QueryRow("SELECT balance FROM accounts WHERE account_id = $1 FOR UPDATE", destID).Scan(&destBalanceStr)
+	t, err := scanTransaction(tx.QueryRow(`UPDATE transactions SET source_balance_after = $1, destination_balance_after = $2
+		WHERE id = $3 RETURNING `+transactionColumns,
+		balances[sourceID].String(), balances[destID].String(), transactionID))
 	if err != nil {
-		return fmt.Errorf("destination account not found or error: %w", err)
+		return nil, err
+	}
+
+	if quoteID != "" {
+		_, err = tx.Exec("UPDATE fx_quotes SET transaction_id = $1 WHERE id = $2", t.ID, quoteID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Link the idempotency key to the transaction it produced
+	if req.IdempotencyKey != "" {
+		_, err = tx.Exec("UPDATE idempotency_keys SET transaction_id = $1 WHERE idempotency_key = $2", t.ID, req.IdempotencyKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeTransferCompleted(tx, *t); err != nil {
+		return nil, err
+	}
+	if err := writeAudit(tx, req.Audit, models.AuditActionTransactionCreate, models.AuditEntityTransaction, auditID(t.ID), nil, t); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, err
 	}
-	destBalance, err := decimal.NewFromString(destBalanceStr)
+	return t, nil
+}
+
+// SubmitBatch applies every leg in one DB transaction. All accounts involved
+// are locked up front in ascending id order, each leg is validated, and the
+// batch is rejected as a whole if any leg fails. Legs must be same-currency.
+// Each leg is audited as its own transaction.
+func (r *TransactionRepository) SubmitBatch(legs []models.TransferRequest, audit models.AuditContext) (*models.TransferBatch, error) {
+	b, err := withRetry(func() (*models.TransferBatch, error) {
+		return r.submitBatch(legs, audit)
+	})
+	return b, translateError(err)
+}
+
+func (r *TransactionRepository) submitBatch(legs []models.TransferRequest, audit models.AuditContext) (*models.TransferBatch, error) {
+	if len(legs) == 0 {
+		return nil, fmt.Errorf("%w: batch has no legs", apperrors.ErrInvalidAmount)
+	}
+
+	tx, err := r.DB.Begin()
 	if err != nil {
-		return fmt.Errorf("invalid destination account balance: %w", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var ids []int64
+	seen := map[int64]bool{}
+	for _, leg := range legs {
+		for _, id := range []int64{leg.SourceAccountID, leg.DestinationAccountID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
 	}
-	newDestBalance := destBalance.Add(amt)
-	_, err = tx.Exec("UPDATE accounts SET balance = $1 WHERE account_id = $2", newDestBalance.String(), destID)
+	accounts, err := lockAccounts(tx, ids)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Log transaction
-	_, err = tx.Exec(`INSERT INTO transactions (source_account_id, destination_account_id, amount) VALUES ($1, $2, $3)`, sourceID, destID, amount.String())
+	// Validate every leg and check that no debited account ends up beyond
+	// its overdraft limit once the whole batch is applied.
+	net := map[int64]decimal.Decimal{}
+	for i, leg := range legs {
+		if !leg.Amount.IsPositive() {
+			return nil, fmt.Errorf("%w: leg %d", apperrors.ErrInvalidAmount, i)
+		}
+		source, dest := accounts[leg.SourceAccountID], accounts[leg.DestinationAccountID]
+		if err := checkTransfer(accounts, leg.SourceAccountID, leg.DestinationAccountID); err != nil {
+			return nil, fmt.Errorf("leg %d: %w", i, err)
+		}
+		if source.Currency != dest.Currency || (leg.Currency != "" && leg.Currency != source.Currency) {
+			return nil, fmt.Errorf("%w: leg %d moves %s to %s", apperrors.ErrCurrencyMismatch, i, source.Currency, dest.Currency)
+		}
+		if err := leg.Amount.CheckScale(source.Currency); err != nil {
+			return nil, fmt.Errorf("%w: leg %d: %v", apperrors.ErrInvalidAmount, i, err)
+		}
+		net[leg.SourceAccountID] = net[leg.SourceAccountID].Sub(leg.Amount.Decimal)
+		net[leg.DestinationAccountID] = net[leg.DestinationAccountID].Add(leg.Amount.Decimal)
+	}
+	for _, id := range ids {
+		if net[id].IsNegative() && accounts[id].Available().Add(net[id]).IsNegative() {
+			return nil, fmt.Errorf("%w: account %d", apperrors.ErrInsufficientFunds, id)
+		}
+	}
+
+	batch := &models.TransferBatch{}
+	err = tx.QueryRow("INSERT INTO transfer_batches DEFAULT VALUES RETURNING id, created_at").Scan(&batch.ID, &batch.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, leg := range legs {
+		currency := accounts[leg.SourceAccountID].Currency
+		t, err := postTransfer(tx, models.EntryKindTransfer, leg.SourceAccountID, leg.DestinationAccountID, leg.Amount, currency, &batch.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		batch.Transactions = append(batch.Transactions, *t)
+	}
+	for _, t := range batch.Transactions {
+		if err := writeAudit(tx, audit, models.AuditActionTransactionCreate, models.AuditEntityTransaction, auditID(t.ID), nil, t); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// ReverseTransaction records a compensating transaction that moves amount,
+// in the original's source currency, back from the original destination to
+// the original source. A nil amount reverses whatever is left. Conversions
+// are reversed at the rate originally applied. The audit log records the
+// reversal's creation and the change to the original.
+func (r *TransactionRepository) ReverseTransaction(id int64, amount *models.Money, audit models.AuditContext) (*models.Transaction, error) {
+	t, err := withRetry(func() (*models.Transaction, error) {
+		return r.reverseTransaction(id, amount, audit)
+	})
+	return t, translateError(err)
+}
+
+func (r *TransactionRepository) reverseTransaction(id int64, amount *models.Money, audit models.AuditContext) (*models.Transaction, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the original serializes concurrent reversals of it
+	orig, err := scanTransaction(tx.QueryRow(selectTransactionSQL+" WHERE id = $1 FOR UPDATE", id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrTransactionNotFound, "transaction %d", id)
+	}
+	if orig.ReversesTransactionID != nil {
+		return nil, fmt.Errorf("%w: transaction %d is itself a reversal", apperrors.ErrNotReversible, id)
+	}
+
+	remaining := orig.Amount
+	if orig.ReversedAmount != nil {
+		remaining = models.Money{Decimal: orig.Amount.Sub(orig.ReversedAmount.Decimal)}
+	}
+	if !remaining.IsPositive() {
+		return nil, fmt.Errorf("%w: transaction %d is fully reversed", apperrors.ErrReversalExceedsAmount, id)
+	}
+	refund := remaining
+	if amount != nil {
+		if !amount.IsPositive() {
+			return nil, apperrors.ErrInvalidAmount
+		}
+		if amount.GreaterThan(remaining.Decimal) {
+			return nil, fmt.Errorf("%w: %s %s of transaction %d is left to reverse", apperrors.ErrReversalExceedsAmount, remaining, orig.Currency, id)
+		}
+		if err := amount.CheckScale(orig.Currency); err != nil {
+			return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidAmount, err)
+		}
+		refund = *amount
+	}
+
+	// The amount taken back from the destination, in its own currency. The
+	// reversal that completes the chain takes exactly what is left so that
+	// rounding never leaves a residue.
+	debitAmount := refund
+	if refund.Equal(remaining.Decimal) {
+		var alreadyDebited decimal.Decimal
+		err = tx.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM transactions WHERE reverses_transaction_id = $1", id).Scan(&alreadyDebited)
+		if err != nil {
+			return nil, err
+		}
+		debitAmount = models.Money{Decimal: orig.DestinationAmount.Sub(alreadyDebited)}
+	} else if orig.Currency != orig.DestinationCurrency {
+		debitAmount = refund.Convert(orig.DestinationAmount.Div(orig.Amount.Decimal), orig.DestinationCurrency)
+	}
+	if !debitAmount.IsPositive() {
+		return nil, fmt.Errorf("%w: reversal rounds to zero %s", apperrors.ErrInvalidAmount, orig.DestinationCurrency)
+	}
+
+	sourceID, destID := orig.DestinationAccountID, orig.SourceAccountID
+	accounts, err := lockAccounts(tx, []int64{sourceID, destID})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTransfer(accounts, sourceID, destID); err != nil {
+		return nil, err
+	}
+	if accounts[sourceID].Available().LessThan(debitAmount.Decimal) {
+		return nil, fmt.Errorf("%w: account %d", apperrors.ErrInsufficientFunds, sourceID)
+	}
+
+	var reversalID int64
+	err = tx.QueryRow(`INSERT INTO transactions (source_account_id, destination_account_id, amount, currency,
+		destination_amount, destination_currency, reverses_transaction_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		sourceID, destID, debitAmount.String(), orig.DestinationCurrency, refund.String(), orig.Currency, id).Scan(&reversalID)
+	if err != nil {
+		return nil, err
+	}
+
+	postings, err := transferPostings(tx, sourceID, debitAmount, orig.DestinationCurrency, destID, refund, orig.Currency)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := postJournalEntry(tx, models.EntryKindReversal, &reversalID, postings)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := scanTransaction(tx.QueryRow(`UPDATE transactions SET source_balance_after = $1, destination_balance_after = $2
+		WHERE id = $3 RETURNING `+transactionColumns,
+		balances[sourceID].String(), balances[destID].String(), reversalID))
+	if err != nil {
+		return nil, err
+	}
+
+	reversed, err := scanTransaction(tx.QueryRow("UPDATE transactions SET reversed_amount = reversed_amount + $1 WHERE id = $2 RETURNING "+transactionColumns,
+		refund.String(), id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeTransferCompleted(tx, *t); err != nil {
+		return nil, err
+	}
+	if err := writeAudit(tx, audit, models.AuditActionTransactionCreate, models.AuditEntityTransaction, auditID(t.ID), nil, t); err != nil {
+		return nil, err
+	}
+	if err := writeAudit(tx, audit, models.AuditActionTransactionReverse, models.AuditEntityTransaction, auditID(id), orig, reversed); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// writeTransferCompleted adds a TransferCompleted event for t to the outbox
+func writeTransferCompleted(tx *sql.Tx, t models.Transaction) error {
+	return writeEvent(tx, models.EventTransferCompleted, []int64{t.SourceAccountID, t.DestinationAccountID}, t)
+}
+
+// RecordTransferFailed adds a TransferFailed event for a rejected transfer
+// to the outbox. The transfer's own DB transaction was rolled back, so the
+// event is written in a new one.
+func (r *TransactionRepository) RecordTransferFailed(req models.TransferRequest, cause error) error {
+	return translateError(r.recordTransferFailed(req, cause))
+}
+
+func (r *TransactionRepository) recordTransferFailed(req models.TransferRequest, cause error) error {
+	failure := models.TransferFailure{
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               req.Amount,
+		Currency:             req.Currency,
+		IdempotencyKey:       req.IdempotencyKey,
+		Reason:               cause.Error(),
+	}
+	if e, ok := apperrors.As(cause); ok {
+		failure.Code = e.Code
+	}
+
+	tx, err := r.DB.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	// Commit transaction
+	if err := writeEvent(tx, models.EventTransferFailed, []int64{req.SourceAccountID, req.DestinationAccountID}, failure); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
+
+// transferPostings debits sourceAmount from sourceID and credits destAmount
+// to destID. A conversion goes through the FX position accounts so each
+// currency balances on its own.
+func transferPostings(tx *sql.Tx, sourceID int64, sourceAmount models.Money, sourceCurrency models.Currency,
+	destID int64, destAmount models.Money, destCurrency models.Currency) ([]models.Posting, error) {
+	postings := []models.Posting{debit(sourceID, sourceAmount, sourceCurrency)}
+	if sourceCurrency != destCurrency {
+		fxSourceID, err := systemAccountID(tx, models.SystemAccountFX, sourceCurrency)
+		if err != nil {
+			return nil, err
+		}
+		fxDestID, err := systemAccountID(tx, models.SystemAccountFX, destCurrency)
+		if err != nil {
+			return nil, err
+		}
+		postings = append(postings,
+			credit(fxSourceID, sourceAmount, sourceCurrency),
+			debit(fxDestID, destAmount, destCurrency))
+	}
+	return append(postings, credit(destID, destAmount, destCurrency)), nil
+}
+
+// postTransfer records a same-currency transfer whose accounts are already
+// locked and validated by the caller, posts it to the ledger as a journal
+// entry of kind and adds a TransferCompleted event for it to the outbox.
+// reversesID links a refund to the transaction it undoes.
+func postTransfer(tx *sql.Tx, kind string, sourceID, destID int64, amount models.Money, currency models.Currency, batchID, reversesID *int64) (*models.Transaction, error) {
+	var transactionID int64
+	err := tx.QueryRow(`INSERT INTO transactions (source_account_id, destination_account_id, amount, currency,
+		destination_amount, destination_currency, batch_id, reverses_transaction_id)
+		VALUES ($1, $2, $3, $4, $3, $4, $5, $6) RETURNING id`,
+		sourceID, destID, amount.String(), currency, batchID, reversesID).Scan(&transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := postJournalEntry(tx, kind, &transactionID, []models.Posting{
+		debit(sourceID, amount, currency),
+		credit(destID, amount, currency),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := scanTransaction(tx.QueryRow(`UPDATE transactions SET source_balance_after = $1, destination_balance_after = $2
+		WHERE id = $3 RETURNING `+transactionColumns,
+		balances[sourceID].String(), balances[destID].String(), transactionID))
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTransferCompleted(tx, *t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// DeleteExpiredIdempotencyKeys removes idempotency keys older than ttl and
+// returns how many were deleted. A sweep that deletes anything is audited
+// as one entry carrying the count.
+func (r *TransactionRepository) DeleteExpiredIdempotencyKeys(ttl time.Duration, audit models.AuditContext) (int64, error) {
+	deleted, err := r.deleteExpiredIdempotencyKeys(ttl, audit)
+	return deleted, translateError(err)
+}
+
+func (r *TransactionRepository) deleteExpiredIdempotencyKeys(ttl time.Duration, audit models.AuditContext) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec("DELETE FROM idempotency_keys WHERE created_at < NOW() - $1 * INTERVAL '1 second'", int64(ttl.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		after := map[string]interface{}{"deleted": deleted, "ttl_seconds": int64(ttl.Seconds())}
+		if err := writeAudit(tx, audit, models.AuditActionIdempotencyKeysExpired, models.AuditEntityIdempotencyKey, "", nil, after); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// GetIdempotentTransaction returns the transaction req.IdempotencyKey is
+// already bound to, or nil when the key is unused or its transfer has not
+// committed yet. A key bound to a different payload fails with
+// ErrIdempotencyKeyReused.
+func (r *TransactionRepository) GetIdempotentTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	var storedHash string
+	var transactionID sql.NullInt64
+	err := r.DB.QueryRow("SELECT request_hash, transaction_id FROM idempotency_keys WHERE idempotency_key = $1", req.IdempotencyKey).Scan(&storedHash, &transactionID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if storedHash != hashTransferRequest(req) {
+		return nil, apperrors.ErrIdempotencyKeyReused
+	}
+	if !transactionID.Valid {
+		return nil, nil
+	}
+	t, err := scanTransaction(r.DB.QueryRow(selectTransactionSQL+" WHERE id = $1", transactionID.Int64))
+	return t, translateError(err)
+}
+
+// hashTransferRequest fingerprints the payload an idempotency key is bound to.
+// The amount is normalised so "50" and "50.00" are the same request.
+func hashTransferRequest(req models.TransferRequest) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%s|%s|%t|%s",
+		req.SourceAccountID, req.DestinationAccountID, req.Amount.Decimal.String(), req.Currency, req.AllowConversion, req.QuoteID)))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetTransaction returns a transaction together with its reversal chain.
+func (r *TransactionRepository) GetTransaction(id int64) (*models.Transaction, error) {
+	t, err := scanTransaction(r.DB.QueryRow(selectTransactionSQL+" WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrTransactionNotFound, "transaction %d", id)
+	}
+	if t.ReversedAmount == nil {
+		return t, nil
+	}
+
+	rows, err := r.DB.Query("SELECT id FROM transactions WHERE reverses_transaction_id = $1 ORDER BY id", id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var reversalID int64
+		if err := rows.Scan(&reversalID); err != nil {
+			return nil, translateError(err)
+		}
+		t.Reversals = append(t.Reversals, reversalID)
+	}
+	return t, translateError(rows.Err())
+}
+
+func (r *TransactionRepository) GetAccountCurrency(accountID int64) (models.Currency, error) {
+	var currency models.Currency
+	err := r.DB.QueryRow("SELECT currency FROM accounts WHERE account_id = $1", accountID).Scan(&currency)
+	if err != nil {
+		return "", notFound(err, apperrors.ErrAccountNotFound, "account %d", accountID)
+	}
+	return currency, nil
+}
+
+// ListAccountTransactions returns up to filter.Limit transactions touching
+// accountID, newest first, starting after filter.Cursor.
+func (r *TransactionRepository) ListAccountTransactions(accountID int64, filter models.TransactionFilter) ([]models.AccountTransaction, error) {
+	txs, err := r.listAccountTransactions(accountID, filter)
+	return txs, translateError(err)
+}
+
+func (r *TransactionRepository) listAccountTransactions(accountID int64, filter models.TransactionFilter) ([]models.AccountTransaction, error) {
+	var exists bool
+	if err := r.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM accounts WHERE account_id = $1)", accountID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: account %d", apperrors.ErrAccountNotFound, accountID)
+	}
+
+	args := []interface{}{accountID}
+	var conds []string
+	switch filter.Direction {
+	case models.DirectionDebit:
+		conds = append(conds, "source_account_id = $1")
+	case models.DirectionCredit:
+		conds = append(conds, "destination_account_id = $1")
+	default:
+		conds = append(conds, "(source_account_id = $1 OR destination_account_id = $1)")
+	}
+	addCond := func(expr string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(expr, len(args)))
+	}
+	if filter.Cursor > 0 {
+		addCond("id < $%d", filter.Cursor)
+	}
+	if filter.MinAmount != nil {
+		addCond("amount >= $%d", filter.MinAmount.String())
+	}
+	if filter.MaxAmount != nil {
+		addCond("amount <= $%d", filter.MaxAmount.String())
+	}
+	if filter.CreatedFrom != nil {
+		addCond("created_at >= $%d", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		addCond("created_at < $%d", *filter.CreatedTo)
+	}
+	args = append(args, filter.Limit)
+
+	query := fmt.Sprintf(selectTransactionSQL+" WHERE %s ORDER BY id DESC LIMIT $%d", strings.Join(conds, " AND "), len(args))
+	rows, err := r.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []models.AccountTransaction{}
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, models.NewAccountTransaction(*t, accountID))
+	}
+	return result, rows.Err()
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row rowScanner) (*models.Transaction, error) {
+	var t models.Transaction
+	var fxRate, sourceBalance, destBalance decimal.NullDecimal
+	var quoteID sql.NullString
+	var batchID, reversesID sql.NullInt64
+	var reversed decimal.Decimal
+	var feeSchedule sql.NullString
+	if err := row.Scan(&t.ID, &t.SourceAccountID, &t.DestinationAccountID, &t.Amount.Decimal, &t.Currency,
+		&t.DestinationAmount.Decimal, &t.DestinationCurrency, &fxRate, &quoteID, &batchID,
+		&t.CreatedAt, &sourceBalance, &destBalance, &reversesID, &reversed, &t.Fee.Decimal, &feeSchedule); err != nil {
+		return nil, err
+	}
+	t.FeeSchedule = feeSchedule.String
+	t.TotalDebited = models.Money{Decimal: t.Amount.Add(t.Fee.Decimal)}
+	switch {
+	case reversesID.Valid:
+		t.ReversesTransactionID = &reversesID.Int64
+	case reversed.IsZero():
+		t.ReversalStatus = models.ReversalStatusNone
+	case reversed.LessThan(t.Amount.Decimal):
+		t.ReversalStatus = models.ReversalStatusPartial
+	default:
+		t.ReversalStatus = models.ReversalStatusReversed
+	}
+	if !reversed.IsZero() {
+		t.ReversedAmount = &models.Money{Decimal: reversed}
+	}
+	if batchID.Valid {
+		t.BatchID = &batchID.Int64
+	}
+	if fxRate.Valid {
+		t.FXRate = &fxRate.Decimal
+	}
+	t.FXQuoteID = quoteID.String
+	if sourceBalance.Valid {
+		t.SourceBalanceAfter = &models.Money{Decimal: sourceBalance.Decimal}
+	}
+	if destBalance.Valid {
+		t.DestinationBalanceAfter = &models.Money{Decimal: destBalance.Decimal}
+	}
+	return &t, nil
+}
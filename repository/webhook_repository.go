@@ -0,0 +1,336 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepositoryInterface interface {
+	CreateWebhook(sub models.WebhookSubscription) (*models.WebhookSubscription, error)
+	GetWebhook(id int64) (*models.WebhookSubscription, error)
+	ListWebhooks() ([]models.WebhookSubscription, error)
+	UpdateWebhook(id int64, update models.WebhookSubscriptionUpdate) (*models.WebhookSubscription, error)
+	DeleteWebhook(id int64) error
+	EnqueueWebhookDeliveries(e models.Event) (int64, error)
+	ClaimDueWebhookDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+	RecordWebhookAttempt(deliveryID int64, attempt models.WebhookAttempt, status string, nextAttemptAt *time.Time) error
+	ListWebhookDeliveries(subscriptionID int64, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	GetWebhookDelivery(id int64) (*models.WebhookDelivery, error)
+	RedeliverWebhook(id int64) (*models.WebhookDelivery, error)
+}
+
+const webhookColumns = "id, url, event_types, account_ids, active, created_at, updated_at"
+
+const webhookDeliveryColumns = "id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_error, delivered_at, created_at"
+
+type WebhookRepository struct {
+	DB *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{DB: db}
+}
+
+// CreateWebhook stores a subscription and returns it with its secret.
+func (r *WebhookRepository) CreateWebhook(sub models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	created, err := scanWebhook(r.DB.QueryRow(`INSERT INTO webhook_subscriptions (url, secret, event_types, account_ids)
+		VALUES ($1, $2, $3, $4) RETURNING `+webhookColumns,
+		sub.URL, sub.Secret, pq.Array(sub.EventTypes), pq.Array(sub.AccountIDs)))
+	if err != nil {
+		return nil, translateError(err)
+	}
+	created.Secret = sub.Secret
+	return created, nil
+}
+
+func (r *WebhookRepository) GetWebhook(id int64) (*models.WebhookSubscription, error) {
+	sub, err := scanWebhook(r.DB.QueryRow("SELECT "+webhookColumns+" FROM webhook_subscriptions WHERE id = $1 AND deleted_at IS NULL", id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrWebhookNotFound, "webhook %d", id)
+	}
+	return sub, nil
+}
+
+// ListWebhooks returns every subscription that has not been deleted, oldest
+// first.
+func (r *WebhookRepository) ListWebhooks() ([]models.WebhookSubscription, error) {
+	subs, err := r.listWebhooks()
+	return subs, translateError(err)
+}
+
+func (r *WebhookRepository) listWebhooks() ([]models.WebhookSubscription, error) {
+	rows, err := r.DB.Query("SELECT " + webhookColumns + " FROM webhook_subscriptions WHERE deleted_at IS NULL ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		sub, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// UpdateWebhook changes the fields set in update.
+func (r *WebhookRepository) UpdateWebhook(id int64, update models.WebhookSubscriptionUpdate) (*models.WebhookSubscription, error) {
+	var url, eventTypes, accountIDs interface{}
+	if update.URL != nil {
+		url = *update.URL
+	}
+	if update.EventTypes != nil {
+		eventTypes = pq.Array(update.EventTypes)
+	}
+	if update.AccountIDs != nil {
+		accountIDs = pq.Array(update.AccountIDs)
+	}
+	sub, err := scanWebhook(r.DB.QueryRow(`UPDATE webhook_subscriptions SET
+			url = COALESCE($1, url),
+			event_types = COALESCE($2, event_types),
+			account_ids = COALESCE($3, account_ids),
+			active = COALESCE($4, active),
+			updated_at = NOW()
+		WHERE id = $5 AND deleted_at IS NULL RETURNING `+webhookColumns,
+		url, eventTypes, accountIDs, update.Active, id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrWebhookNotFound, "webhook %d", id)
+	}
+	return sub, nil
+}
+
+// DeleteWebhook stops a subscription for good. Its deliveries are kept, and
+// any still pending are never attempted again.
+func (r *WebhookRepository) DeleteWebhook(id int64) error {
+	res, err := r.DB.Exec("UPDATE webhook_subscriptions SET active = FALSE, deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return translateError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return translateError(err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: webhook %d", apperrors.ErrWebhookNotFound, id)
+	}
+	return nil
+}
+
+// EnqueueWebhookDeliveries creates a pending delivery of e for every active
+// subscription to it, and returns how many were created. An event that
+// was already enqueued is not enqueued again.
+func (r *WebhookRepository) EnqueueWebhookDeliveries(e models.Event) (int64, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	res, err := r.DB.Exec(`INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, next_attempt_at)
+		SELECT id, $1, $2, $3, $4, NOW() FROM webhook_subscriptions
+		WHERE active AND $2 = ANY(event_types)
+		  AND (cardinality(account_ids) = 0 OR account_ids && $5::bigint[])
+		ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		e.ID, e.Type, string(body), models.WebhookDeliveryPending, pq.Array(e.AccountIDs))
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return res.RowsAffected()
+}
+
+// ClaimDueWebhookDeliveries returns up to limit pending deliveries that are
+// due, with their subscription's URL and secret, and pushes their next
+// attempt back by lease. A worker that dies mid-delivery thereby leaves it
+// to be claimed again once the lease runs out. Rows locked by another
+// worker are skipped.
+func (r *WebhookRepository) ClaimDueWebhookDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	rows, err := r.DB.Query(`UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() AT TIME ZONE 'UTC' + $1 * INTERVAL '1 millisecond'
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id AND d.id IN (
+			SELECT dd.id FROM webhook_deliveries dd
+			JOIN webhook_subscriptions ss ON ss.id = dd.subscription_id
+			WHERE dd.status = $2 AND dd.next_attempt_at <= NOW() AT TIME ZONE 'UTC' AND ss.active
+			ORDER BY dd.next_attempt_at
+			LIMIT $3
+			FOR UPDATE OF dd SKIP LOCKED)
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+			d.next_attempt_at, d.last_error, d.delivered_at, d.created_at, s.url, s.secret`,
+		lease.Milliseconds(), models.WebhookDeliveryPending, limit)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+
+	var claimed []models.WebhookDelivery
+	for rows.Next() {
+		var url, secret string
+		d, err := scanWebhookDelivery(rows, &url, &secret)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		d.URL, d.Secret = url, secret
+		claimed = append(claimed, *d)
+	}
+	return claimed, translateError(rows.Err())
+}
+
+// RecordWebhookAttempt logs an attempt and moves the delivery to status.
+// A pending delivery is next attempted at nextAttemptAt.
+func (r *WebhookRepository) RecordWebhookAttempt(deliveryID int64, attempt models.WebhookAttempt, status string, nextAttemptAt *time.Time) error {
+	return translateError(r.recordWebhookAttempt(deliveryID, attempt, status, nextAttemptAt))
+}
+
+func (r *WebhookRepository) recordWebhookAttempt(deliveryID int64, attempt models.WebhookAttempt, status string, nextAttemptAt *time.Time) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var attemptErr, lastErr, next interface{}
+	if attempt.Error != "" {
+		attemptErr, lastErr = attempt.Error, attempt.Error
+	}
+	if nextAttemptAt != nil {
+		next = nextAttemptAt.UTC()
+	}
+	_, err = tx.Exec(`INSERT INTO webhook_delivery_attempts (delivery_id, attempted_at, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5)`,
+		deliveryID, attempt.AttemptedAt.UTC(), attempt.StatusCode, attemptErr, attempt.DurationMS)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, next_attempt_at = $2, last_error = $3,
+			delivered_at = CASE WHEN $1 = $4 THEN $5::timestamp END
+		WHERE id = $6`,
+		status, next, lastErr, models.WebhookDeliveryDelivered, attempt.AttemptedAt.UTC(), deliveryID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListWebhookDeliveries returns a subscription's deliveries, newest first.
+func (r *WebhookRepository) ListWebhookDeliveries(subscriptionID int64, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	deliveries, err := r.listWebhookDeliveries(subscriptionID, filter)
+	return deliveries, translateError(err)
+}
+
+func (r *WebhookRepository) listWebhookDeliveries(subscriptionID int64, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	if _, err := r.GetWebhook(subscriptionID); err != nil {
+		return nil, err
+	}
+
+	var status interface{}
+	if filter.Status != "" {
+		status = filter.Status
+	}
+	rows, err := r.DB.Query(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2::text IS NULL OR status = $2)
+		ORDER BY id DESC LIMIT $3`, subscriptionID, status, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetWebhookDelivery returns a delivery with its attempt log, oldest
+// attempt first.
+func (r *WebhookRepository) GetWebhookDelivery(id int64) (*models.WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(r.DB.QueryRow("SELECT "+webhookDeliveryColumns+" FROM webhook_deliveries WHERE id = $1", id))
+	if err != nil {
+		return nil, notFound(err, apperrors.ErrWebhookDeliveryNotFound, "webhook delivery %d", id)
+	}
+
+	rows, err := r.DB.Query(`SELECT id, attempted_at, status_code, error, duration_ms FROM webhook_delivery_attempts
+		WHERE delivery_id = $1 ORDER BY id`, id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a models.WebhookAttempt
+		var statusCode sql.NullInt64
+		var attemptErr sql.NullString
+		if err := rows.Scan(&a.ID, &a.AttemptedAt, &statusCode, &attemptErr, &a.DurationMS); err != nil {
+			return nil, translateError(err)
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			a.StatusCode = &code
+		}
+		a.Error = attemptErr.String
+		d.AttemptLog = append(d.AttemptLog, a)
+	}
+	return d, translateError(rows.Err())
+}
+
+// RedeliverWebhook makes a delivered or dead delivery pending again, due
+// now and with a fresh allowance of attempts. Its attempt log is kept.
+func (r *WebhookRepository) RedeliverWebhook(id int64) (*models.WebhookDelivery, error) {
+	d, err := scanWebhookDelivery(r.DB.QueryRow(`UPDATE webhook_deliveries
+		SET status = $1, attempts = 0, next_attempt_at = NOW() AT TIME ZONE 'UTC', delivered_at = NULL
+		WHERE id = $2 AND status <> $1 RETURNING `+webhookDeliveryColumns,
+		models.WebhookDeliveryPending, id))
+	if err == sql.ErrNoRows {
+		if _, err := r.GetWebhookDelivery(id); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: webhook delivery %d", apperrors.ErrWebhookDeliveryPending, id)
+	}
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return d, nil
+}
+
+func scanWebhook(row rowScanner) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	if err := row.Scan(&sub.ID, &sub.URL, pq.Array(&sub.EventTypes), pq.Array(&sub.AccountIDs), &sub.Active, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if sub.AccountIDs == nil {
+		sub.AccountIDs = []int64{}
+	}
+	return &sub, nil
+}
+
+func scanWebhookDelivery(row rowScanner, extra ...interface{}) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	var nextAttemptAt, deliveredAt sql.NullTime
+	var lastError sql.NullString
+	dest := append([]interface{}{&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&nextAttemptAt, &lastError, &deliveredAt, &d.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	d.Payload = json.RawMessage(payload)
+	d.LastError = lastError.String
+	if nextAttemptAt.Valid && d.Status == models.WebhookDeliveryPending {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return &d, nil
+}
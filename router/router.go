@@ -1,6 +1,8 @@
 package router
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
 	"time"
 	"transactions/config"
@@ -17,6 +19,24 @@ func loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// requestIDMiddleware gives every request an X-Request-ID, keeping the
+// client's when it sends one, and echoes it in the response so callers can
+// find their changes in the audit log.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(handler.RequestIDHeader)
+		if id == "" {
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err == nil {
+				id = hex.EncodeToString(b)
+				r.Header.Set(handler.RequestIDHeader, id)
+			}
+		}
+		w.Header().Set(handler.RequestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
 func healthcheck(w http.ResponseWriter, r *http.Request) {
 	handler.WriteSuccessResponse(w, http.StatusOK, "server running", map[string]interface{}{
 		"health": "ok",
@@ -27,11 +47,60 @@ func healthcheck(w http.ResponseWriter, r *http.Request) {
 func NewRouter(h *handler.Handler) http.Handler {
 	r := mux.NewRouter()
 	r.Use(loggingMiddleware)
+	r.Use(requestIDMiddleware)
 
 	r.HandleFunc("/health", healthcheck).Methods("GET")
 
 	r.HandleFunc("/accounts", h.Account.CreateAccount).Methods("POST")
+	r.HandleFunc("/accounts/overdrawn", h.Account.ListOverdrawnAccounts).Methods("GET")
 	r.HandleFunc("/accounts/{account_id}", h.Account.GetAccount).Methods("GET")
+	r.HandleFunc("/accounts/{account_id}", h.Account.UpdateAccount).Methods("PATCH")
+	r.HandleFunc("/accounts/{account_id}/overdraft-limit", h.Account.SetOverdraftLimit).Methods("PUT")
+	r.HandleFunc("/accounts/{account_id}/overdraft-limit/changes", h.Account.ListOverdraftLimitChanges).Methods("GET")
+	r.HandleFunc("/accounts/{account_id}/balance", h.Balance.GetBalance).Methods("GET")
+	r.HandleFunc("/accounts/{account_id}/balance-history", h.Balance.ListBalanceHistory).Methods("GET")
+	r.HandleFunc("/accounts/{account_id}/statements", h.Statement.GetStatement).Methods("GET")
+	r.HandleFunc("/accounts/{account_id}/transactions", h.Transaction.ListAccountTransactions).Methods("GET")
+	r.HandleFunc("/accounts/{account_id}/deposits", h.External.Deposit).Methods("POST")
+	r.HandleFunc("/accounts/{account_id}/withdrawals", h.External.Withdraw).Methods("POST")
 	r.HandleFunc("/transactions", h.Transaction.SubmitTransaction).Methods("POST")
+	r.HandleFunc("/transactions/{id}", h.Transaction.GetTransaction).Methods("GET")
+	r.HandleFunc("/transactions/{id}/reversals", h.Transaction.ReverseTransaction).Methods("POST")
+
+	r.HandleFunc("/transfers/batch", h.Transaction.SubmitBatch).Methods("POST")
+	r.HandleFunc("/transfers", h.Transfer.AuthorizeTransfer).Methods("POST")
+	r.HandleFunc("/transfers/{id:[0-9]+}", h.Transfer.GetPendingTransfer).Methods("GET")
+	r.HandleFunc("/transfers/{id:[0-9]+}/capture", h.Transfer.CaptureTransfer).Methods("POST")
+	r.HandleFunc("/transfers/{id:[0-9]+}/void", h.Transfer.VoidTransfer).Methods("POST")
+
+	r.HandleFunc("/scheduled-transfers", h.Scheduled.CreateScheduledTransfer).Methods("POST")
+	r.HandleFunc("/scheduled-transfers", h.Scheduled.ListScheduledTransfers).Methods("GET")
+	r.HandleFunc("/scheduled-transfers/{id:[0-9]+}", h.Scheduled.GetScheduledTransfer).Methods("GET")
+	r.HandleFunc("/scheduled-transfers/{id:[0-9]+}/cancel", h.Scheduled.CancelScheduledTransfer).Methods("POST")
+
+	r.HandleFunc("/standing-orders", h.Standing.CreateStandingOrder).Methods("POST")
+	r.HandleFunc("/standing-orders", h.Standing.ListStandingOrders).Methods("GET")
+	r.HandleFunc("/standing-orders/{id:[0-9]+}", h.Standing.GetStandingOrder).Methods("GET")
+	r.HandleFunc("/standing-orders/{id:[0-9]+}/pause", h.Standing.PauseStandingOrder).Methods("POST")
+	r.HandleFunc("/standing-orders/{id:[0-9]+}/resume", h.Standing.ResumeStandingOrder).Methods("POST")
+	r.HandleFunc("/standing-orders/{id:[0-9]+}/cancel", h.Standing.CancelStandingOrder).Methods("POST")
+	r.HandleFunc("/standing-orders/{id:[0-9]+}/runs", h.Standing.ListStandingOrderRuns).Methods("GET")
+
+	r.HandleFunc("/external-transfers/{id:[0-9]+}", h.External.GetExternalTransfer).Methods("GET")
+
+	r.HandleFunc("/audit", h.Audit.ListAuditEntries).Methods("GET")
+	r.HandleFunc("/audit/verify", h.Audit.VerifyAuditChain).Methods("GET")
+
+	r.HandleFunc("/webhooks", h.Webhook.CreateWebhook).Methods("POST")
+	r.HandleFunc("/webhooks", h.Webhook.ListWebhooks).Methods("GET")
+	r.HandleFunc("/webhooks/{id:[0-9]+}", h.Webhook.GetWebhook).Methods("GET")
+	r.HandleFunc("/webhooks/{id:[0-9]+}", h.Webhook.UpdateWebhook).Methods("PATCH")
+	r.HandleFunc("/webhooks/{id:[0-9]+}", h.Webhook.DeleteWebhook).Methods("DELETE")
+	r.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", h.Webhook.ListWebhookDeliveries).Methods("GET")
+	r.HandleFunc("/webhook-deliveries/{id:[0-9]+}", h.Webhook.GetWebhookDelivery).Methods("GET")
+	r.HandleFunc("/webhook-deliveries/{id:[0-9]+}/redeliver", h.Webhook.RedeliverWebhook).Methods("POST")
+
+	r.HandleFunc("/fx/quotes", h.FX.CreateQuote).Methods("POST")
+	r.HandleFunc("/fx/quotes/{id}", h.FX.GetQuote).Methods("GET")
 	return r
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"transactions/models"
 	"transactions/repository"
 )
 
@@ -12,10 +13,28 @@ func NewAccountService(repo repository.AccountRepositoryInterface) *AccountServi
 	return &AccountService{Repo: repo}
 }
 
-func (s *AccountService) CreateAccount(accountID int64, initialBalance string) error {
-	return s.Repo.CreateAccount(accountID, initialBalance)
+func (s *AccountService) CreateAccount(accountID int64, initialBalance string, currency models.Currency, accountType string, audit models.AuditContext) error {
+	return s.Repo.CreateAccount(accountID, initialBalance, currency, accountType, audit)
 }
 
 func (s *AccountService) GetAccount(accountID int64) (interface{}, error) {
 	return s.Repo.GetAccount(accountID)
 }
+
+// UpdateAccountStatus freezes, unfreezes or closes an account.
+func (s *AccountService) UpdateAccountStatus(accountID int64, change models.AccountStatusChange) (*models.Account, error) {
+	return s.Repo.UpdateAccountStatus(accountID, change)
+}
+
+// SetOverdraftLimit sets how far below zero an account may go.
+func (s *AccountService) SetOverdraftLimit(accountID int64, limit models.Money, reason, changedBy string, audit models.AuditContext) (*models.Account, error) {
+	return s.Repo.SetOverdraftLimit(accountID, limit, reason, changedBy, audit)
+}
+
+func (s *AccountService) ListOverdraftLimitChanges(accountID int64) ([]models.OverdraftLimitChange, error) {
+	return s.Repo.ListOverdraftLimitChanges(accountID)
+}
+
+func (s *AccountService) ListOverdrawnAccounts() ([]models.Account, error) {
+	return s.Repo.ListOverdrawnAccounts()
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"transactions/models"
+	"transactions/repository"
+)
+
+type AuditServiceInterface interface {
+	ListAuditEntries(filter models.AuditFilter) (*models.AuditPage, error)
+	VerifyAuditChain() (*models.AuditVerification, error)
+}
+
+type AuditService struct {
+	Repo repository.AuditRepositoryInterface
+}
+
+func NewAuditService(repo repository.AuditRepositoryInterface) *AuditService {
+	return &AuditService{Repo: repo}
+}
+
+// ListAuditEntries returns one page of the audit log, newest first. Like
+// transaction history it fetches one extra row to find the next cursor.
+func (s *AuditService) ListAuditEntries(filter models.AuditFilter) (*models.AuditPage, error) {
+	filter.Limit = clampPageSize(filter.Limit)
+	limit := filter.Limit
+	filter.Limit++
+
+	entries, err := s.Repo.ListAuditEntries(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = models.EncodeCursor(entries[limit-1].ID)
+	}
+	return page, nil
+}
+
+// VerifyAuditChain recomputes the audit log's hash chain.
+func (s *AuditService) VerifyAuditChain() (*models.AuditVerification, error) {
+	return s.Repo.VerifyAuditChain()
+}
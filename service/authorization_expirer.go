@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"time"
+	"transactions/config"
+	"transactions/models"
+	"transactions/repository"
+)
+
+// expireBatchSize bounds how many authorizations one pass releases
+const expireBatchSize = 500
+
+// AuthorizationExpirer periodically releases the holds of pending transfers
+// whose TTL has elapsed.
+type AuthorizationExpirer struct {
+	Repo     repository.PendingTransferRepositoryInterface
+	Interval time.Duration
+}
+
+func NewAuthorizationExpirer(repo repository.PendingTransferRepositoryInterface, interval time.Duration) *AuthorizationExpirer {
+	return &AuthorizationExpirer{Repo: repo, Interval: interval}
+}
+
+// Run expires once immediately and then on every tick until ctx is cancelled.
+func (e *AuthorizationExpirer) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		e.expire()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *AuthorizationExpirer) expire() {
+	logger := config.GetLogger()
+	expired, err := e.Repo.ExpirePendingTransfers(expireBatchSize, models.AuditContext{Actor: models.AuditActorAuthorizationExpirer})
+	if err != nil {
+		logger.Printf("authorization expirer: %v", err)
+	}
+	if expired > 0 {
+		logger.Printf("authorization expirer: released %d expired authorizations", expired)
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"time"
+	"transactions/models"
+	"transactions/repository"
+)
+
+type BalanceServiceInterface interface {
+	GetBalanceAt(accountID int64, at time.Time) (*models.AccountBalance, error)
+	ListBalanceSnapshots(accountID int64, from, to time.Time) ([]models.BalanceSnapshot, error)
+}
+
+type BalanceService struct {
+	Repo repository.BalanceRepositoryInterface
+}
+
+func NewBalanceService(repo repository.BalanceRepositoryInterface) *BalanceService {
+	return &BalanceService{Repo: repo}
+}
+
+// GetBalanceAt returns what an account held at a point in time, reading
+// the nearest end-of-day snapshot and the postings after it.
+func (s *BalanceService) GetBalanceAt(accountID int64, at time.Time) (*models.AccountBalance, error) {
+	return s.Repo.GetBalanceAt(accountID, at)
+}
+
+func (s *BalanceService) ListBalanceSnapshots(accountID int64, from, to time.Time) ([]models.BalanceSnapshot, error) {
+	return s.Repo.ListBalanceSnapshots(accountID, from, to)
+}
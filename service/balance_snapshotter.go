@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"time"
+	"transactions/config"
+	"transactions/models"
+	"transactions/repository"
+)
+
+// snapshotSettleTime is how long after midnight a day is left open, so
+// transactions that began before it have committed when it is snapshotted.
+const snapshotSettleTime = 5 * time.Minute
+
+// BalanceSnapshotter periodically records the end-of-day balance of every
+// account for each UTC day that has ended, catching up on any days missed
+// while the service was down.
+type BalanceSnapshotter struct {
+	Repo     repository.BalanceRepositoryInterface
+	Interval time.Duration
+	Now      func() time.Time
+}
+
+func NewBalanceSnapshotter(repo repository.BalanceRepositoryInterface, interval time.Duration) *BalanceSnapshotter {
+	return &BalanceSnapshotter{Repo: repo, Interval: interval, Now: time.Now}
+}
+
+// Run snapshots once immediately and then on every tick until ctx is
+// cancelled.
+func (s *BalanceSnapshotter) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.SnapshotDays()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SnapshotDays snapshots every day from the first one without snapshots
+// through the last day that has ended and settled. It returns how many days
+// were snapshotted.
+func (s *BalanceSnapshotter) SnapshotDays() int {
+	logger := config.GetLogger()
+	next, err := s.Repo.NextSnapshotDate()
+	if err != nil {
+		logger.Printf("balance snapshots: %v", err)
+		return 0
+	}
+	if next == nil {
+		return 0
+	}
+
+	now := s.Now().UTC().Add(-snapshotSettleTime)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	days := 0
+	for day := time.Date(next.Year(), next.Month(), next.Day(), 0, 0, 0, 0, time.UTC); day.Before(today); day = day.AddDate(0, 0, 1) {
+		n, err := s.Repo.SnapshotBalances(day)
+		if err != nil {
+			logger.Printf("balance snapshots: %s: %v", day.Format(models.SnapshotDateFormat), err)
+			return days
+		}
+		logger.Printf("balance snapshots: %s: recorded %d balances", day.Format(models.SnapshotDateFormat), n)
+		days++
+	}
+	return days
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+	"transactions/payments"
+	"transactions/repository"
+)
+
+type ExternalTransferServiceInterface interface {
+	Deposit(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error)
+	Withdraw(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error)
+	GetExternalTransfer(id int64) (*models.ExternalTransfer, error)
+}
+
+type ExternalTransferService struct {
+	Repo repository.ExternalTransferRepositoryInterface
+	Rail payments.PaymentRail
+	// Timeout bounds each call to the rail.
+	Timeout time.Duration
+	// Limits enforces transfer limits and velocity rules on withdrawals. Nil
+	// disables them.
+	Limits *LimitEngine
+}
+
+func NewExternalTransferService(repo repository.ExternalTransferRepositoryInterface, rail payments.PaymentRail, timeout time.Duration, limits *LimitEngine) *ExternalTransferService {
+	return &ExternalTransferService{Repo: repo, Rail: rail, Timeout: timeout, Limits: limits}
+}
+
+// Deposit records a deposit and asks the rail to collect it, crediting the
+// account once it has.
+func (s *ExternalTransferService) Deposit(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	t.Direction, t.Rail = models.ExternalDeposit, s.Rail.Name()
+	d, err := s.Repo.CreateDeposit(t, audit)
+	if err != nil {
+		return nil, err
+	}
+	return s.settle(d, s.Rail.Collect, audit)
+}
+
+// Withdraw debits a withdrawal and asks the rail to pay it out, refunding
+// the account if the rail declines. A new withdrawal is first checked
+// against the limit rules; a retry of a recorded one already counts
+// towards them and is not checked again.
+func (s *ExternalTransferService) Withdraw(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	t.Direction, t.Rail = models.ExternalWithdrawal, s.Rail.Name()
+	if s.Limits != nil {
+		existing, err := s.Repo.FindExternalTransfer(t.Direction, t.ExternalReference)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			if err := s.Limits.Check(models.TransferRequest{SourceAccountID: t.AccountID, Amount: t.Amount, Currency: t.Currency}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	w, err := s.Repo.CreateWithdrawal(t, audit)
+	if err != nil {
+		return nil, err
+	}
+	return s.settle(w, s.Rail.Payout, audit)
+}
+
+func (s *ExternalTransferService) GetExternalTransfer(id int64) (*models.ExternalTransfer, error) {
+	return s.Repo.GetExternalTransfer(id)
+}
+
+// settle hands a pending transfer to the rail and records the outcome. A
+// retry of a completed transfer returns it and a retry of a declined one
+// fails again without asking the rail. When the rail's answer is unknown
+// the transfer stays pending; retrying the request with the same external
+// reference asks the rail again under the same payment reference. The
+// outcome is audited as the rail's, under the request that asked for it.
+func (s *ExternalTransferService) settle(t *models.ExternalTransfer, move func(context.Context, payments.Payment) (string, error), audit models.AuditContext) (*models.ExternalTransfer, error) {
+	switch t.Status {
+	case models.ExternalTransferCompleted:
+		return t, nil
+	case models.ExternalTransferFailed:
+		return nil, fmt.Errorf("%w: %s %d: %s", apperrors.ErrPaymentDeclined, t.Direction, t.ID, t.FailureReason)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+	railRef, err := move(ctx, payments.Payment{
+		Reference:     fmt.Sprintf("%s-%d", t.Direction, t.ID),
+		AccountID:     t.AccountID,
+		Amount:        t.Amount,
+		Currency:      t.Currency,
+		FundingSource: t.FundingSource,
+	})
+	audit.Actor = models.AuditActorPaymentRail
+	if errors.Is(err, apperrors.ErrPaymentDeclined) {
+		if _, ferr := s.Repo.FailExternalTransfer(t.ID, err.Error(), audit); ferr != nil {
+			return nil, ferr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %d on %s: %v", apperrors.ErrPaymentRailUnavailable, t.Direction, t.ID, t.Rail, err)
+	}
+	return s.Repo.CompleteExternalTransfer(t.ID, railRef, audit)
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"transactions/models"
+	"transactions/repository"
+)
+
+// FeeScheduleSource supplies the fee schedules to charge.
+type FeeScheduleSource interface {
+	FeeSchedules() ([]models.FeeSchedule, error)
+}
+
+// StaticFeeSchedules is a fixed set of schedules, typically read from a
+// config file.
+type StaticFeeSchedules []models.FeeSchedule
+
+func (s StaticFeeSchedules) FeeSchedules() ([]models.FeeSchedule, error) {
+	return s, nil
+}
+
+// LoadFeeSchedulesFile reads a JSON array of fee schedules:
+//
+//	[{"name": "default", "type": "percentage", "percentage": "0.01", "min_fee": "0.50", "max_fee": "25"}]
+func LoadFeeSchedulesFile(path string) (StaticFeeSchedules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var schedules []models.FeeSchedule
+	if err := json.Unmarshal(b, &schedules); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, s := range schedules {
+		if err := validateFeeSchedule(s); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return schedules, nil
+}
+
+func validateFeeSchedule(s models.FeeSchedule) error {
+	if s.Name == "" {
+		return fmt.Errorf("fee schedule without a name")
+	}
+	if s.AccountID != 0 && s.AccountType != "" {
+		return fmt.Errorf("fee schedule %s sets both account_id and account_type", s.Name)
+	}
+	switch s.Type {
+	case models.FeeFlat:
+		if s.Flat == nil {
+			return fmt.Errorf("fee schedule %s needs a flat amount", s.Name)
+		}
+	case models.FeePercentage:
+		if s.Percentage == nil {
+			return fmt.Errorf("fee schedule %s needs a percentage", s.Name)
+		}
+	case models.FeeTiered:
+		if len(s.Tiers) == 0 {
+			return fmt.Errorf("fee schedule %s needs tiers", s.Name)
+		}
+	default:
+		return fmt.Errorf("fee schedule %s has unknown type %q", s.Name, s.Type)
+	}
+	return nil
+}
+
+// FeeEngine prices transfers. The most specific schedule matching the
+// source account wins: one for the account itself, then one for its type,
+// then a default. Schedules with a currency only match accounts in it.
+type FeeEngine struct {
+	Schedules FeeScheduleSource
+	Accounts  repository.FeeRepositoryInterface
+	// RevenueAccountID receives fees in its currency. Fees in other
+	// currencies go to the fees:<CUR> system account.
+	RevenueAccountID int64
+}
+
+func NewFeeEngine(schedules FeeScheduleSource, accounts repository.FeeRepositoryInterface, revenueAccountID int64) *FeeEngine {
+	return &FeeEngine{Schedules: schedules, Accounts: accounts, RevenueAccountID: revenueAccountID}
+}
+
+// Apply sets the fee for req, leaving it zero when no schedule matches.
+func (e *FeeEngine) Apply(req *models.TransferRequest) error {
+	schedules, err := e.Schedules.FeeSchedules()
+	if err != nil {
+		return err
+	}
+	if len(schedules) == 0 {
+		return nil
+	}
+
+	currency, accountType, err := e.Accounts.AccountFeeProfile(req.SourceAccountID)
+	if err != nil {
+		return err
+	}
+
+	var best *models.FeeSchedule
+	bestRank := 0
+	for i, s := range schedules {
+		if s.Currency != "" && s.Currency != currency {
+			continue
+		}
+		rank := 1
+		switch {
+		case s.AccountID != 0 && s.AccountID != req.SourceAccountID,
+			s.AccountType != "" && s.AccountType != accountType:
+			continue
+		case s.AccountID != 0:
+			rank = 3
+		case s.AccountType != "":
+			rank = 2
+		}
+		if rank > bestRank {
+			best, bestRank = &schedules[i], rank
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	req.Fee = best.Fee(req.Amount, currency)
+	req.FeeSchedule = best.Name
+	req.FeeAccountID = e.RevenueAccountID
+	return nil
+}
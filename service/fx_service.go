@@ -0,0 +1,57 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+	"transactions/fx"
+	"transactions/models"
+	"transactions/repository"
+)
+
+type FXServiceInterface interface {
+	CreateQuote(source, dest models.Currency, amount *models.Money) (*models.FXQuote, error)
+	GetQuote(id string) (*models.FXQuote, error)
+}
+
+type FXService struct {
+	Repo     repository.FXRepositoryInterface
+	Rates    fx.RateProvider
+	QuoteTTL time.Duration
+}
+
+func NewFXService(repo repository.FXRepositoryInterface, rates fx.RateProvider, quoteTTL time.Duration) *FXService {
+	return &FXService{Repo: repo, Rates: rates, QuoteTTL: quoteTTL}
+}
+
+// CreateQuote locks the current rate for source to dest. When amount is
+// given the quote can only be redeemed for exactly that source amount.
+func (s *FXService) CreateQuote(source, dest models.Currency, amount *models.Money) (*models.FXQuote, error) {
+	rate, err := s.Rates.Rate(source, dest)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newQuoteID()
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.CreateQuote(&models.FXQuote{
+		ID:                  id,
+		SourceCurrency:      source,
+		DestinationCurrency: dest,
+		Rate:                rate,
+		SourceAmount:        amount,
+	}, s.QuoteTTL)
+}
+
+func (s *FXService) GetQuote(id string) (*models.FXQuote, error) {
+	return s.Repo.GetQuote(id)
+}
+
+func newQuoteID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "q_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"time"
+	"transactions/config"
+	"transactions/models"
+	"transactions/repository"
+)
+
+// IdempotencySweeper periodically deletes idempotency keys that are older
+// than the configured retention window.
+type IdempotencySweeper struct {
+	Repo     repository.TransactionRepositoryInterface
+	TTL      time.Duration
+	Interval time.Duration
+}
+
+func NewIdempotencySweeper(repo repository.TransactionRepositoryInterface, ttl, interval time.Duration) *IdempotencySweeper {
+	return &IdempotencySweeper{Repo: repo, TTL: ttl, Interval: interval}
+}
+
+// Run sweeps once immediately and then on every tick until ctx is cancelled.
+func (s *IdempotencySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *IdempotencySweeper) sweep() {
+	logger := config.GetLogger()
+	deleted, err := s.Repo.DeleteExpiredIdempotencyKeys(s.TTL, models.AuditContext{Actor: models.AuditActorIdempotencySweeper})
+	if err != nil {
+		logger.Printf("idempotency sweeper: %v", err)
+		return
+	}
+	if deleted > 0 {
+		logger.Printf("idempotency sweeper: removed %d expired keys", deleted)
+	}
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+	"transactions/repository"
+
+	"github.com/shopspring/decimal"
+)
+
+// LimitRuleSource supplies the limit rules to enforce.
+type LimitRuleSource interface {
+	LimitRules() ([]models.LimitRule, error)
+}
+
+// StaticLimitRules is a fixed rule set, typically read from a config file.
+type StaticLimitRules []models.LimitRule
+
+func (s StaticLimitRules) LimitRules() ([]models.LimitRule, error) {
+	return s, nil
+}
+
+// LoadLimitRulesFile reads a JSON array of limit rules:
+//
+//	[{"name": "usd-daily", "type": "daily_outgoing", "currency": "USD", "amount": "5000"},
+//	 {"name": "hourly-count", "type": "hourly_count", "max_count": 20}]
+func LoadLimitRulesFile(path string) (StaticLimitRules, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []models.LimitRule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for _, rule := range rules {
+		if err := validateLimitRule(rule); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return rules, nil
+}
+
+func validateLimitRule(rule models.LimitRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("limit rule without a name")
+	}
+	switch rule.Type {
+	case models.LimitMaxAmount, models.LimitDailyOutgoing, models.LimitMonthlyOutgoing:
+		if rule.Amount == nil || !rule.Amount.IsPositive() {
+			return fmt.Errorf("limit rule %s needs a positive amount", rule.Name)
+		}
+	case models.LimitHourlyCount:
+		if rule.MaxCount <= 0 {
+			return fmt.Errorf("limit rule %s needs a positive max_count", rule.Name)
+		}
+	default:
+		return fmt.Errorf("limit rule %s has unknown type %q", rule.Name, rule.Type)
+	}
+	return nil
+}
+
+// LimitEngine checks money leaving an account against the configured limit
+// rules before it moves: transfers, batch legs, authorizations and
+// withdrawals. The check reads usage outside the transfer's DB
+// transaction, so concurrent transfers from one account can overshoot a cap
+// by the transfers in flight.
+type LimitEngine struct {
+	Rules LimitRuleSource
+	Usage repository.LimitRepositoryInterface
+	Now   func() time.Time
+}
+
+func NewLimitEngine(rules LimitRuleSource, usage repository.LimitRepositoryInterface) *LimitEngine {
+	return &LimitEngine{Rules: rules, Usage: usage, Now: time.Now}
+}
+
+// Check returns an error naming the first rule req would breach.
+func (e *LimitEngine) Check(req models.TransferRequest) error {
+	return e.CheckBatch([]models.TransferRequest{req})
+}
+
+// CheckBatch checks transfers applied together. Each is held to the per
+// transfer maximum, while the transfers of one source account count
+// towards its caps together.
+func (e *LimitEngine) CheckBatch(reqs []models.TransferRequest) error {
+	rules, err := e.Rules.LimitRules()
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var sources []int64
+	amounts := map[int64][]models.Money{}
+	for _, req := range reqs {
+		if _, ok := amounts[req.SourceAccountID]; !ok {
+			sources = append(sources, req.SourceAccountID)
+		}
+		amounts[req.SourceAccountID] = append(amounts[req.SourceAccountID], req.Amount)
+	}
+
+	windows := models.NewUsageWindows(e.Now())
+	for _, accountID := range sources {
+		usage, err := e.Usage.OutgoingUsage(accountID, windows)
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			if rule.AccountID != 0 && rule.AccountID != accountID {
+				continue
+			}
+			if rule.Currency != "" && rule.Currency != usage.Currency {
+				continue
+			}
+			if err := checkLimitRule(rule, amounts[accountID], usage); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkLimitRule(rule models.LimitRule, amounts []models.Money, usage *models.OutgoingUsage) error {
+	total := decimal.Zero
+	for _, amount := range amounts {
+		total = total.Add(amount.Decimal)
+	}
+
+	switch rule.Type {
+	case models.LimitMaxAmount:
+		for _, amount := range amounts {
+			if amount.GreaterThan(rule.Amount.Decimal) {
+				return fmt.Errorf("%w: rule %s allows at most %s %s per transfer", apperrors.ErrTransferLimitExceeded, rule.Name, rule.Amount, usage.Currency)
+			}
+		}
+	case models.LimitDailyOutgoing:
+		if usage.Today.Add(total).GreaterThan(rule.Amount.Decimal) {
+			return fmt.Errorf("%w: rule %s allows %s %s per day, %s already sent", apperrors.ErrDailyLimitExceeded, rule.Name, rule.Amount, usage.Currency, usage.Today)
+		}
+	case models.LimitMonthlyOutgoing:
+		if usage.ThisMonth.Add(total).GreaterThan(rule.Amount.Decimal) {
+			return fmt.Errorf("%w: rule %s allows %s %s per month, %s already sent", apperrors.ErrMonthlyLimitExceeded, rule.Name, rule.Amount, usage.Currency, usage.ThisMonth)
+		}
+	case models.LimitHourlyCount:
+		if usage.LastHour+len(amounts) > rule.MaxCount {
+			return fmt.Errorf("%w: rule %s allows %d transfers per hour", apperrors.ErrVelocityLimitExceeded, rule.Name, rule.MaxCount)
+		}
+	}
+	return nil
+}
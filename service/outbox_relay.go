@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"time"
+	"transactions/config"
+	"transactions/events"
+	"transactions/models"
+	"transactions/repository"
+)
+
+// OutboxRelay publishes the events recorded in the outbox.
+type OutboxRelay struct {
+	Repo      repository.OutboxRepositoryInterface
+	Publisher events.Publisher
+	Interval  time.Duration
+	BatchSize int
+	// Timeout bounds each call to the publisher.
+	Timeout time.Duration
+}
+
+func NewOutboxRelay(repo repository.OutboxRepositoryInterface, publisher events.Publisher, interval time.Duration, batchSize int, timeout time.Duration) *OutboxRelay {
+	return &OutboxRelay{Repo: repo, Publisher: publisher, Interval: interval, BatchSize: batchSize, Timeout: timeout}
+}
+
+// Run relays once immediately and then on every tick until ctx is
+// cancelled. A pass that publishes a full batch is followed straight away
+// by the next one.
+func (o *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+
+	for {
+		for o.Relay(ctx) >= o.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay makes one pass over the outbox and returns how many events it
+// published.
+func (o *OutboxRelay) Relay(ctx context.Context) int {
+	logger := config.GetLogger()
+	blocked := map[int64]bool{}
+	published, err := o.Repo.RelayOutbox(o.BatchSize, func(page []models.Event) map[int64]error {
+		return o.publish(ctx, page, blocked)
+	})
+	if err != nil {
+		logger.Printf("outbox relay: %v", err)
+	}
+	return published
+}
+
+// publish hands a page of the pass to the publisher in order. Once an
+// event fails, its accounts are blocked for the rest of the pass: later
+// events sharing one of them are held back, and block their own accounts
+// in turn, so that no account's events overtake each other. Held events
+// are retried on the next pass. It returns nil once ctx is done.
+func (o *OutboxRelay) publish(ctx context.Context, page []models.Event, blocked map[int64]bool) map[int64]error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	logger := config.GetLogger()
+	outcomes := map[int64]error{}
+
+	for _, e := range page {
+		if ctx.Err() != nil {
+			break
+		}
+		held := false
+		for _, id := range e.AccountIDs {
+			held = held || blocked[id]
+		}
+		if held {
+			for _, id := range e.AccountIDs {
+				blocked[id] = true
+			}
+			continue
+		}
+
+		pubCtx, cancel := context.WithTimeout(ctx, o.Timeout)
+		err := o.Publisher.Publish(pubCtx, e)
+		cancel()
+		outcomes[e.ID] = err
+		if err != nil {
+			logger.Printf("outbox relay: publishing %s event %d to %s: %v", e.Type, e.ID, o.Publisher.Name(), err)
+			for _, id := range e.AccountIDs {
+				blocked[id] = true
+			}
+		}
+	}
+	return outcomes
+}
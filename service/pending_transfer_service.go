@@ -0,0 +1,53 @@
+package service
+
+import (
+	"time"
+	"transactions/models"
+	"transactions/repository"
+)
+
+type PendingTransferServiceInterface interface {
+	AuthorizeTransfer(req models.TransferRequest) (*models.PendingTransfer, error)
+	CaptureTransfer(id int64, amount *models.Money, audit models.AuditContext) (*models.PendingTransfer, error)
+	VoidTransfer(id int64, audit models.AuditContext) (*models.PendingTransfer, error)
+	GetPendingTransfer(id int64) (*models.PendingTransfer, error)
+}
+
+type PendingTransferService struct {
+	Repo repository.PendingTransferRepositoryInterface
+	// TTL is how long an authorization holds funds before it expires
+	TTL time.Duration
+	// Limits enforces transfer limits and velocity rules. Nil disables them.
+	Limits *LimitEngine
+}
+
+func NewPendingTransferService(repo repository.PendingTransferRepositoryInterface, ttl time.Duration, limits *LimitEngine) *PendingTransferService {
+	return &PendingTransferService{Repo: repo, TTL: ttl, Limits: limits}
+}
+
+// AuthorizeTransfer reserves req.Amount on the source account. The funds
+// stay in the ledger balance but leave the available balance until the
+// transfer is captured, voided or expires. The limit rules are checked
+// when authorizing; a pending authorization counts towards them, and so
+// does its capture in its place.
+func (s *PendingTransferService) AuthorizeTransfer(req models.TransferRequest) (*models.PendingTransfer, error) {
+	if s.Limits != nil {
+		if err := s.Limits.Check(req); err != nil {
+			return nil, err
+		}
+	}
+	return s.Repo.AuthorizeTransfer(req, s.TTL)
+}
+
+// CaptureTransfer settles the authorization, in full when amount is nil.
+func (s *PendingTransferService) CaptureTransfer(id int64, amount *models.Money, audit models.AuditContext) (*models.PendingTransfer, error) {
+	return s.Repo.CaptureTransfer(id, amount, audit)
+}
+
+func (s *PendingTransferService) VoidTransfer(id int64, audit models.AuditContext) (*models.PendingTransfer, error) {
+	return s.Repo.VoidTransfer(id, audit)
+}
+
+func (s *PendingTransferService) GetPendingTransfer(id int64) (*models.PendingTransfer, error) {
+	return s.Repo.GetPendingTransfer(id)
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"time"
+	"transactions/config"
+	"transactions/models"
+	"transactions/repository"
+)
+
+// Reconciler verifies cached account balances against the ledger, on
+// demand or periodically.
+type Reconciler struct {
+	Repo     repository.ReconciliationRepositoryInterface
+	Interval time.Duration
+}
+
+func NewReconciler(repo repository.ReconciliationRepositoryInterface, interval time.Duration) *Reconciler {
+	return &Reconciler{Repo: repo, Interval: interval}
+}
+
+// Run reconciles on every tick until ctx is cancelled, recording each run.
+// Unlike the other workers it waits a full interval before the first run,
+// so restarts do not each scan the whole ledger.
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		r.Reconcile(true)
+	}
+}
+
+// Reconcile recomputes every balance from the ledger and logs any drift or
+// unconserved currency. With record set the report is also stored in
+// reconciliation_runs.
+func (r *Reconciler) Reconcile(record bool) (*models.ReconciliationReport, error) {
+	logger := config.GetLogger()
+	report, err := r.Repo.Reconcile()
+	if err != nil {
+		logger.Printf("reconciliation: %v", err)
+		return nil, err
+	}
+
+	for _, d := range report.Drifts {
+		logger.Printf("reconciliation: account %d balance %s %s, ledger %s, drift %s",
+			d.AccountID, d.Balance.String(), d.Currency, d.LedgerBalance.String(), d.Drift.String())
+	}
+	for _, c := range report.Currencies {
+		if !c.Conserved() {
+			logger.Printf("reconciliation: %s not conserved: balances sum to %s, postings to %s",
+				c.Currency, c.BalancesTotal.String(), c.PostingsTotal.String())
+		}
+	}
+	logger.Printf("reconciliation: checked %d accounts, %d drifted, balanced: %t",
+		report.AccountsChecked, len(report.Drifts), report.Balanced)
+
+	if record {
+		id, err := r.Repo.RecordReconciliationRun(*report)
+		if err != nil {
+			logger.Printf("reconciliation: recording run: %v", err)
+			return report, err
+		}
+		report.RunID = &id
+	}
+	return report, nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"time"
+	"transactions/models"
+	"transactions/repository"
+)
+
+type ScheduledTransferServiceInterface interface {
+	CreateScheduledTransfer(req models.TransferRequest, executeAt time.Time) (*models.ScheduledTransfer, error)
+	GetScheduledTransfer(id int64) (*models.ScheduledTransfer, error)
+	ListScheduledTransfers(filter models.ScheduledTransferFilter) ([]models.ScheduledTransfer, error)
+	CancelScheduledTransfer(id int64) (*models.ScheduledTransfer, error)
+}
+
+type ScheduledTransferService struct {
+	Repo repository.ScheduledTransferRepositoryInterface
+}
+
+func NewScheduledTransferService(repo repository.ScheduledTransferRepositoryInterface) *ScheduledTransferService {
+	return &ScheduledTransferService{Repo: repo}
+}
+
+// CreateScheduledTransfer stores req for the scheduled transfer worker to
+// submit at executeAt.
+func (s *ScheduledTransferService) CreateScheduledTransfer(req models.TransferRequest, executeAt time.Time) (*models.ScheduledTransfer, error) {
+	return s.Repo.CreateScheduledTransfer(req, executeAt)
+}
+
+func (s *ScheduledTransferService) GetScheduledTransfer(id int64) (*models.ScheduledTransfer, error) {
+	return s.Repo.GetScheduledTransfer(id)
+}
+
+func (s *ScheduledTransferService) ListScheduledTransfers(filter models.ScheduledTransferFilter) ([]models.ScheduledTransfer, error) {
+	filter.Limit = clampPageSize(filter.Limit)
+	return s.Repo.ListScheduledTransfers(filter)
+}
+
+// CancelScheduledTransfer cancels a transfer the worker has not picked up.
+func (s *ScheduledTransferService) CancelScheduledTransfer(id int64) (*models.ScheduledTransfer, error) {
+	return s.Repo.CancelScheduledTransfer(id)
+}
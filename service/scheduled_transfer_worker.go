@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"time"
+	"transactions/apperrors"
+	"transactions/config"
+	"transactions/repository"
+)
+
+// scheduledBatchSize bounds how many due transfers one pass claims
+const scheduledBatchSize = 100
+
+// ScheduledTransferWorker periodically submits scheduled transfers that have
+// fallen due. They go through the same path as POST /transactions, limits
+// and fees included, under an idempotency key derived from the schedule id.
+type ScheduledTransferWorker struct {
+	Repo         repository.ScheduledTransferRepositoryInterface
+	Transactions TransactionServiceInterface
+	Interval     time.Duration
+	// Lease is how long a claimed transfer may go without a recorded
+	// outcome before it is claimed again.
+	Lease time.Duration
+}
+
+func NewScheduledTransferWorker(repo repository.ScheduledTransferRepositoryInterface, transactions TransactionServiceInterface, interval, lease time.Duration) *ScheduledTransferWorker {
+	return &ScheduledTransferWorker{Repo: repo, Transactions: transactions, Interval: interval, Lease: lease}
+}
+
+// Run executes once immediately and then on every tick until ctx is
+// cancelled.
+func (w *ScheduledTransferWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.ExecuteDue()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExecuteDue claims one batch of due transfers, submits each and records
+// the outcome. It returns how many were executed.
+func (w *ScheduledTransferWorker) ExecuteDue() int {
+	logger := config.GetLogger()
+	claimed, err := w.Repo.ClaimDueScheduledTransfers(scheduledBatchSize, w.Lease)
+	if err != nil {
+		logger.Printf("scheduled transfers: %v", err)
+		return 0
+	}
+
+	executed := 0
+	for _, s := range claimed {
+		// An earlier attempt committed the transfer but died before
+		// recording it
+		if s.SubmittedTransactionID != nil {
+			if err := w.Repo.CompleteScheduledTransfer(s.ID, *s.SubmittedTransactionID); err != nil {
+				logger.Printf("scheduled transfers: transfer %d: %v", s.ID, err)
+				continue
+			}
+			executed++
+			continue
+		}
+
+		t, err := w.Transactions.SubmitTransaction(s.TransferRequest())
+		if err != nil {
+			// Domain errors are final; anything else is retried once the
+			// claim's lease runs out
+			if e, ok := apperrors.As(err); ok && e.Kind != apperrors.KindUnavailable {
+				err = w.Repo.FailScheduledTransfer(s.ID, e.Code, err.Error())
+			}
+			if err != nil {
+				logger.Printf("scheduled transfers: transfer %d: %v", s.ID, err)
+			}
+			continue
+		}
+		if err := w.Repo.CompleteScheduledTransfer(s.ID, t.ID); err != nil {
+			logger.Printf("scheduled transfers: transfer %d: %v", s.ID, err)
+			continue
+		}
+		executed++
+	}
+	if executed > 0 {
+		logger.Printf("scheduled transfers: executed %d transfers", executed)
+	}
+	return executed
+}
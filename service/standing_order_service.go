@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+	"transactions/recurrence"
+	"transactions/repository"
+)
+
+type StandingOrderServiceInterface interface {
+	CreateStandingOrder(o models.StandingOrder, startAt *time.Time) (*models.StandingOrder, error)
+	GetStandingOrder(id int64) (*models.StandingOrder, error)
+	ListStandingOrders(filter models.StandingOrderFilter) ([]models.StandingOrder, error)
+	PauseStandingOrder(id int64) (*models.StandingOrder, error)
+	ResumeStandingOrder(id int64) (*models.StandingOrder, error)
+	CancelStandingOrder(id int64) (*models.StandingOrder, error)
+	ListStandingOrderRuns(id int64, limit int) ([]models.StandingOrderRun, error)
+}
+
+type StandingOrderService struct {
+	Repo repository.StandingOrderRepositoryInterface
+	Now  func() time.Time
+}
+
+func NewStandingOrderService(repo repository.StandingOrderRepositoryInterface) *StandingOrderService {
+	return &StandingOrderService{Repo: repo, Now: time.Now}
+}
+
+// CreateStandingOrder stores o with its first run at the first occurrence
+// of its schedule from startAt, or from now when startAt is nil or past.
+func (s *StandingOrderService) CreateStandingOrder(o models.StandingOrder, startAt *time.Time) (*models.StandingOrder, error) {
+	from := s.Now().UTC()
+	if startAt != nil && startAt.After(from) {
+		// Next is strictly after its argument; step back so a start on
+		// an occurrence includes it
+		from = startAt.UTC().Add(-time.Nanosecond)
+	}
+	next, err := nextOccurrence(o.Schedule, from, o.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return nil, fmt.Errorf("%w: %q has no run before ends_at", apperrors.ErrInvalidSchedule, o.Schedule)
+	}
+	o.NextRunAt = *next
+	return s.Repo.CreateStandingOrder(o)
+}
+
+func (s *StandingOrderService) GetStandingOrder(id int64) (*models.StandingOrder, error) {
+	return s.Repo.GetStandingOrder(id)
+}
+
+func (s *StandingOrderService) ListStandingOrders(filter models.StandingOrderFilter) ([]models.StandingOrder, error) {
+	filter.Limit = clampPageSize(filter.Limit)
+	return s.Repo.ListStandingOrders(filter)
+}
+
+func (s *StandingOrderService) PauseStandingOrder(id int64) (*models.StandingOrder, error) {
+	return s.Repo.UpdateStandingOrderStatus(id, models.StandingOrderPaused, nil)
+}
+
+// ResumeStandingOrder reactivates a paused order from the next occurrence
+// after now. Occurrences missed while paused are not made up.
+func (s *StandingOrderService) ResumeStandingOrder(id int64) (*models.StandingOrder, error) {
+	o, err := s.Repo.GetStandingOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	next, err := nextOccurrence(o.Schedule, s.Now().UTC(), o.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return nil, fmt.Errorf("%w: standing order %d has no run left before ends_at", apperrors.ErrInvalidOrderTransition, id)
+	}
+	return s.Repo.UpdateStandingOrderStatus(id, models.StandingOrderActive, next)
+}
+
+func (s *StandingOrderService) CancelStandingOrder(id int64) (*models.StandingOrder, error) {
+	return s.Repo.UpdateStandingOrderStatus(id, models.StandingOrderCancelled, nil)
+}
+
+// ListStandingOrderRuns returns an order's run history, newest first.
+func (s *StandingOrderService) ListStandingOrderRuns(id int64, limit int) ([]models.StandingOrderRun, error) {
+	return s.Repo.ListStandingOrderRuns(id, clampPageSize(limit))
+}
+
+// nextOccurrence returns the first run of schedule after after, or nil if
+// there is none before endsAt.
+func nextOccurrence(schedule string, after time.Time, endsAt *time.Time) (*time.Time, error) {
+	sched, err := recurrence.Parse(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidSchedule, err)
+	}
+	next := sched.Next(after)
+	if next.IsZero() || (endsAt != nil && next.After(*endsAt)) {
+		return nil, nil
+	}
+	return &next, nil
+}
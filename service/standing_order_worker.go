@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"transactions/apperrors"
+	"transactions/config"
+	"transactions/models"
+	"transactions/recurrence"
+	"transactions/repository"
+)
+
+// standingOrderBatchSize bounds how many due orders one pass claims
+const standingOrderBatchSize = 100
+
+// StandingOrderWorker periodically runs standing orders that have fallen
+// due. Each occurrence is submitted like POST /transactions under an
+// idempotency key derived from the order and occurrence, and its outcome
+// is kept in the order's run history.
+type StandingOrderWorker struct {
+	Repo         repository.StandingOrderRepositoryInterface
+	Transactions TransactionServiceInterface
+	Interval     time.Duration
+	// Lease is how long a claimed order may go without a recorded run
+	// before it is claimed again.
+	Lease time.Duration
+	Now   func() time.Time
+}
+
+func NewStandingOrderWorker(repo repository.StandingOrderRepositoryInterface, transactions TransactionServiceInterface, interval, lease time.Duration) *StandingOrderWorker {
+	return &StandingOrderWorker{Repo: repo, Transactions: transactions, Interval: interval, Lease: lease, Now: time.Now}
+}
+
+// Run executes once immediately and then on every tick until ctx is
+// cancelled.
+func (w *StandingOrderWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.RunDue()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue claims one batch of due orders and runs each. It returns how many
+// runs were executed.
+func (w *StandingOrderWorker) RunDue() int {
+	logger := config.GetLogger()
+	claimed, err := w.Repo.ClaimDueStandingOrders(standingOrderBatchSize, w.Lease)
+	if err != nil {
+		logger.Printf("standing orders: %v", err)
+		return 0
+	}
+
+	executed := 0
+	for _, o := range claimed {
+		run, retryAt, err := w.runOrder(o)
+		if err != nil {
+			// Left claimed; it is retried once the lease runs out
+			logger.Printf("standing orders: order %d: %v", o.ID, err)
+			continue
+		}
+		if err := w.Repo.RecordStandingOrderRun(run, w.nextRun(o), retryAt); err != nil {
+			logger.Printf("standing orders: order %d: %v", o.ID, err)
+			continue
+		}
+		if run.Status == models.StandingOrderRunExecuted {
+			executed++
+		}
+	}
+	if executed > 0 {
+		logger.Printf("standing orders: executed %d runs", executed)
+	}
+	return executed
+}
+
+// runOrder submits the occurrence due at o.NextRunAt. Domain errors become
+// the run's outcome; on insufficient funds an order with the retry policy
+// gets a retry time, as long as retries remain and the retry falls before
+// the next occurrence. Any other error is returned.
+func (w *StandingOrderWorker) runOrder(o models.StandingOrder) (models.StandingOrderRun, *time.Time, error) {
+	run := models.StandingOrderRun{StandingOrderID: o.ID, ScheduledFor: o.NextRunAt, Attempt: o.Retries + 1}
+
+	// An earlier attempt committed the transfer but died before recording
+	// the run
+	if o.SubmittedTransactionID != nil {
+		run.Status, run.TransactionID = models.StandingOrderRunExecuted, o.SubmittedTransactionID
+		return run, nil, nil
+	}
+
+	t, err := w.Transactions.SubmitTransaction(o.TransferRequest())
+	if err == nil {
+		run.Status, run.TransactionID = models.StandingOrderRunExecuted, &t.ID
+		return run, nil, nil
+	}
+	e, ok := apperrors.As(err)
+	if !ok || e.Kind == apperrors.KindUnavailable {
+		return run, nil, err
+	}
+
+	run.Status, run.FailureCode, run.FailureReason = models.StandingOrderRunFailed, e.Code, err.Error()
+	if !errors.Is(err, apperrors.ErrInsufficientFunds) {
+		return run, nil, nil
+	}
+	run.Status = models.StandingOrderRunSkipped
+	if o.OnInsufficientFunds == models.InsufficientFundsRetry && o.Retries < o.MaxRetries {
+		retryAt := w.Now().UTC().Add(time.Duration(o.RetryIntervalSeconds) * time.Second)
+		if next := w.nextRun(o); next == nil || retryAt.Before(*next) {
+			run.Status = models.StandingOrderRunRetrying
+			return run, &retryAt, nil
+		}
+	}
+	return run, nil, nil
+}
+
+// nextRun returns the occurrence after the one just run, or nil once the
+// order has ended. Occurrences that passed while the service was down are
+// not made up.
+func (w *StandingOrderWorker) nextRun(o models.StandingOrder) *time.Time {
+	sched, err := recurrence.Parse(o.Schedule)
+	if err != nil {
+		config.GetLogger().Printf("standing orders: order %d: %v", o.ID, err)
+		return nil
+	}
+	after := o.NextRunAt
+	if now := w.Now().UTC(); now.After(after) {
+		after = now
+	}
+	next := sched.Next(after)
+	if next.IsZero() || (o.EndsAt != nil && next.After(*o.EndsAt)) {
+		return nil
+	}
+	return &next
+}
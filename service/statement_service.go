@@ -0,0 +1,25 @@
+package service
+
+import (
+	"time"
+	"transactions/models"
+	"transactions/repository"
+)
+
+type StatementServiceInterface interface {
+	StreamStatement(accountID int64, from, to time.Time, w models.StatementWriter) error
+}
+
+type StatementService struct {
+	Repo repository.StatementRepositoryInterface
+}
+
+func NewStatementService(repo repository.StatementRepositoryInterface) *StatementService {
+	return &StatementService{Repo: repo}
+}
+
+// StreamStatement writes an account's statement for [from, to) to w as it
+// is read.
+func (s *StatementService) StreamStatement(accountID int64, from, to time.Time, w models.StatementWriter) error {
+	return s.Repo.StreamStatement(accountID, from, to, w)
+}
@@ -1,22 +1,149 @@
 package service
 
 import (
+	"transactions/apperrors"
+	"transactions/config"
 	"transactions/models"
 	"transactions/repository"
 )
 
+const (
+	DefaultTransactionPageSize = 50
+	MaxTransactionPageSize     = 200
+
+	// MaxBatchLegs caps the number of legs in one POST /transfers/batch
+	MaxBatchLegs = 100
+)
+
 type TransactionServiceInterface interface {
-	SubmitTransaction(sourceID, destID int64, amount models.Money) error
+	SubmitTransaction(req models.TransferRequest) (*models.Transaction, error)
+	GetTransaction(id int64) (*models.Transaction, error)
+	ListAccountTransactions(accountID int64, filter models.TransactionFilter) (*models.TransactionPage, error)
+	SubmitBatch(legs []models.TransferRequest, audit models.AuditContext) (*models.TransferBatch, error)
+	ReverseTransaction(id int64, amount *models.Money, audit models.AuditContext) (*models.Transaction, error)
 }
 
 type TransactionService struct {
 	Repo repository.TransactionRepositoryInterface
+	// FX locks a quote for conversions requested without one. Nil disables
+	// implicit conversion.
+	FX FXServiceInterface
+	// Limits enforces transfer limits and velocity rules. Nil disables them.
+	Limits *LimitEngine
+	// Fees prices transfers. Nil makes them free.
+	Fees *FeeEngine
+}
+
+func NewTransactionService(repo repository.TransactionRepositoryInterface, fx FXServiceInterface, limits *LimitEngine, fees *FeeEngine) *TransactionService {
+	return &TransactionService{Repo: repo, FX: fx, Limits: limits, Fees: fees}
+}
+
+// SubmitTransaction applies a transfer. It is first checked against the
+// limit rules and priced by the fee schedule. A conversion requested
+// without a quote gets one locked at the current rate, which the
+// repository then redeems in the same DB transaction as the transfer. A
+// transfer rejected for a business reason is published as a TransferFailed
+// event.
+func (s *TransactionService) SubmitTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	t, err := s.submitTransaction(req)
+	if e, ok := apperrors.As(err); ok && e.Kind == apperrors.KindUnprocessable {
+		if recordErr := s.Repo.RecordTransferFailed(req, err); recordErr != nil {
+			config.GetLogger().Printf("recording failed transfer %d -> %d: %v", req.SourceAccountID, req.DestinationAccountID, recordErr)
+		}
+	}
+	return t, err
+}
+
+func (s *TransactionService) submitTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	// A retry of a transfer that already committed is replayed before
+	// limits and fees, which the original itself now counts against
+	if req.IdempotencyKey != "" {
+		t, err := s.Repo.GetIdempotentTransaction(req)
+		if err != nil || t != nil {
+			return t, err
+		}
+	}
+	if s.Limits != nil {
+		if err := s.Limits.Check(req); err != nil {
+			return nil, err
+		}
+	}
+	if s.Fees != nil {
+		if err := s.Fees.Apply(&req); err != nil {
+			return nil, err
+		}
+	}
+	if req.AllowConversion && req.QuoteID == "" && s.FX != nil {
+		source, err := s.Repo.GetAccountCurrency(req.SourceAccountID)
+		if err != nil {
+			return nil, err
+		}
+		dest, err := s.Repo.GetAccountCurrency(req.DestinationAccountID)
+		if err != nil {
+			return nil, err
+		}
+		if source != dest {
+			amount := req.Amount
+			quote, err := s.FX.CreateQuote(source, dest, &amount)
+			if err != nil {
+				return nil, err
+			}
+			req.LockedQuoteID = quote.ID
+		}
+	}
+	return s.Repo.SubmitTransaction(req)
 }
 
-func NewTransactionService(repo repository.TransactionRepositoryInterface) *TransactionService {
-	return &TransactionService{Repo: repo}
+// SubmitBatch applies all legs atomically. The legs are first checked
+// against the limit rules, summed per source account. Batches do not
+// convert between currencies.
+func (s *TransactionService) SubmitBatch(legs []models.TransferRequest, audit models.AuditContext) (*models.TransferBatch, error) {
+	if s.Limits != nil {
+		if err := s.Limits.CheckBatch(legs); err != nil {
+			return nil, err
+		}
+	}
+	return s.Repo.SubmitBatch(legs, audit)
+}
+
+// ReverseTransaction refunds amount of transaction id, or all of what has
+// not been reversed yet when amount is nil.
+func (s *TransactionService) ReverseTransaction(id int64, amount *models.Money, audit models.AuditContext) (*models.Transaction, error) {
+	return s.Repo.ReverseTransaction(id, amount, audit)
+}
+
+func (s *TransactionService) GetTransaction(id int64) (*models.Transaction, error) {
+	return s.Repo.GetTransaction(id)
+}
+
+// ListAccountTransactions returns one page of an account's history. It asks
+// the repository for one extra row to know whether another page follows.
+func (s *TransactionService) ListAccountTransactions(accountID int64, filter models.TransactionFilter) (*models.TransactionPage, error) {
+	filter.Limit = clampPageSize(filter.Limit)
+	limit := filter.Limit
+	filter.Limit++
+
+	txs, err := s.Repo.ListAccountTransactions(accountID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.TransactionPage{Transactions: txs}
+	if len(txs) > limit {
+		page.Transactions = txs[:limit]
+		page.NextCursor = models.EncodeCursor(txs[limit-1].ID)
+	}
+	return page, nil
 }
 
-func (s *TransactionService) SubmitTransaction(sourceID, destID int64, amount models.Money) error {
-	return s.Repo.SubmitTransaction(sourceID, destID, amount)
+// clampPageSize applies the default page size to an unset limit and caps
+// the rest.
+func clampPageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultTransactionPageSize
+	}
+	if limit > MaxTransactionPageSize {
+		return MaxTransactionPageSize
+	}
+	return limit
 }
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"transactions/models"
+	"transactions/repository"
+)
+
+type WebhookServiceInterface interface {
+	CreateWebhook(sub models.WebhookSubscription) (*models.WebhookSubscription, error)
+	GetWebhook(id int64) (*models.WebhookSubscription, error)
+	ListWebhooks() ([]models.WebhookSubscription, error)
+	UpdateWebhook(id int64, update models.WebhookSubscriptionUpdate) (*models.WebhookSubscription, error)
+	DeleteWebhook(id int64) error
+	ListWebhookDeliveries(subscriptionID int64, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	GetWebhookDelivery(id int64) (*models.WebhookDelivery, error)
+	RedeliverWebhook(id int64) (*models.WebhookDelivery, error)
+}
+
+// WebhookService manages webhook subscriptions. It is also the events
+// publisher that turns each outbox event into deliveries for the
+// WebhookWorker.
+type WebhookService struct {
+	Repo repository.WebhookRepositoryInterface
+}
+
+func NewWebhookService(repo repository.WebhookRepositoryInterface) *WebhookService {
+	return &WebhookService{Repo: repo}
+}
+
+// CreateWebhook stores sub, generating a secret when none was given.
+func (s *WebhookService) CreateWebhook(sub models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	if sub.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		sub.Secret = secret
+	}
+	if sub.AccountIDs == nil {
+		sub.AccountIDs = []int64{}
+	}
+	return s.Repo.CreateWebhook(sub)
+}
+
+func (s *WebhookService) GetWebhook(id int64) (*models.WebhookSubscription, error) {
+	return s.Repo.GetWebhook(id)
+}
+
+func (s *WebhookService) ListWebhooks() ([]models.WebhookSubscription, error) {
+	return s.Repo.ListWebhooks()
+}
+
+func (s *WebhookService) UpdateWebhook(id int64, update models.WebhookSubscriptionUpdate) (*models.WebhookSubscription, error) {
+	return s.Repo.UpdateWebhook(id, update)
+}
+
+func (s *WebhookService) DeleteWebhook(id int64) error {
+	return s.Repo.DeleteWebhook(id)
+}
+
+func (s *WebhookService) ListWebhookDeliveries(subscriptionID int64, filter models.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	filter.Limit = clampPageSize(filter.Limit)
+	return s.Repo.ListWebhookDeliveries(subscriptionID, filter)
+}
+
+func (s *WebhookService) GetWebhookDelivery(id int64) (*models.WebhookDelivery, error) {
+	return s.Repo.GetWebhookDelivery(id)
+}
+
+// RedeliverWebhook queues a delivered or dead delivery to be sent again.
+func (s *WebhookService) RedeliverWebhook(id int64) (*models.WebhookDelivery, error) {
+	return s.Repo.RedeliverWebhook(id)
+}
+
+func (s *WebhookService) Name() string {
+	return "webhooks"
+}
+
+// Publish enqueues a delivery of e for each subscription to it. Sending
+// them is left to the WebhookWorker, so a slow receiver never holds up the
+// outbox.
+func (s *WebhookService) Publish(ctx context.Context, e models.Event) error {
+	_, err := s.Repo.EnqueueWebhookDeliveries(e)
+	return err
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"transactions/config"
+	"transactions/models"
+	"transactions/repository"
+)
+
+// webhookBatchSize bounds how many due deliveries one pass claims
+const webhookBatchSize = 50
+
+// WebhookWorker sends pending webhook deliveries. A delivery is signed with
+// its subscription's secret and counts as delivered on any 2xx response;
+// otherwise it is retried with exponential backoff until MaxAttempts, when
+// it becomes dead.
+type WebhookWorker struct {
+	Repo     repository.WebhookRepositoryInterface
+	Client   *http.Client
+	Interval time.Duration
+	// Lease is how long a claimed delivery may go without a recorded
+	// attempt before it is claimed again. It must exceed the client timeout.
+	Lease       time.Duration
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	Now         func() time.Time
+}
+
+func NewWebhookWorker(repo repository.WebhookRepositoryInterface, client *http.Client, interval, lease time.Duration, maxAttempts int, backoffBase, backoffMax time.Duration) *WebhookWorker {
+	return &WebhookWorker{
+		Repo:        repo,
+		Client:      client,
+		Interval:    interval,
+		Lease:       lease,
+		MaxAttempts: maxAttempts,
+		BackoffBase: backoffBase,
+		BackoffMax:  backoffMax,
+		Now:         time.Now,
+	}
+}
+
+// Run delivers once immediately and then on every tick until ctx is
+// cancelled.
+func (w *WebhookWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		w.DeliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverDue claims one batch of due deliveries, attempts each and records
+// the outcome. It returns how many were delivered.
+func (w *WebhookWorker) DeliverDue(ctx context.Context) int {
+	logger := config.GetLogger()
+	claimed, err := w.Repo.ClaimDueWebhookDeliveries(webhookBatchSize, w.Lease)
+	if err != nil {
+		logger.Printf("webhooks: %v", err)
+		return 0
+	}
+
+	delivered := 0
+	for _, d := range claimed {
+		if ctx.Err() != nil {
+			break
+		}
+		attempt := w.attempt(ctx, d)
+
+		status := models.WebhookDeliveryDelivered
+		var next *time.Time
+		if attempt.Error != "" {
+			status = models.WebhookDeliveryDead
+			if d.Attempts+1 < w.MaxAttempts {
+				status = models.WebhookDeliveryPending
+				t := attempt.AttemptedAt.Add(w.backoff(d.Attempts + 1))
+				next = &t
+			}
+			logger.Printf("webhooks: delivery %d to subscription %d failed (attempt %d, now %s): %s",
+				d.ID, d.SubscriptionID, d.Attempts+1, status, attempt.Error)
+		}
+
+		if err := w.Repo.RecordWebhookAttempt(d.ID, attempt, status, next); err != nil {
+			// The lease runs out and the delivery is attempted again
+			logger.Printf("webhooks: recording attempt of delivery %d: %v", d.ID, err)
+			continue
+		}
+		if status == models.WebhookDeliveryDelivered {
+			delivered++
+		}
+	}
+	return delivered
+}
+
+// attempt POSTs the delivery's payload to its subscription once.
+func (w *WebhookWorker) attempt(ctx context.Context, d models.WebhookDelivery) models.WebhookAttempt {
+	now := w.Now()
+	attempt := models.WebhookAttempt{AttemptedAt: now}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	signature := models.WebhookSignature(d.Secret, now.Unix(), d.Payload)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(models.WebhookSignatureHeader, "t="+strconv.FormatInt(now.Unix(), 10)+",v1="+signature)
+	req.Header.Set(models.WebhookEventHeader, d.EventType)
+	req.Header.Set(models.WebhookDeliveryHeader, strconv.FormatInt(d.ID, 10))
+
+	start := time.Now()
+	resp, err := w.Client.Do(req)
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	attempt.StatusCode = &resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("receiver responded %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// backoff is the wait after the given number of failed attempts:
+// BackoffBase doubled for each attempt after the first, capped at
+// BackoffMax.
+func (w *WebhookWorker) backoff(attempts int) time.Duration {
+	d := w.BackoffBase
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= w.BackoffMax {
+			return w.BackoffMax
+		}
+	}
+	if d > w.BackoffMax {
+		return w.BackoffMax
+	}
+	return d
+}
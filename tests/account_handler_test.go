@@ -3,10 +3,11 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"transactions/apperrors"
 	"transactions/handler"
 	"transactions/models"
 	"transactions/service"
@@ -16,16 +17,51 @@ import (
 
 type mockAccountRepo struct{}
 
-func (m *mockAccountRepo) CreateAccount(accountID int64, initialBalance string) error {
+func (m *mockAccountRepo) CreateAccount(accountID int64, initialBalance string, currency models.Currency, accountType string, audit models.AuditContext) error {
 	if accountID == 999 {
-		return errors.New("duplicate account")
+		return apperrors.ErrDuplicateAccount
 	}
 	return nil
 }
 func (m *mockAccountRepo) GetAccount(accountID int64) (*models.Account, error) {
+	switch accountID {
+	case 404:
+		return nil, fmt.Errorf("%w: account %d", apperrors.ErrAccountNotFound, accountID)
+	case 503:
+		return nil, fmt.Errorf("%w: connection refused", apperrors.ErrDatabaseUnavailable)
+	}
 	return &models.Account{AccountID: accountID, Balance: "100.00"}, nil
 }
 
+// UpdateAccountStatus treats every account as active with a balance of 100
+func (m *mockAccountRepo) UpdateAccountStatus(accountID int64, change models.AccountStatusChange) (*models.Account, error) {
+	if accountID == 404 {
+		return nil, fmt.Errorf("%w: account %d", apperrors.ErrAccountNotFound, accountID)
+	}
+	if change.Status == models.AccountStatusActive {
+		return nil, fmt.Errorf("%w: account %d is active", apperrors.ErrInvalidTransition, accountID)
+	}
+	if change.Status == models.AccountStatusClosed && change.SweepToAccountID == 0 {
+		return nil, apperrors.ErrBalanceNotZero
+	}
+	return &models.Account{AccountID: accountID, Balance: "100.00", Status: change.Status, StatusReason: change.Reason, AllowIncoming: change.AllowIncoming}, nil
+}
+
+func (m *mockAccountRepo) SetOverdraftLimit(accountID int64, limit models.Money, reason, changedBy string, audit models.AuditContext) (*models.Account, error) {
+	if accountID == 404 {
+		return nil, fmt.Errorf("%w: account %d", apperrors.ErrAccountNotFound, accountID)
+	}
+	return &models.Account{AccountID: accountID, Balance: "100.00", OverdraftLimit: limit.String()}, nil
+}
+
+func (m *mockAccountRepo) ListOverdraftLimitChanges(accountID int64) ([]models.OverdraftLimitChange, error) {
+	return []models.OverdraftLimitChange{}, nil
+}
+
+func (m *mockAccountRepo) ListOverdrawnAccounts() ([]models.Account, error) {
+	return []models.Account{{AccountID: 7, Balance: "-20.00", InOverdraft: true}}, nil
+}
+
 func TestCreateAccount_Success(t *testing.T) {
 	h := handler.NewAccountHandler(&service.AccountService{Repo: &mockAccountRepo{}})
 	body := []byte(`{"account_id": 1, "initial_balance": "100.00"}`)
@@ -55,8 +91,8 @@ func TestCreateAccount_Error(t *testing.T) {
 	w := httptest.NewRecorder()
 
 	h.CreateAccount(w, req)
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected status 400, got %d", w.Code)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", w.Code)
 	}
 	var resp map[string]interface{}
 	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
@@ -65,6 +101,9 @@ func TestCreateAccount_Error(t *testing.T) {
 	if resp["success"].(bool) {
 		t.Errorf("expected success false, got true")
 	}
+	if resp["code"] != "duplicate_account" {
+		t.Errorf("expected code duplicate_account, got: %v", resp["code"])
+	}
 	if resp["error"] == nil || resp["error"] == "" {
 		t.Errorf("expected error message, got: %v", resp["error"])
 	}
@@ -116,3 +155,170 @@ func TestGetAccount_BadRequest(t *testing.T) {
 		t.Errorf("expected error message, got: %v", resp["error"])
 	}
 }
+
+func TestGetAccount_ErrorStatus(t *testing.T) {
+	cases := []struct {
+		id     string
+		status int
+		code   string
+	}{
+		{"404", http.StatusNotFound, "account_not_found"},
+		{"503", http.StatusServiceUnavailable, "database_unavailable"},
+	}
+	for _, tc := range cases {
+		h := handler.NewAccountHandler(&service.AccountService{Repo: &mockAccountRepo{}})
+		req := httptest.NewRequest(http.MethodGet, "/accounts/"+tc.id, nil)
+		req = mux.SetURLVars(req, map[string]string{"account_id": tc.id})
+		w := httptest.NewRecorder()
+
+		h.GetAccount(w, req)
+		if w.Code != tc.status {
+			t.Fatalf("account %s: expected status %d, got %d", tc.id, tc.status, w.Code)
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid JSON response: %v", err)
+		}
+		if resp["code"] != tc.code {
+			t.Errorf("account %s: expected code %s, got %v", tc.id, tc.code, resp["code"])
+		}
+	}
+}
+
+func TestCreateAccount_ProblemJSONValidation(t *testing.T) {
+	h := handler.NewAccountHandler(&service.AccountService{Repo: &mockAccountRepo{}})
+	body := []byte(`{"account_id": 0, "initial_balance": "-5"}`)
+	req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewBuffer(body))
+	req.Header.Set("Accept", "application/problem+json, application/json;q=0.5")
+	w := httptest.NewRecorder()
+
+	h.CreateAccount(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != handler.ProblemContentType {
+		t.Fatalf("expected content type %s, got %s", handler.ProblemContentType, ct)
+	}
+	var problem handler.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if problem.Status != http.StatusBadRequest || problem.Instance != "/accounts" || problem.Type == "" {
+		t.Errorf("unexpected problem: %+v", problem)
+	}
+	if len(problem.Errors) != 2 {
+		t.Fatalf("expected 2 field errors, got %+v", problem.Errors)
+	}
+	if problem.Errors[0].Field != "account_id" || problem.Errors[1].Field != "initial_balance" {
+		t.Errorf("unexpected field errors: %+v", problem.Errors)
+	}
+}
+
+func TestCreateAccount_CurrencyScale(t *testing.T) {
+	cases := []struct {
+		body   string
+		status int
+	}{
+		{`{"account_id": 2, "initial_balance": "10.50", "currency": "eur"}`, http.StatusCreated},
+		{`{"account_id": 2, "initial_balance": "1.5", "currency": "JPY"}`, http.StatusBadRequest},
+		{`{"account_id": 2, "initial_balance": "1.000", "currency": "KWD"}`, http.StatusCreated},
+		{`{"account_id": 2, "initial_balance": "1.00", "currency": "XXX"}`, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		h := handler.NewAccountHandler(&service.AccountService{Repo: &mockAccountRepo{}})
+		req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewBufferString(tc.body))
+		w := httptest.NewRecorder()
+
+		h.CreateAccount(w, req)
+		if w.Code != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.body, tc.status, w.Code)
+		}
+	}
+}
+
+func TestUpdateAccount(t *testing.T) {
+	h := handler.NewAccountHandler(&service.AccountService{Repo: &mockAccountRepo{}})
+	tests := []struct {
+		name           string
+		accountID      string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"freeze", "1", `{"status": "frozen", "reason": "suspected_fraud", "allow_incoming": true}`, http.StatusOK, ""},
+		{"close with sweep", "1", `{"status": "closed", "reason": "customer_request", "sweep_to_account_id": 2}`, http.StatusOK, ""},
+		{"close with balance", "1", `{"status": "closed", "reason": "customer_request"}`, http.StatusUnprocessableEntity, "balance_not_zero"},
+		{"invalid transition", "1", `{"status": "active", "reason": "resolved"}`, http.StatusConflict, "invalid_status_transition"},
+		{"unknown reason", "1", `{"status": "frozen", "reason": "because"}`, http.StatusBadRequest, "validation_failed"},
+		{"sweep to self", "1", `{"status": "closed", "reason": "customer_request", "sweep_to_account_id": 1}`, http.StatusBadRequest, "validation_failed"},
+		{"allow_incoming when closing", "1", `{"status": "closed", "reason": "other", "allow_incoming": true}`, http.StatusBadRequest, "validation_failed"},
+		{"unknown account", "404", `{"status": "frozen", "reason": "compromised"}`, http.StatusNotFound, "account_not_found"},
+		{"system account", "-2", `{"status": "closed", "reason": "other", "sweep_to_account_id": 5}`, http.StatusBadRequest, "invalid_request"},
+		{"sweep to system account", "1", `{"status": "closed", "reason": "other", "sweep_to_account_id": -2}`, http.StatusBadRequest, "validation_failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/accounts/"+tt.accountID, bytes.NewBufferString(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"account_id": tt.accountID})
+			w := httptest.NewRecorder()
+
+			h.UpdateAccount(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			var resp handler.ErrorResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if resp.Code != tt.expectedCode {
+				t.Errorf("expected code %q, got %q", tt.expectedCode, resp.Code)
+			}
+		})
+	}
+}
+
+func TestSetOverdraftLimit(t *testing.T) {
+	h := handler.NewAccountHandler(&service.AccountService{Repo: &mockAccountRepo{}})
+	tests := []struct {
+		name           string
+		accountID      string
+		body           string
+		expectedStatus int
+	}{
+		{"success", "1", `{"limit": "500.00", "reason": "credit line approved", "changed_by": "ops@example.com"}`, http.StatusOK},
+		{"negative limit", "1", `{"limit": "-1", "reason": "oops"}`, http.StatusBadRequest},
+		{"missing reason", "1", `{"limit": "100"}`, http.StatusBadRequest},
+		{"unknown account", "404", `{"limit": "100", "reason": "credit line approved"}`, http.StatusNotFound},
+		{"system account", "-3", `{"limit": "100", "reason": "credit line approved"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/accounts/"+tt.accountID+"/overdraft-limit", bytes.NewBufferString(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"account_id": tt.accountID})
+			w := httptest.NewRecorder()
+
+			h.SetOverdraftLimit(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestListOverdrawnAccounts(t *testing.T) {
+	h := handler.NewAccountHandler(&service.AccountService{Repo: &mockAccountRepo{}})
+	req := httptest.NewRequest(http.MethodGet, "/accounts/overdrawn", nil)
+	w := httptest.NewRecorder()
+
+	h.ListOverdrawnAccounts(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp struct {
+		Data []models.Account `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if len(resp.Data) != 1 || !resp.Data[0].InOverdraft {
+		t.Errorf("unexpected accounts: %+v", resp.Data)
+	}
+}
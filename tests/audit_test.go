@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"transactions/handler"
+	"transactions/models"
+	"transactions/router"
+	"transactions/service"
+)
+
+// mockAuditRepo returns entries 3, 2 and 1 and records the last filter
+type mockAuditRepo struct {
+	filter models.AuditFilter
+}
+
+func (m *mockAuditRepo) ListAuditEntries(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	m.filter = filter
+	entries := []models.AuditEntry{}
+	for id := int64(3); id >= 1 && len(entries) < filter.Limit; id-- {
+		entries = append(entries, models.AuditEntry{ID: id, Actor: "alice", Action: models.AuditActionAccountCreate,
+			EntityType: models.AuditEntityAccount, EntityID: "1", After: json.RawMessage(`{"account_id":1}`)})
+	}
+	return entries, nil
+}
+
+func (m *mockAuditRepo) VerifyAuditChain() (*models.AuditVerification, error) {
+	return &models.AuditVerification{EntriesChecked: 3, Valid: true}, nil
+}
+
+// auditRecorder captures the audit context of submitted transfers
+type auditRecorder struct {
+	fakeTransactionService
+	audit models.AuditContext
+}
+
+func (a *auditRecorder) SubmitTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	a.audit = req.Audit
+	return a.fakeTransactionService.SubmitTransaction(req)
+}
+
+func TestListAuditEntries_FiltersAndPages(t *testing.T) {
+	repo := &mockAuditRepo{}
+	h := handler.NewAuditHandler(service.NewAuditService(repo))
+
+	req := httptest.NewRequest(http.MethodGet, "/audit?actor=alice&entity_type=account&entity_id=1&from=2025-01-01T00:00:00Z&limit=2", nil)
+	w := httptest.NewRecorder()
+	h.ListAuditEntries(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if repo.filter.Actor != "alice" || repo.filter.EntityType != "account" || repo.filter.EntityID != "1" || repo.filter.From == nil {
+		t.Errorf("filter not passed through: %+v", repo.filter)
+	}
+
+	var resp struct {
+		Data models.AuditPage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if len(resp.Data.Entries) != 2 || resp.Data.NextCursor != models.EncodeCursor(2) {
+		t.Errorf("expected 2 entries and a cursor after entry 2, got %+v", resp.Data)
+	}
+}
+
+func TestListAuditEntries_InvalidQuery(t *testing.T) {
+	h := handler.NewAuditHandler(service.NewAuditService(&mockAuditRepo{}))
+
+	for _, query := range []string{
+		"from=yesterday",
+		"from=2025-02-01T00:00:00Z&to=2025-01-01T00:00:00Z",
+		"entity_id=1",
+		"cursor=!!",
+		"limit=0",
+	} {
+		w := httptest.NewRecorder()
+		h.ListAuditEntries(w, httptest.NewRequest(http.MethodGet, "/audit?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestRequestsCarryAuditContext(t *testing.T) {
+	rec := &auditRecorder{}
+	r := router.NewRouter(&handler.Handler{
+		Transaction: handler.NewTransactionHandler(rec),
+		Audit:       handler.NewAuditHandler(service.NewAuditService(&mockAuditRepo{})),
+	})
+
+	body := []byte(`{"source_account_id": 1, "destination_account_id": 2, "amount": "10.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewBuffer(body))
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set(handler.ActorHeader, "alice")
+	req.Header.Set(handler.RequestIDHeader, "req-42")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	want := models.AuditContext{Actor: "alice", RequestID: "req-42", SourceIP: "203.0.113.7"}
+	if rec.audit != want {
+		t.Errorf("expected audit context %+v, got %+v", want, rec.audit)
+	}
+	if got := w.Header().Get(handler.RequestIDHeader); got != "req-42" {
+		t.Errorf("expected the request id to be echoed, got %q", got)
+	}
+
+	// Without headers the request gets a generated id and an anonymous actor
+	req = httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewBuffer(body))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if rec.audit.Actor != models.AuditActorAnonymous || rec.audit.RequestID == "" {
+		t.Errorf("expected an anonymous actor and a generated request id, got %+v", rec.audit)
+	}
+	if w.Header().Get(handler.RequestIDHeader) != rec.audit.RequestID {
+		t.Errorf("expected the generated request id %q to be echoed, got %q", rec.audit.RequestID, w.Header().Get(handler.RequestIDHeader))
+	}
+}
+
+func TestAuditEntryHashChain(t *testing.T) {
+	first := models.AuditEntry{
+		OccurredAt: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		Actor:      "alice", Action: models.AuditActionAccountCreate,
+		EntityType: models.AuditEntityAccount, EntityID: "1",
+		After:    json.RawMessage(`{"account_id":1,"balance":"100.00"}`),
+		PrevHash: models.AuditGenesisHash,
+	}
+	first.Hash = first.ComputeHash()
+	second := models.AuditEntry{
+		OccurredAt: first.OccurredAt.Add(time.Second),
+		Actor:      "bob", Action: models.AuditActionAccountStatus,
+		EntityType: models.AuditEntityAccount, EntityID: "1",
+		Before:   first.After,
+		After:    json.RawMessage(`{"account_id":1,"balance":"100.00","status":"frozen"}`),
+		PrevHash: first.Hash,
+	}
+	second.Hash = second.ComputeHash()
+
+	// The same instant in another zone hashes the same
+	local := first
+	local.OccurredAt = first.OccurredAt.In(time.FixedZone("CET", 3600))
+	if local.ComputeHash() != first.Hash {
+		t.Error("expected the hash not to depend on the time zone")
+	}
+
+	tampered := first
+	tampered.After = json.RawMessage(`{"account_id":1,"balance":"900.00"}`)
+	if tampered.ComputeHash() == first.Hash {
+		t.Error("expected a changed snapshot to change the hash")
+	}
+
+	// Moving a character between fields must not collide
+	shifted := first
+	shifted.Actor, shifted.RequestID = "alic", "e"
+	if shifted.ComputeHash() == first.Hash {
+		t.Error("expected field boundaries to be part of the hash")
+	}
+
+	if second.PrevHash != first.Hash || second.ComputeHash() != second.Hash {
+		t.Error("expected the second entry to link to the first")
+	}
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	h := handler.NewAuditHandler(service.NewAuditService(&mockAuditRepo{}))
+	w := httptest.NewRecorder()
+	h.VerifyAuditChain(w, httptest.NewRequest(http.MethodGet, "/audit/verify", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.AuditVerification `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if !resp.Data.Valid || resp.Data.EntriesChecked != 3 {
+		t.Errorf("unexpected verification %+v", resp.Data)
+	}
+}
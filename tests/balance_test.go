@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"transactions/apperrors"
+	"transactions/handler"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+type mockBalanceRepo struct {
+	next        *time.Time
+	snapshotted []string
+	queriedAt   time.Time
+	from, to    time.Time
+}
+
+func (m *mockBalanceRepo) GetBalanceAt(accountID int64, at time.Time) (*models.AccountBalance, error) {
+	if accountID == 404 {
+		return nil, fmt.Errorf("%w: account %d", apperrors.ErrAccountNotFound, accountID)
+	}
+	m.queriedAt = at
+	return &models.AccountBalance{AccountID: accountID, Currency: "USD", Balance: *money("42.50"), At: at}, nil
+}
+
+func (m *mockBalanceRepo) ListBalanceSnapshots(accountID int64, from, to time.Time) ([]models.BalanceSnapshot, error) {
+	m.from, m.to = from, to
+	return []models.BalanceSnapshot{}, nil
+}
+
+func (m *mockBalanceRepo) NextSnapshotDate() (*time.Time, error) {
+	return m.next, nil
+}
+
+func (m *mockBalanceRepo) SnapshotBalances(day time.Time) (int64, error) {
+	m.snapshotted = append(m.snapshotted, day.Format(models.SnapshotDateFormat))
+	return 3, nil
+}
+
+func TestBalanceSnapshotter_CatchesUp(t *testing.T) {
+	next := time.Date(2025, 3, 28, 0, 0, 0, 0, time.UTC)
+	repo := &mockBalanceRepo{next: &next}
+	s := service.NewBalanceSnapshotter(repo, time.Hour)
+
+	// Shortly after midnight the day that just ended is not settled yet
+	s.Now = func() time.Time { return time.Date(2025, 3, 31, 0, 2, 0, 0, time.UTC) }
+	if n := s.SnapshotDays(); n != 2 {
+		t.Errorf("expected 2 days snapshotted, got %d", n)
+	}
+	want := []string{"2025-03-28", "2025-03-29"}
+	if fmt.Sprint(repo.snapshotted) != fmt.Sprint(want) {
+		t.Errorf("expected snapshots for %v, got %v", want, repo.snapshotted)
+	}
+
+	repo.next = nil
+	if n := s.SnapshotDays(); n != 0 {
+		t.Errorf("expected nothing to snapshot on an empty ledger, got %d", n)
+	}
+}
+
+func newTestBalanceRouter(repo *mockBalanceRepo) http.Handler {
+	h := handler.NewBalanceHandler(service.NewBalanceService(repo))
+	r := mux.NewRouter()
+	r.HandleFunc("/accounts/{account_id}/balance", h.GetBalance).Methods("GET")
+	r.HandleFunc("/accounts/{account_id}/balance-history", h.ListBalanceHistory).Methods("GET")
+	return r
+}
+
+func TestGetBalanceAt(t *testing.T) {
+	repo := &mockBalanceRepo{}
+	r := newTestBalanceRouter(repo)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"at a point in time", "/accounts/1/balance?at=2025-01-15T12:30:00Z", http.StatusOK},
+		{"now", "/accounts/1/balance", http.StatusOK},
+		{"future", "/accounts/1/balance?at=" + future, http.StatusBadRequest},
+		{"invalid at", "/accounts/1/balance?at=yesterday", http.StatusBadRequest},
+		{"unknown account", "/accounts/404/balance?at=2025-01-15T12:30:00Z", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/accounts/1/balance?at=2025-01-15T14:30:00%2B02:00", nil))
+	var resp struct {
+		Data models.AccountBalance `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if !repo.queriedAt.Equal(time.Date(2025, 1, 15, 12, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected the balance at 12:30 UTC, got %s", repo.queriedAt)
+	}
+	if resp.Data.Balance.String() != "42.5" {
+		t.Errorf("expected balance 42.5, got %s", resp.Data.Balance.String())
+	}
+}
+
+func TestListBalanceHistory(t *testing.T) {
+	repo := &mockBalanceRepo{}
+	r := newTestBalanceRouter(repo)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{"range", "?from=2025-01-01&to=2025-01-31", http.StatusOK},
+		{"default range", "", http.StatusOK},
+		{"from after to", "?from=2025-02-01&to=2025-01-31", http.StatusBadRequest},
+		{"range too long", "?from=2023-01-01&to=2025-01-31", http.StatusBadRequest},
+		{"invalid date", "?from=01/01/2025", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/accounts/1/balance-history"+tt.query, nil))
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if days := repo.to.Sub(repo.from).Hours()/24 + 1; days != 30 {
+		t.Errorf("expected a default range of 30 days, got %v", days)
+	}
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+	"transactions/models"
+	"transactions/repository"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
+)
+
+// openTestDB connects to TEST_DATABASE_URL and migrates it to the latest
+// schema. Tests that need a real Postgres are skipped when it is unset.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	m, err := migrate.New("file://../db/migrations", url)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		t.Fatalf("migrate up: %v", err)
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestOpposingTransfersConserveMoney(t *testing.T) {
+	db := openTestDB(t)
+	accounts := repository.NewAccountRepository(db)
+	transactions := repository.NewTransactionRepository(db)
+
+	// Fresh ids per run so the test can be repeated against the same database
+	a := time.Now().UnixNano() % 1_000_000_000_000
+	b := a + 1
+	if err := accounts.CreateAccount(a, "1000.00", models.DefaultCurrency, models.DefaultAccountType, models.AuditContext{}); err != nil {
+		t.Fatalf("create account %d: %v", a, err)
+	}
+	if err := accounts.CreateAccount(b, "1000.00", models.DefaultCurrency, models.DefaultAccountType, models.AuditContext{}); err != nil {
+		t.Fatalf("create account %d: %v", b, err)
+	}
+
+	const workers = 16
+	const transfersPerWorker = 25
+	amount, _ := models.NewMoneyFromString("1.00")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*transfersPerWorker)
+	for w := 0; w < workers; w++ {
+		src, dst := a, b
+		if w%2 == 1 {
+			src, dst = b, a
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < transfersPerWorker; i++ {
+				_, err := transactions.SubmitTransaction(models.TransferRequest{
+					SourceAccountID:      src,
+					DestinationAccountID: dst,
+					Amount:               amount,
+				})
+				if err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("transfer failed: %v", err)
+	}
+
+	// Each direction moved the same total, so both balances are unchanged
+	want := decimal.RequireFromString("1000.00")
+	for _, id := range []int64{a, b} {
+		acc, err := accounts.GetAccount(id)
+		if err != nil {
+			t.Fatalf("get account %d: %v", id, err)
+		}
+		if got := decimal.RequireFromString(acc.Balance); !got.Equal(want) {
+			t.Errorf("account %d balance = %s, want %s", id, got, want)
+		}
+
+		var posted decimal.Decimal
+		if err := db.QueryRow("SELECT COALESCE(SUM(amount), 0) FROM postings WHERE account_id = $1", id).Scan(&posted); err != nil {
+			t.Fatalf("sum postings: %v", err)
+		}
+		if !posted.Equal(want) {
+			t.Errorf("account %d postings sum = %s, want %s", id, posted, want)
+		}
+	}
+}
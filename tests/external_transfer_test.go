@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"transactions/apperrors"
+	"transactions/handler"
+	"transactions/models"
+	"transactions/payments"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+// mockExternalTransferRepo keeps transfers in memory and records the ledger
+// side of each one as a fake transaction id, and the actor of each change.
+type mockExternalTransferRepo struct {
+	transfers map[int64]*models.ExternalTransfer
+	nextTxID  int64
+	actors    []string
+}
+
+func newMockExternalTransferRepo() *mockExternalTransferRepo {
+	return &mockExternalTransferRepo{transfers: map[int64]*models.ExternalTransfer{}, nextTxID: 100}
+}
+
+func (m *mockExternalTransferRepo) create(t models.ExternalTransfer) (*models.ExternalTransfer, error) {
+	for _, existing := range m.transfers {
+		if existing.Direction == t.Direction && existing.ExternalReference == t.ExternalReference {
+			if existing.AccountID != t.AccountID || !existing.Amount.Equal(t.Amount.Decimal) {
+				return nil, apperrors.ErrExternalReferenceReused
+			}
+			return existing, nil
+		}
+	}
+	t.ID = int64(len(m.transfers) + 1)
+	t.Currency = models.Currency("USD")
+	t.Status = models.ExternalTransferPending
+	m.transfers[t.ID] = &t
+	return &t, nil
+}
+
+func (m *mockExternalTransferRepo) postTransaction() *int64 {
+	m.nextTxID++
+	id := m.nextTxID
+	return &id
+}
+
+func (m *mockExternalTransferRepo) CreateDeposit(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	m.actors = append(m.actors, audit.Actor)
+	return m.create(t)
+}
+
+func (m *mockExternalTransferRepo) CreateWithdrawal(t models.ExternalTransfer, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	m.actors = append(m.actors, audit.Actor)
+	w, err := m.create(t)
+	if err == nil && w.TransactionID == nil {
+		w.TransactionID = m.postTransaction()
+	}
+	return w, err
+}
+
+func (m *mockExternalTransferRepo) CompleteExternalTransfer(id int64, railReference string, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	m.actors = append(m.actors, audit.Actor)
+	t := m.transfers[id]
+	if t.Direction == models.ExternalDeposit {
+		t.TransactionID = m.postTransaction()
+	}
+	t.Status, t.RailReference = models.ExternalTransferCompleted, railReference
+	return t, nil
+}
+
+func (m *mockExternalTransferRepo) FailExternalTransfer(id int64, reason string, audit models.AuditContext) (*models.ExternalTransfer, error) {
+	m.actors = append(m.actors, audit.Actor)
+	t := m.transfers[id]
+	if t.Direction == models.ExternalWithdrawal {
+		t.RefundTransactionID = m.postTransaction()
+	}
+	t.Status, t.FailureReason = models.ExternalTransferFailed, reason
+	return t, nil
+}
+
+func (m *mockExternalTransferRepo) GetExternalTransfer(id int64) (*models.ExternalTransfer, error) {
+	t, ok := m.transfers[id]
+	if !ok {
+		return nil, apperrors.ErrExternalTransferNotFound
+	}
+	return t, nil
+}
+
+func (m *mockExternalTransferRepo) FindExternalTransfer(direction, externalReference string) (*models.ExternalTransfer, error) {
+	for _, t := range m.transfers {
+		if t.Direction == direction && t.ExternalReference == externalReference {
+			return t, nil
+		}
+	}
+	return nil, nil
+}
+
+func newTestExternalTransferRouter(repo *mockExternalTransferRepo, rail payments.PaymentRail) http.Handler {
+	h := handler.NewExternalTransferHandler(service.NewExternalTransferService(repo, rail, time.Second, nil))
+	r := mux.NewRouter()
+	r.HandleFunc("/accounts/{account_id}/deposits", h.Deposit).Methods("POST")
+	r.HandleFunc("/accounts/{account_id}/withdrawals", h.Withdraw).Methods("POST")
+	r.HandleFunc("/external-transfers/{id:[0-9]+}", h.GetExternalTransfer).Methods("GET")
+	return r
+}
+
+func TestDeposit(t *testing.T) {
+	repo := newMockExternalTransferRepo()
+	rail := payments.NewFakeRail()
+	r := newTestExternalTransferRouter(repo, rail)
+	body := `{"amount": "25", "external_reference": "dep-1", "funding_source": "card-4242"}`
+
+	w, d := doRequest[models.ExternalTransfer](t, r, http.MethodPost, "/accounts/1/deposits", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if d.Status != models.ExternalTransferCompleted || d.TransactionID == nil || d.RailReference == "" {
+		t.Errorf("expected a completed deposit with a transaction and rail reference, got %+v", d)
+	}
+	if w.Header().Get("Location") != fmt.Sprintf("/external-transfers/%d", d.ID) {
+		t.Errorf("unexpected Location %q", w.Header().Get("Location"))
+	}
+
+	w, replay := doRequest[models.ExternalTransfer](t, r, http.MethodPost, "/accounts/1/deposits", body)
+	if w.Code != http.StatusCreated || replay.ID != d.ID || *replay.TransactionID != *d.TransactionID {
+		t.Errorf("expected the same deposit on replay, got %d: %s", w.Code, w.Body.String())
+	}
+	if n := len(rail.Payments()); n != 1 {
+		t.Errorf("expected the rail to collect once, got %d payments", n)
+	}
+
+	w, _ = doRequest[models.ExternalTransfer](t, r, http.MethodPost, "/accounts/1/deposits", `{"amount": "30", "external_reference": "dep-1", "funding_source": "card-4242"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 reusing a reference, got %d", w.Code)
+	}
+}
+
+func TestWithdrawal_Declined(t *testing.T) {
+	repo := newMockExternalTransferRepo()
+	rail := payments.NewFakeRail()
+	rail.Decline("iban-closed")
+	r := newTestExternalTransferRouter(repo, rail)
+
+	w, _ := doRequest[models.ExternalTransfer](t, r, http.MethodPost, "/accounts/1/withdrawals", `{"amount": "40", "external_reference": "wd-1", "funding_source": "iban-closed"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", w.Code, w.Body.String())
+	}
+
+	wd := repo.transfers[1]
+	if wd.Status != models.ExternalTransferFailed || wd.RefundTransactionID == nil {
+		t.Errorf("expected a failed and refunded withdrawal, got %+v", wd)
+	}
+	// The request is audited as its caller's, the decline as the rail's
+	if got := fmt.Sprint(repo.actors); got != fmt.Sprint([]string{models.AuditActorAnonymous, models.AuditActorPaymentRail}) {
+		t.Errorf("unexpected audit actors %s", got)
+	}
+
+	w, got := doRequest[models.ExternalTransfer](t, r, http.MethodGet, "/external-transfers/1", "")
+	if w.Code != http.StatusOK || got.Status != models.ExternalTransferFailed {
+		t.Errorf("expected to read back the failed withdrawal, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestExternalTransferValidation(t *testing.T) {
+	r := newTestExternalTransferRouter(newMockExternalTransferRepo(), payments.NewFakeRail())
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"zero amount", "/accounts/1/deposits", `{"amount": "0", "external_reference": "x", "funding_source": "card"}`},
+		{"missing reference", "/accounts/1/deposits", `{"amount": "5", "funding_source": "card"}`},
+		{"missing funding source", "/accounts/1/withdrawals", `{"amount": "5", "external_reference": "x"}`},
+		{"bad currency", "/accounts/1/withdrawals", `{"amount": "5", "currency": "ZZZ", "external_reference": "x", "funding_source": "card"}`},
+		{"bad account id", "/accounts/abc/deposits", `{"amount": "5", "external_reference": "x", "funding_source": "card"}`},
+		{"system account withdrawal", "/accounts/-2/withdrawals", `{"amount": "5", "external_reference": "x", "funding_source": "card"}`},
+		{"system account deposit", "/accounts/-1/deposits", `{"amount": "5", "external_reference": "x", "funding_source": "card"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := doRequest[models.ExternalTransfer](t, r, http.MethodPost, tt.path, tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected status 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
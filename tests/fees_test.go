@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"testing"
+	"transactions/models"
+	"transactions/repository"
+	"transactions/service"
+
+	"github.com/shopspring/decimal"
+)
+
+type mockFeeRepo struct{}
+
+func (m *mockFeeRepo) FeeSchedules() ([]models.FeeSchedule, error) {
+	return nil, nil
+}
+
+// AccountFeeProfile makes account 5 a business account in EUR and every
+// other account a standard one in USD
+func (m *mockFeeRepo) AccountFeeProfile(accountID int64) (models.Currency, string, error) {
+	if accountID == 5 {
+		return "EUR", "business", nil
+	}
+	return "USD", models.DefaultAccountType, nil
+}
+
+func pct(s string) *decimal.Decimal {
+	d := decimal.RequireFromString(s)
+	return &d
+}
+
+func TestFeeSchedule_Fee(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule models.FeeSchedule
+		amount   string
+		want     string
+	}{
+		{"flat", models.FeeSchedule{Type: models.FeeFlat, Flat: money("1.50")}, "100", "1.5"},
+		{"percentage", models.FeeSchedule{Type: models.FeePercentage, Percentage: pct("0.015")}, "200", "3"},
+		{"percentage rounds to minor unit", models.FeeSchedule{Type: models.FeePercentage, Percentage: pct("0.015")}, "33.33", "0.5"},
+		{"minimum", models.FeeSchedule{Type: models.FeePercentage, Percentage: pct("0.01"), MinFee: money("0.50")}, "10", "0.5"},
+		{"maximum", models.FeeSchedule{Type: models.FeePercentage, Percentage: pct("0.01"), MaxFee: money("25")}, "10000", "25"},
+		{"first tier", tiered(), "50", "0.25"},
+		{"tier boundary is inclusive", tiered(), "100", "0.25"},
+		{"open-ended last tier", tiered(), "1000", "10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.Fee(*money(tt.amount), "USD"); got.String() != tt.want {
+				t.Errorf("expected fee %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func tiered() models.FeeSchedule {
+	return models.FeeSchedule{Type: models.FeeTiered, Tiers: []models.FeeTier{
+		{UpTo: money("100"), Flat: money("0.25")},
+		{Percentage: pct("0.01")},
+	}}
+}
+
+func TestFeeEngine_MostSpecificScheduleWins(t *testing.T) {
+	schedules := service.StaticFeeSchedules{
+		{Name: "default", Type: models.FeeFlat, Flat: money("1")},
+		{Name: "business", Type: models.FeeFlat, AccountType: "business", Flat: money("2")},
+		{Name: "account-9", Type: models.FeeFlat, AccountID: 9, Flat: money("0")},
+		{Name: "eur-only", Type: models.FeeFlat, Currency: "EUR", Flat: money("3")},
+	}
+	engine := service.NewFeeEngine(schedules, &mockFeeRepo{}, 77)
+
+	tests := []struct {
+		accountID    int64
+		wantSchedule string
+		wantFee      string
+	}{
+		{1, "default", "1"},
+		{5, "business", "2"},
+		{9, "account-9", "0"},
+	}
+	for _, tt := range tests {
+		req := models.TransferRequest{SourceAccountID: tt.accountID, DestinationAccountID: 2, Amount: *money("100")}
+		if err := engine.Apply(&req); err != nil {
+			t.Fatalf("account %d: %v", tt.accountID, err)
+		}
+		if req.FeeSchedule != tt.wantSchedule || req.Fee.String() != tt.wantFee || req.FeeAccountID != 77 {
+			t.Errorf("account %d: got schedule %q fee %s revenue account %d", tt.accountID, req.FeeSchedule, req.Fee, req.FeeAccountID)
+		}
+	}
+}
+
+func TestSubmitTransaction_FeeFallsBackWhenRevenueAccountClosed(t *testing.T) {
+	db := openTestDB(t)
+	accounts := repository.NewAccountRepository(db)
+	ids := createTestAccounts(t, accounts, "100.00", "0", "0")
+	if _, err := accounts.UpdateAccountStatus(ids[2], models.AccountStatusChange{Status: models.AccountStatusClosed, Reason: "other"}); err != nil {
+		t.Fatalf("close revenue account: %v", err)
+	}
+
+	amount, _ := models.NewMoneyFromString("10.00")
+	fee, _ := models.NewMoneyFromString("1.00")
+	txn, err := repository.NewTransactionRepository(db).SubmitTransaction(models.TransferRequest{
+		SourceAccountID: ids[0], DestinationAccountID: ids[1], Amount: amount, Fee: fee, FeeAccountID: ids[2],
+	})
+	if err != nil {
+		t.Fatalf("expected the transfer to go through, got %v", err)
+	}
+
+	var feeAccountID int64
+	err = db.QueryRow(`SELECT p.account_id FROM postings p JOIN journal_entries j ON j.id = p.entry_id
+		WHERE j.transaction_id = $1 AND p.account_id NOT IN ($2, $3) AND p.amount > 0`, txn.ID, ids[0], ids[1]).Scan(&feeAccountID)
+	if err != nil {
+		t.Fatalf("fee posting: %v", err)
+	}
+	if want := systemAccount(t, db, models.SystemAccountFees); feeAccountID != want {
+		t.Errorf("expected the fee credited to the fees system account %d, got %d", want, feeAccountID)
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"transactions/apperrors"
+	"transactions/fx"
+	"transactions/handler"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+type mockFXRepo struct {
+	quotes map[string]*models.FXQuote
+}
+
+func (m *mockFXRepo) CreateQuote(quote *models.FXQuote, ttl time.Duration) (*models.FXQuote, error) {
+	q := *quote
+	q.CreatedAt = time.Now()
+	q.ExpiresAt = q.CreatedAt.Add(ttl)
+	if q.SourceAmount != nil {
+		dest := q.SourceAmount.Convert(q.Rate, q.DestinationCurrency)
+		q.DestinationAmount = &dest
+	}
+	m.quotes[q.ID] = &q
+	return &q, nil
+}
+
+func (m *mockFXRepo) GetQuote(id string) (*models.FXQuote, error) {
+	q, ok := m.quotes[id]
+	if !ok {
+		return nil, apperrors.ErrQuoteNotFound
+	}
+	return q, nil
+}
+
+func newTestFXHandler(t *testing.T) *handler.FXHandler {
+	rates, err := fx.NewStaticRateProvider(map[string]decimal.Decimal{
+		"USD/EUR": decimal.RequireFromString("0.92"),
+	})
+	if err != nil {
+		t.Fatalf("failed to build rate provider: %v", err)
+	}
+	repo := &mockFXRepo{quotes: map[string]*models.FXQuote{}}
+	return handler.NewFXHandler(service.NewFXService(repo, rates, time.Minute))
+}
+
+func TestCreateQuote_Success(t *testing.T) {
+	h := newTestFXHandler(t)
+	body := []byte(`{"source_currency": "USD", "destination_currency": "EUR", "amount": "100.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/fx/quotes", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	h.CreateQuote(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Success bool           `json:"success"`
+		Data    models.FXQuote `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if resp.Data.ID == "" || !resp.Data.Rate.Equal(decimal.RequireFromString("0.92")) {
+		t.Errorf("unexpected quote: %+v", resp.Data)
+	}
+	if resp.Data.DestinationAmount == nil || resp.Data.DestinationAmount.String() != "92" {
+		t.Errorf("unexpected destination amount: %v", resp.Data.DestinationAmount)
+	}
+	if loc := w.Header().Get("Location"); loc != "/fx/quotes/"+resp.Data.ID {
+		t.Errorf("unexpected Location header: %q", loc)
+	}
+}
+
+func TestCreateQuote_InverseRate(t *testing.T) {
+	h := newTestFXHandler(t)
+	body := []byte(`{"source_currency": "EUR", "destination_currency": "USD", "amount": "92.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/fx/quotes", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	h.CreateQuote(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.FXQuote `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if resp.Data.DestinationAmount == nil || resp.Data.DestinationAmount.String() != "100" {
+		t.Errorf("unexpected destination amount: %v", resp.Data.DestinationAmount)
+	}
+}
+
+func TestCreateQuote_UnknownPair(t *testing.T) {
+	h := newTestFXHandler(t)
+	body := []byte(`{"source_currency": "USD", "destination_currency": "JPY"}`)
+	req := httptest.NewRequest(http.MethodPost, "/fx/quotes", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	h.CreateQuote(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", w.Code)
+	}
+}
+
+func TestGetQuote_NotFound(t *testing.T) {
+	h := newTestFXHandler(t)
+	req := httptest.NewRequest(http.MethodGet, "/fx/quotes/q_missing", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "q_missing"})
+	w := httptest.NewRecorder()
+
+	h.GetQuote(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,387 @@
+package tests
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+	"transactions/apperrors"
+	"transactions/models"
+	"transactions/repository"
+
+	"github.com/shopspring/decimal"
+)
+
+// createTestAccounts opens one account per balance under fresh ids, so the
+// test can be repeated against the same database
+func createTestAccounts(t *testing.T, accounts *repository.AccountRepository, balances ...string) []int64 {
+	t.Helper()
+	base := time.Now().UnixNano() % 1_000_000_000_000
+	ids := make([]int64, len(balances))
+	for i, balance := range balances {
+		ids[i] = base + int64(i)
+		if err := accounts.CreateAccount(ids[i], balance, models.DefaultCurrency, models.DefaultAccountType, models.AuditContext{}); err != nil {
+			t.Fatalf("create account %d: %v", ids[i], err)
+		}
+	}
+	return ids
+}
+
+// submitTestTransfer moves amount from src to dst and returns its journal
+// entry
+func submitTestTransfer(t *testing.T, db *sql.DB, src, dst int64, amount string) int64 {
+	t.Helper()
+	m, _ := models.NewMoneyFromString(amount)
+	txn, err := repository.NewTransactionRepository(db).SubmitTransaction(models.TransferRequest{
+		SourceAccountID: src, DestinationAccountID: dst, Amount: m,
+	})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	var entryID int64
+	if err := db.QueryRow("SELECT id FROM journal_entries WHERE transaction_id = $1", txn.ID).Scan(&entryID); err != nil {
+		t.Fatalf("journal entry of transaction %d: %v", txn.ID, err)
+	}
+	return entryID
+}
+
+func TestLedger_TransferPostingsBalance(t *testing.T) {
+	db := openTestDB(t)
+	ids := createTestAccounts(t, repository.NewAccountRepository(db), "100.00", "0")
+	entryID := submitTestTransfer(t, db, ids[0], ids[1], "25.50")
+
+	rows, err := db.Query("SELECT account_id, amount FROM postings WHERE entry_id = $1", entryID)
+	if err != nil {
+		t.Fatalf("postings: %v", err)
+	}
+	defer rows.Close()
+	sum := decimal.Zero
+	byAccount := map[int64]decimal.Decimal{}
+	for rows.Next() {
+		var id int64
+		var amount decimal.Decimal
+		if err := rows.Scan(&id, &amount); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		sum = sum.Add(amount)
+		byAccount[id] = byAccount[id].Add(amount)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("postings: %v", err)
+	}
+
+	if !sum.IsZero() {
+		t.Errorf("postings sum to %s, want 0", sum)
+	}
+	if !byAccount[ids[0]].Equal(decimal.RequireFromString("-25.50")) || !byAccount[ids[1]].Equal(decimal.RequireFromString("25.50")) {
+		t.Errorf("unexpected postings %v", byAccount)
+	}
+}
+
+func TestLedger_UnbalancedEntryRejectedAtCommit(t *testing.T) {
+	db := openTestDB(t)
+	ids := createTestAccounts(t, repository.NewAccountRepository(db), "0")
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback()
+	var entryID int64
+	if err := tx.QueryRow("INSERT INTO journal_entries (kind) VALUES ('test') RETURNING id").Scan(&entryID); err != nil {
+		t.Fatalf("insert entry: %v", err)
+	}
+	// The check is deferred, so the lone posting itself is accepted
+	if _, err := tx.Exec("INSERT INTO postings (entry_id, account_id, amount, currency) VALUES ($1, $2, 10, $3)",
+		entryID, ids[0], models.DefaultCurrency); err != nil {
+		t.Fatalf("insert posting: %v", err)
+	}
+	if err := tx.Commit(); err == nil {
+		t.Fatal("expected the commit of an unbalanced entry to fail")
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM journal_entries WHERE id = $1", entryID).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 0 {
+		t.Error("expected the unbalanced entry to be rolled back")
+	}
+}
+
+func TestLedger_PostingsAreAppendOnly(t *testing.T) {
+	db := openTestDB(t)
+	ids := createTestAccounts(t, repository.NewAccountRepository(db), "100.00", "0")
+	entryID := submitTestTransfer(t, db, ids[0], ids[1], "1.00")
+
+	for _, stmt := range []string{
+		"UPDATE postings SET amount = amount * 2 WHERE entry_id = $1",
+		"DELETE FROM postings WHERE entry_id = $1",
+	} {
+		if _, err := db.Exec(stmt, entryID); err == nil {
+			t.Errorf("%s: expected the change to be rejected", stmt)
+		}
+	}
+
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM postings WHERE entry_id = $1", entryID).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected both postings to remain, got %d", n)
+	}
+}
+
+func TestLedger_BalanceEqualsSumOfPostings(t *testing.T) {
+	db := openTestDB(t)
+	ids := createTestAccounts(t, repository.NewAccountRepository(db), "100.00", "50.00", "0")
+	submitTestTransfer(t, db, ids[0], ids[1], "30.00")
+	submitTestTransfer(t, db, ids[1], ids[2], "45.25")
+	submitTestTransfer(t, db, ids[2], ids[0], "0.25")
+
+	for _, id := range ids {
+		var balance, posted decimal.Decimal
+		err := db.QueryRow(`SELECT balance, (SELECT COALESCE(SUM(amount), 0) FROM postings WHERE account_id = $1)
+			FROM accounts WHERE account_id = $1`, id).Scan(&balance, &posted)
+		if err != nil {
+			t.Fatalf("account %d: %v", id, err)
+		}
+		if !balance.Equal(posted) {
+			t.Errorf("account %d balance = %s, postings sum = %s", id, balance, posted)
+		}
+	}
+}
+
+// systemAccount returns the id of the system account code:USD, which
+// opening a funded account creates
+func systemAccount(t *testing.T, db *sql.DB, code string) int64 {
+	t.Helper()
+	var id int64
+	if err := db.QueryRow("SELECT account_id FROM accounts WHERE system_code = $1", code+":"+string(models.DefaultCurrency)).Scan(&id); err != nil {
+		t.Fatalf("system account %s: %v", code, err)
+	}
+	return id
+}
+
+func TestUpdateAccountStatus_RejectsSystemAccounts(t *testing.T) {
+	db := openTestDB(t)
+	accounts := repository.NewAccountRepository(db)
+	ids := createTestAccounts(t, accounts, "100.00")
+	equity := systemAccount(t, db, models.SystemAccountEquity)
+
+	for name, tc := range map[string]struct {
+		id     int64
+		change models.AccountStatusChange
+	}{
+		"freeze system account":          {equity, models.AccountStatusChange{Status: models.AccountStatusFrozen, Reason: "other"}},
+		"close and sweep system account": {equity, models.AccountStatusChange{Status: models.AccountStatusClosed, Reason: "other", SweepToAccountID: ids[0]}},
+		"sweep into system account":      {ids[0], models.AccountStatusChange{Status: models.AccountStatusClosed, Reason: "other", SweepToAccountID: equity}},
+	} {
+		if _, err := accounts.UpdateAccountStatus(tc.id, tc.change); !errors.Is(err, apperrors.ErrSystemAccount) {
+			t.Errorf("%s: expected ErrSystemAccount, got %v", name, err)
+		}
+	}
+
+	acc, err := accounts.GetAccount(ids[0])
+	if err != nil {
+		t.Fatalf("get account: %v", err)
+	}
+	if acc.Status != models.AccountStatusActive || !decimal.RequireFromString(acc.Balance).Equal(decimal.RequireFromString("100.00")) {
+		t.Errorf("expected account %d untouched, got %s with %s", ids[0], acc.Status, acc.Balance)
+	}
+}
+
+func TestSetOverdraftLimit_RejectsSystemAccounts(t *testing.T) {
+	db := openTestDB(t)
+	accounts := repository.NewAccountRepository(db)
+	createTestAccounts(t, accounts, "100.00")
+	equity := systemAccount(t, db, models.SystemAccountEquity)
+	limit, _ := models.NewMoneyFromString("100.00")
+
+	if _, err := accounts.SetOverdraftLimit(equity, limit, "other", "", models.AuditContext{}); !errors.Is(err, apperrors.ErrSystemAccount) {
+		t.Fatalf("expected ErrSystemAccount, got %v", err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM overdraft_limit_changes WHERE account_id = $1", equity).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("expected no limit change recorded, got %d", n)
+	}
+}
+
+func TestExternalTransfers_RejectSystemAccounts(t *testing.T) {
+	db := openTestDB(t)
+	createTestAccounts(t, repository.NewAccountRepository(db), "100.00")
+	equity := systemAccount(t, db, models.SystemAccountEquity)
+	external := repository.NewExternalTransferRepository(db)
+	amount, _ := models.NewMoneyFromString("1.00")
+	ref := "system-" + time.Now().Format(time.RFC3339Nano)
+
+	transfer := models.ExternalTransfer{AccountID: equity, Amount: amount, Rail: "fake", FundingSource: "card", ExternalReference: ref}
+	if _, err := external.CreateWithdrawal(withDirection(transfer, models.ExternalWithdrawal), models.AuditContext{}); !errors.Is(err, apperrors.ErrSystemAccount) {
+		t.Errorf("withdrawal: expected ErrSystemAccount, got %v", err)
+	}
+	if _, err := external.CreateDeposit(withDirection(transfer, models.ExternalDeposit), models.AuditContext{}); !errors.Is(err, apperrors.ErrSystemAccount) {
+		t.Errorf("deposit: expected ErrSystemAccount, got %v", err)
+	}
+}
+
+func TestExternalDeposits_ClosedAccount(t *testing.T) {
+	db := openTestDB(t)
+	accounts := repository.NewAccountRepository(db)
+	ids := createTestAccounts(t, accounts, "0", "0")
+	external := repository.NewExternalTransferRepository(db)
+	amount, _ := models.NewMoneyFromString("25.00")
+	ref := "closed-" + time.Now().Format(time.RFC3339Nano)
+	closed := models.AccountStatusChange{Status: models.AccountStatusClosed, Reason: "other"}
+
+	if _, err := accounts.UpdateAccountStatus(ids[0], closed); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	deposit := models.ExternalTransfer{AccountID: ids[0], Amount: amount, Rail: "fake", FundingSource: "card", ExternalReference: ref + "-a", Direction: models.ExternalDeposit}
+	if _, err := external.CreateDeposit(deposit, models.AuditContext{}); !errors.Is(err, apperrors.ErrAccountClosed) {
+		t.Errorf("expected ErrAccountClosed creating a deposit, got %v", err)
+	}
+
+	// Closed while the rail was collecting
+	deposit.AccountID, deposit.ExternalReference = ids[1], ref+"-b"
+	pending, err := external.CreateDeposit(deposit, models.AuditContext{})
+	if err != nil {
+		t.Fatalf("create deposit: %v", err)
+	}
+	if _, err := accounts.UpdateAccountStatus(ids[1], closed); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	completed, err := external.CompleteExternalTransfer(pending.ID, "rail-1", models.AuditContext{})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	acc, err := accounts.GetAccount(ids[1])
+	if err != nil {
+		t.Fatalf("get account: %v", err)
+	}
+	if !decimal.RequireFromString(acc.Balance).IsZero() {
+		t.Errorf("expected the closed account to stay empty, got %s", acc.Balance)
+	}
+	var creditedID int64
+	if err := db.QueryRow("SELECT destination_account_id FROM transactions WHERE id = $1", *completed.TransactionID).Scan(&creditedID); err != nil {
+		t.Fatalf("deposit transaction: %v", err)
+	}
+	if want := systemAccount(t, db, models.SystemAccountSuspense); creditedID != want {
+		t.Errorf("expected the deposit credited to suspense account %d, got %d", want, creditedID)
+	}
+}
+
+func withDirection(t models.ExternalTransfer, direction string) models.ExternalTransfer {
+	t.Direction = direction
+	return t
+}
+
+func TestCaptureTransfer_WritesTransferCompleted(t *testing.T) {
+	db := openTestDB(t)
+	ids := createTestAccounts(t, repository.NewAccountRepository(db), "100.00", "0")
+	pending := repository.NewPendingTransferRepository(db)
+	amount, _ := models.NewMoneyFromString("40.00")
+
+	p, err := pending.AuthorizeTransfer(models.TransferRequest{SourceAccountID: ids[0], DestinationAccountID: ids[1], Amount: amount}, time.Minute)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	p, err = pending.CaptureTransfer(p.ID, nil, models.AuditContext{})
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+
+	var n int
+	err = db.QueryRow(`SELECT COUNT(*) FROM outbox
+		WHERE event_type = $1 AND (payload->>'id')::BIGINT = $2`, models.EventTransferCompleted, *p.TransactionID).Scan(&n)
+	if err != nil {
+		t.Fatalf("outbox: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 TransferCompleted event for transaction %d, got %d", *p.TransactionID, n)
+	}
+}
+
+// auditActions lists the actions audited for an entity, oldest first
+func auditActions(t *testing.T, db *sql.DB, entityType string, id int64) []string {
+	t.Helper()
+	rows, err := db.Query("SELECT action FROM audit_log WHERE entity_type = $1 AND entity_id = $2 ORDER BY id", entityType, strconv.FormatInt(id, 10))
+	if err != nil {
+		t.Fatalf("audit log: %v", err)
+	}
+	defer rows.Close()
+	var actions []string
+	for rows.Next() {
+		var action string
+		if err := rows.Scan(&action); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+func TestPendingTransfers_Audited(t *testing.T) {
+	db := openTestDB(t)
+	ids := createTestAccounts(t, repository.NewAccountRepository(db), "100.00", "0")
+	pending := repository.NewPendingTransferRepository(db)
+	amount, _ := models.NewMoneyFromString("10.00")
+	audit := models.AuditContext{Actor: "merchant"}
+	req := models.TransferRequest{SourceAccountID: ids[0], DestinationAccountID: ids[1], Amount: amount, Audit: audit}
+
+	captured, err := pending.AuthorizeTransfer(req, time.Minute)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if captured, err = pending.CaptureTransfer(captured.ID, nil, audit); err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	voided, err := pending.AuthorizeTransfer(req, time.Minute)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	if _, err := pending.VoidTransfer(voided.ID, audit); err != nil {
+		t.Fatalf("void: %v", err)
+	}
+
+	if got := fmt.Sprint(auditActions(t, db, models.AuditEntityPendingTransfer, captured.ID)); got != "[pending_transfer.authorize pending_transfer.capture]" {
+		t.Errorf("captured transfer audited as %s", got)
+	}
+	if got := fmt.Sprint(auditActions(t, db, models.AuditEntityTransaction, *captured.TransactionID)); got != "[transaction.create]" {
+		t.Errorf("capture's transaction audited as %s", got)
+	}
+	if got := fmt.Sprint(auditActions(t, db, models.AuditEntityPendingTransfer, voided.ID)); got != "[pending_transfer.authorize pending_transfer.void]" {
+		t.Errorf("voided transfer audited as %s", got)
+	}
+}
+
+func TestClaimDueScheduledTransfers_IgnoresSessionTimeZone(t *testing.T) {
+	db := openTestDB(t)
+	ids := createTestAccounts(t, repository.NewAccountRepository(db), "100.00", "0")
+	// A session ahead of UTC would see a transfer due in an hour as past due
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("SET TIME ZONE 'Asia/Tokyo'"); err != nil {
+		t.Fatalf("set time zone: %v", err)
+	}
+	scheduled := repository.NewScheduledTransferRepository(db)
+	amount, _ := models.NewMoneyFromString("1.00")
+
+	s, err := scheduled.CreateScheduledTransfer(models.TransferRequest{SourceAccountID: ids[0], DestinationAccountID: ids[1], Amount: amount}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("schedule: %v", err)
+	}
+	claimed, err := scheduled.ClaimDueScheduledTransfers(1000, time.Minute)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	for _, c := range claimed {
+		if c.ID == s.ID {
+			t.Fatalf("transfer %d due in an hour was claimed", s.ID)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"transactions/apperrors"
+	"transactions/handler"
+	"transactions/models"
+	"transactions/payments"
+	"transactions/repository"
+	"transactions/service"
+)
+
+type mockLimitRepo struct {
+	usage models.OutgoingUsage
+}
+
+func (m *mockLimitRepo) LimitRules() ([]models.LimitRule, error) {
+	return nil, nil
+}
+
+func (m *mockLimitRepo) OutgoingUsage(accountID int64, windows models.UsageWindows) (*models.OutgoingUsage, error) {
+	u := m.usage
+	return &u, nil
+}
+
+func money(s string) *models.Money {
+	m, _ := models.NewMoneyFromString(s)
+	return &m
+}
+
+func TestLimitEngine(t *testing.T) {
+	rules := service.StaticLimitRules{
+		{Name: "usd-max", Type: models.LimitMaxAmount, Currency: "USD", Amount: money("1000")},
+		{Name: "usd-daily", Type: models.LimitDailyOutgoing, Currency: "USD", Amount: money("2000")},
+		{Name: "usd-monthly", Type: models.LimitMonthlyOutgoing, Currency: "USD", Amount: money("10000")},
+		{Name: "hourly", Type: models.LimitHourlyCount, MaxCount: 5},
+		{Name: "account-7-max", Type: models.LimitMaxAmount, AccountID: 7, Amount: money("10")},
+	}
+	tests := []struct {
+		name      string
+		accountID int64
+		amount    string
+		usage     models.OutgoingUsage
+		wantErr   error
+	}{
+		{"within limits", 1, "100", models.OutgoingUsage{Currency: "USD"}, nil},
+		{"per transfer maximum", 1, "1000.01", models.OutgoingUsage{Currency: "USD"}, apperrors.ErrTransferLimitExceeded},
+		{"daily cap", 1, "600", models.OutgoingUsage{Currency: "USD", Today: *money("1500"), ThisMonth: *money("1500")}, apperrors.ErrDailyLimitExceeded},
+		{"monthly cap", 1, "600", models.OutgoingUsage{Currency: "USD", ThisMonth: *money("9500")}, apperrors.ErrMonthlyLimitExceeded},
+		{"hourly count", 1, "1", models.OutgoingUsage{Currency: "USD", LastHour: 5}, apperrors.ErrVelocityLimitExceeded},
+		{"currency scoped rules skip other currencies", 1, "5000", models.OutgoingUsage{Currency: "EUR"}, nil},
+		{"account scoped rule", 7, "11", models.OutgoingUsage{Currency: "EUR"}, apperrors.ErrTransferLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := service.NewLimitEngine(rules, &mockLimitRepo{usage: tt.usage})
+			engine.Now = func() time.Time { return time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC) }
+
+			err := engine.Check(models.TransferRequest{SourceAccountID: tt.accountID, DestinationAccountID: 2, Amount: *money(tt.amount)})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLimitEngine_ErrorNamesRule(t *testing.T) {
+	rules := service.StaticLimitRules{{Name: "usd-max", Type: models.LimitMaxAmount, Amount: money("10")}}
+	engine := service.NewLimitEngine(rules, &mockLimitRepo{usage: models.OutgoingUsage{Currency: "USD"}})
+
+	err := engine.Check(models.TransferRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: *money("11")})
+	e, ok := apperrors.As(err)
+	if !ok || e.Code != "transfer_limit_exceeded" {
+		t.Fatalf("expected transfer_limit_exceeded, got %v", err)
+	}
+	if want := "transfer amount limit exceeded: rule usd-max allows at most 10 USD per transfer"; err.Error() != want {
+		t.Errorf("unexpected message: %q", err.Error())
+	}
+}
+
+// idempotentTransferRepo records transfers by idempotency key and counts
+// them as outgoing usage, like the database does
+type idempotentTransferRepo struct {
+	repository.TransactionRepositoryInterface
+	usage    *mockLimitRepo
+	byKey    map[string]*models.Transaction
+	failures int
+}
+
+func (r *idempotentTransferRepo) GetIdempotentTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	return r.byKey[req.IdempotencyKey], nil
+}
+
+func (r *idempotentTransferRepo) SubmitTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	if t := r.byKey[req.IdempotencyKey]; t != nil {
+		return t, nil
+	}
+	t := &models.Transaction{ID: int64(len(r.byKey) + 1), SourceAccountID: req.SourceAccountID, DestinationAccountID: req.DestinationAccountID, Amount: req.Amount}
+	r.byKey[req.IdempotencyKey] = t
+	r.usage.usage.LastHour++
+	return t, nil
+}
+
+func (r *idempotentTransferRepo) RecordTransferFailed(req models.TransferRequest, cause error) error {
+	r.failures++
+	return nil
+}
+
+func TestSubmitTransaction_ReplayAtLimit(t *testing.T) {
+	usage := &mockLimitRepo{usage: models.OutgoingUsage{Currency: "USD"}}
+	repo := &idempotentTransferRepo{usage: usage, byKey: map[string]*models.Transaction{}}
+	limits := service.NewLimitEngine(service.StaticLimitRules{{Name: "hourly", Type: models.LimitHourlyCount, MaxCount: 1}}, usage)
+	h := handler.NewTransactionHandler(service.NewTransactionService(repo, nil, limits, nil))
+
+	submit := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(`{"source_account_id": 1, "destination_account_id": 2, "amount": "10.00"}`))
+		req.Header.Set(handler.IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+		h.SubmitTransaction(w, req)
+		return w
+	}
+
+	if w := submit("key-1"); w.Code != http.StatusCreated {
+		t.Fatalf("expected the first transfer to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	// The first transfer used up the hourly allowance; its retry is still
+	// a replay
+	w := submit("key-1")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected the retry to replay with 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.Transaction `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data.ID != 1 {
+		t.Errorf("expected transaction 1 replayed, got %s", w.Body.String())
+	}
+	if w := submit("key-2"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected a new transfer to hit the limit, got %d", w.Code)
+	}
+	if len(repo.byKey) != 1 || repo.failures != 1 {
+		t.Errorf("expected 1 transfer and only the new one recorded as failed, got %d and %d", len(repo.byKey), repo.failures)
+	}
+}
+
+func TestLimitEngine_CheckBatchSumsLegsPerSource(t *testing.T) {
+	rules := service.StaticLimitRules{
+		{Name: "usd-daily", Type: models.LimitDailyOutgoing, Currency: "USD", Amount: money("100")},
+		{Name: "hourly", Type: models.LimitHourlyCount, MaxCount: 3},
+	}
+	leg := func(src int64, amount string) models.TransferRequest {
+		return models.TransferRequest{SourceAccountID: src, DestinationAccountID: 9, Amount: *money(amount)}
+	}
+	tests := []struct {
+		name    string
+		legs    []models.TransferRequest
+		usage   models.OutgoingUsage
+		wantErr error
+	}{
+		{"legs within the cap", []models.TransferRequest{leg(1, "40"), leg(1, "60")}, models.OutgoingUsage{Currency: "USD"}, nil},
+		{"legs from one source summed", []models.TransferRequest{leg(1, "60"), leg(1, "60")}, models.OutgoingUsage{Currency: "USD"}, apperrors.ErrDailyLimitExceeded},
+		{"sources checked apart", []models.TransferRequest{leg(1, "60"), leg(2, "60")}, models.OutgoingUsage{Currency: "USD"}, nil},
+		{"every leg counts towards velocity", []models.TransferRequest{leg(1, "1"), leg(1, "1")}, models.OutgoingUsage{Currency: "USD", LastHour: 2}, apperrors.ErrVelocityLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := service.NewLimitEngine(rules, &mockLimitRepo{usage: tt.usage})
+			if err := engine.CheckBatch(tt.legs); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAuthorizeTransfer_AtLimit(t *testing.T) {
+	repo := &mockPendingTransferRepo{transfers: map[int64]*models.PendingTransfer{}}
+	limits := service.NewLimitEngine(service.StaticLimitRules{{Name: "usd-max", Type: models.LimitMaxAmount, Amount: money("50")}}, &mockLimitRepo{usage: models.OutgoingUsage{Currency: "USD"}})
+	svc := service.NewPendingTransferService(repo, time.Hour, limits)
+
+	_, err := svc.AuthorizeTransfer(models.TransferRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: *money("60")})
+	if !errors.Is(err, apperrors.ErrTransferLimitExceeded) {
+		t.Fatalf("expected transfer_limit_exceeded, got %v", err)
+	}
+	if len(repo.transfers) != 0 {
+		t.Errorf("expected no funds held, got %d authorizations", len(repo.transfers))
+	}
+}
+
+func TestWithdraw_AtLimit(t *testing.T) {
+	repo := newMockExternalTransferRepo()
+	usage := &mockLimitRepo{usage: models.OutgoingUsage{Currency: "USD"}}
+	limits := service.NewLimitEngine(service.StaticLimitRules{{Name: "usd-daily", Type: models.LimitDailyOutgoing, Amount: money("50")}}, usage)
+	svc := service.NewExternalTransferService(repo, payments.NewFakeRail(), time.Second, limits)
+	withdrawal := func(ref string) models.ExternalTransfer {
+		return models.ExternalTransfer{AccountID: 1, Amount: *money("40"), ExternalReference: ref, FundingSource: "iban-1"}
+	}
+
+	if _, err := svc.Withdraw(withdrawal("wd-1"), models.AuditContext{}); err != nil {
+		t.Fatalf("first withdrawal: %v", err)
+	}
+	usage.usage.Today = *money("40")
+	// A retry of the recorded withdrawal already counts towards the cap
+	if _, err := svc.Withdraw(withdrawal("wd-1"), models.AuditContext{}); err != nil {
+		t.Errorf("expected the retry to replay, got %v", err)
+	}
+	if _, err := svc.Withdraw(withdrawal("wd-2"), models.AuditContext{}); !errors.Is(err, apperrors.ErrDailyLimitExceeded) {
+		t.Errorf("expected daily_limit_exceeded, got %v", err)
+	}
+	if len(repo.transfers) != 1 {
+		t.Errorf("expected 1 withdrawal, got %d", len(repo.transfers))
+	}
+}
+
+func TestOutgoingUsage_CountsOnlyWhatTheAccountSent(t *testing.T) {
+	db := openTestDB(t)
+	ids := createTestAccounts(t, repository.NewAccountRepository(db), "100.00", "0")
+	submitTestTransfer(t, db, ids[0], ids[1], "10.00")
+	var txnID int64
+	if err := db.QueryRow("SELECT id FROM transactions WHERE source_account_id = $1", ids[0]).Scan(&txnID); err != nil {
+		t.Fatalf("find transfer: %v", err)
+	}
+	if _, err := repository.NewTransactionRepository(db).ReverseTransaction(txnID, nil, models.AuditContext{}); err != nil {
+		t.Fatalf("reverse: %v", err)
+	}
+	if _, err := repository.NewPendingTransferRepository(db).AuthorizeTransfer(models.TransferRequest{SourceAccountID: ids[0], DestinationAccountID: ids[1], Amount: *money("5.00")}, time.Minute); err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+
+	limits := repository.NewLimitRepository(db)
+	windows := models.NewUsageWindows(time.Now())
+	sender, err := limits.OutgoingUsage(ids[0], windows)
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	if !sender.Today.Equal(money("15").Decimal) || sender.LastHour != 2 {
+		t.Errorf("expected the transfer and the authorization to count, got %s in %d transfers", sender.Today, sender.LastHour)
+	}
+	// The reversal debited the recipient, but it did not send anything
+	recipient, err := limits.OutgoingUsage(ids[1], windows)
+	if err != nil {
+		t.Fatalf("usage: %v", err)
+	}
+	if !recipient.Today.IsZero() || recipient.LastHour != 0 {
+		t.Errorf("expected no usage from a reversal, got %s in %d transfers", recipient.Today, recipient.LastHour)
+	}
+}
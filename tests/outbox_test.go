@@ -0,0 +1,213 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"transactions/apperrors"
+	"transactions/events"
+	"transactions/models"
+	"transactions/repository"
+	"transactions/service"
+)
+
+// mockOutboxRepo keeps the outbox in memory and applies outcomes the way
+// the database does
+type mockOutboxRepo struct {
+	events    []models.Event
+	published map[int64]bool
+	attempts  map[int64]int
+}
+
+func newMockOutboxRepo(events ...models.Event) *mockOutboxRepo {
+	return &mockOutboxRepo{events: events, published: map[int64]bool{}, attempts: map[int64]int{}}
+}
+
+func (m *mockOutboxRepo) RelayOutbox(limit int, publish func(events []models.Event) map[int64]error) (int, error) {
+	n, attempted := 0, 0
+	var after int64
+	for attempted < limit {
+		var page []models.Event
+		for _, e := range m.events {
+			if !m.published[e.ID] && e.ID > after && len(page) < limit {
+				page = append(page, e)
+			}
+		}
+		if len(page) == 0 {
+			break
+		}
+		outcomes := publish(page)
+		if outcomes == nil {
+			break
+		}
+		for id, err := range outcomes {
+			m.attempts[id]++
+			if err == nil {
+				m.published[id] = true
+				n++
+			}
+		}
+		attempted += len(outcomes)
+		if len(page) < limit {
+			break
+		}
+		after = page[len(page)-1].ID
+	}
+	return n, nil
+}
+
+func outboxEvent(id int64, accounts ...int64) models.Event {
+	return models.Event{ID: id, Type: models.EventTransferCompleted, AccountIDs: accounts, Payload: json.RawMessage(`{}`)}
+}
+
+func publishedIDs(p *events.MemoryPublisher) []int64 {
+	var ids []int64
+	for _, e := range p.Events() {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+func TestOutboxRelay_KeepsPerAccountOrderAcrossFailures(t *testing.T) {
+	repo := newMockOutboxRepo(
+		outboxEvent(1, 1, 2),
+		outboxEvent(2, 3),
+		outboxEvent(3, 2, 4),
+		outboxEvent(4, 5),
+	)
+	publisher := events.NewMemoryPublisher()
+	relay := service.NewOutboxRelay(repo, publisher, time.Second, 10, time.Second)
+
+	// Account 2's consumer is down: event 1 fails and event 3, which also
+	// concerns account 2, must wait behind it
+	publisher.Fail(2, errors.New("broker unavailable"))
+	if n := relay.Relay(context.Background()); n != 2 {
+		t.Fatalf("expected 2 events published, got %d", n)
+	}
+	if got := fmt.Sprint(publishedIDs(publisher)); got != "[2 4]" {
+		t.Errorf("expected events 2 and 4 first, got %s", got)
+	}
+	if repo.attempts[1] != 1 || repo.attempts[3] != 0 {
+		t.Errorf("expected event 1 attempted and event 3 held back, got %v", repo.attempts)
+	}
+
+	publisher.Recover(2)
+	if n := relay.Relay(context.Background()); n != 2 {
+		t.Fatalf("expected the held events to be published, got %d", n)
+	}
+	if got := fmt.Sprint(publishedIDs(publisher)); got != "[2 4 1 3]" {
+		t.Errorf("expected events 1 then 3 after recovery, got %s", got)
+	}
+	if n := relay.Relay(context.Background()); n != 0 {
+		t.Errorf("expected nothing left to publish, got %d", n)
+	}
+}
+
+func TestOutboxRelay_PagesPastHeldEvents(t *testing.T) {
+	// A full batch of account 1's events waits behind its failing first one
+	repo := newMockOutboxRepo(
+		outboxEvent(1, 1),
+		outboxEvent(2, 1),
+		outboxEvent(3, 1, 2),
+		outboxEvent(4, 2),
+		outboxEvent(5, 3),
+	)
+	publisher := events.NewMemoryPublisher()
+	relay := service.NewOutboxRelay(repo, publisher, time.Second, 2, time.Second)
+
+	publisher.Fail(1, errors.New("broker unavailable"))
+	if n := relay.Relay(context.Background()); n != 1 {
+		t.Fatalf("expected 1 event published, got %d", n)
+	}
+	// Event 4 shares account 2 with the held event 3, so it is held too
+	if got := fmt.Sprint(publishedIDs(publisher)); got != "[5]" {
+		t.Errorf("expected account 3's event past the held ones, got %s", got)
+	}
+	if repo.attempts[1] != 1 || repo.attempts[2]+repo.attempts[3]+repo.attempts[4] != 0 {
+		t.Errorf("expected only event 1 attempted of account 1 and 2's events, got %v", repo.attempts)
+	}
+}
+
+func TestOutboxRelay_BatchSize(t *testing.T) {
+	repo := newMockOutboxRepo(outboxEvent(1, 1), outboxEvent(2, 1), outboxEvent(3, 1))
+	publisher := events.NewMemoryPublisher()
+	relay := service.NewOutboxRelay(repo, publisher, time.Second, 2, time.Second)
+
+	if n := relay.Relay(context.Background()); n != 2 {
+		t.Fatalf("expected one batch of 2, got %d", n)
+	}
+	if n := relay.Relay(context.Background()); n != 1 {
+		t.Fatalf("expected the last event in the next pass, got %d", n)
+	}
+}
+
+func TestWriterPublisher_WritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	p := events.NewWriterPublisher("test", &buf)
+	for _, e := range []models.Event{outboxEvent(1, 1, 2), outboxEvent(2, 3)} {
+		if err := p.Publish(context.Background(), e); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	var e models.Event
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatalf("invalid JSON line: %v", err)
+	}
+	if e.ID != 1 || e.Type != models.EventTransferCompleted || fmt.Sprint(e.AccountIDs) != "[1 2]" {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+// failingTransferRepo rejects every transfer with err and records the
+// TransferFailed events the service asks for
+type failingTransferRepo struct {
+	repository.TransactionRepositoryInterface
+	err      error
+	failures []error
+}
+
+func (f *failingTransferRepo) SubmitTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	return nil, f.err
+}
+
+func (f *failingTransferRepo) RecordTransferFailed(req models.TransferRequest, cause error) error {
+	f.failures = append(f.failures, cause)
+	return nil
+}
+
+func TestSubmitTransaction_RecordsTransferFailed(t *testing.T) {
+	amount, _ := models.NewMoneyFromString("10.00")
+	req := models.TransferRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: amount}
+
+	tests := []struct {
+		name     string
+		err      error
+		recorded bool
+	}{
+		{"business rejection", fmt.Errorf("%w: account 1", apperrors.ErrInsufficientFunds), true},
+		{"unknown account", fmt.Errorf("%w: account 2", apperrors.ErrAccountNotFound), false},
+		{"database down", fmt.Errorf("%w: connection refused", apperrors.ErrDatabaseUnavailable), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &failingTransferRepo{err: tt.err}
+			_, err := service.NewTransactionService(repo, nil, nil, nil).SubmitTransaction(req)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected the original error, got %v", err)
+			}
+			if recorded := len(repo.failures) == 1; recorded != tt.recorded {
+				t.Errorf("expected TransferFailed recorded: %t, got %v", tt.recorded, repo.failures)
+			}
+		})
+	}
+}
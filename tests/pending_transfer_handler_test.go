@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"transactions/apperrors"
+	"transactions/handler"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+type mockPendingTransferRepo struct {
+	transfers map[int64]*models.PendingTransfer
+}
+
+func (m *mockPendingTransferRepo) AuthorizeTransfer(req models.TransferRequest, ttl time.Duration) (*models.PendingTransfer, error) {
+	if req.Amount.String() == "9999" {
+		return nil, apperrors.ErrInsufficientFunds
+	}
+	p := &models.PendingTransfer{
+		ID:                   int64(len(m.transfers) + 1),
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               req.Amount,
+		Currency:             models.DefaultCurrency,
+		Status:               models.PendingTransferPending,
+		CreatedAt:            time.Now(),
+	}
+	p.ExpiresAt = p.CreatedAt.Add(ttl)
+	m.transfers[p.ID] = p
+	return p, nil
+}
+
+func (m *mockPendingTransferRepo) pending(id int64) (*models.PendingTransfer, error) {
+	p, ok := m.transfers[id]
+	if !ok {
+		return nil, apperrors.ErrTransferNotFound
+	}
+	if p.Status != models.PendingTransferPending {
+		return nil, fmt.Errorf("%w: transfer %d is %s", apperrors.ErrTransferNotPending, id, p.Status)
+	}
+	return p, nil
+}
+
+func (m *mockPendingTransferRepo) CaptureTransfer(id int64, amount *models.Money, audit models.AuditContext) (*models.PendingTransfer, error) {
+	p, err := m.pending(id)
+	if err != nil {
+		return nil, err
+	}
+	capture := p.Amount
+	if amount != nil {
+		if amount.GreaterThan(p.Amount.Decimal) {
+			return nil, apperrors.ErrInvalidAmount
+		}
+		capture = *amount
+	}
+	transactionID := int64(100 + id)
+	p.Status, p.CapturedAmount, p.TransactionID = models.PendingTransferCaptured, &capture, &transactionID
+	return p, nil
+}
+
+func (m *mockPendingTransferRepo) VoidTransfer(id int64, audit models.AuditContext) (*models.PendingTransfer, error) {
+	p, err := m.pending(id)
+	if err != nil {
+		return nil, err
+	}
+	p.Status = models.PendingTransferVoided
+	return p, nil
+}
+
+func (m *mockPendingTransferRepo) GetPendingTransfer(id int64) (*models.PendingTransfer, error) {
+	p, ok := m.transfers[id]
+	if !ok {
+		return nil, apperrors.ErrTransferNotFound
+	}
+	return p, nil
+}
+
+func (m *mockPendingTransferRepo) ExpirePendingTransfers(limit int, audit models.AuditContext) (int64, error) {
+	return 0, nil
+}
+
+func newTestTransferRouter() http.Handler {
+	repo := &mockPendingTransferRepo{transfers: map[int64]*models.PendingTransfer{}}
+	h := handler.NewPendingTransferHandler(service.NewPendingTransferService(repo, time.Hour, nil))
+	r := mux.NewRouter()
+	r.HandleFunc("/transfers", h.AuthorizeTransfer).Methods("POST")
+	r.HandleFunc("/transfers/{id:[0-9]+}", h.GetPendingTransfer).Methods("GET")
+	r.HandleFunc("/transfers/{id:[0-9]+}/capture", h.CaptureTransfer).Methods("POST")
+	r.HandleFunc("/transfers/{id:[0-9]+}/void", h.VoidTransfer).Methods("POST")
+	return r
+}
+
+func TestAuthorizeTransfer_Success(t *testing.T) {
+	r := newTestTransferRouter()
+	w, p := doRequest[models.PendingTransfer](t, r, http.MethodPost, "/transfers", `{"source_account_id": 1, "destination_account_id": 2, "amount": "40.00"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if p.Status != models.PendingTransferPending || p.Amount.String() != "40" {
+		t.Errorf("unexpected transfer: %+v", p)
+	}
+	if loc := w.Header().Get("Location"); loc != fmt.Sprintf("/transfers/%d", p.ID) {
+		t.Errorf("unexpected Location header: %q", loc)
+	}
+}
+
+func TestAuthorizeTransfer_Errors(t *testing.T) {
+	r := newTestTransferRouter()
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"invalid amount", `{"source_account_id": 1, "destination_account_id": 2, "amount": "-1"}`, http.StatusBadRequest},
+		{"same account", `{"source_account_id": 1, "destination_account_id": 1, "amount": "1"}`, http.StatusBadRequest},
+		{"insufficient funds", `{"source_account_id": 1, "destination_account_id": 2, "amount": "9999"}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, _ := doRequest[models.PendingTransfer](t, r, http.MethodPost, "/transfers", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCaptureTransfer_Partial(t *testing.T) {
+	r := newTestTransferRouter()
+	_, p := doRequest[models.PendingTransfer](t, r, http.MethodPost, "/transfers", `{"source_account_id": 1, "destination_account_id": 2, "amount": "40.00"}`)
+	path := fmt.Sprintf("/transfers/%d/capture", p.ID)
+
+	w, captured := doRequest[models.PendingTransfer](t, r, http.MethodPost, path, `{"amount": "25.00"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if captured.Status != models.PendingTransferCaptured || captured.CapturedAmount == nil || captured.CapturedAmount.String() != "25" {
+		t.Errorf("unexpected capture: %+v", captured)
+	}
+	if captured.TransactionID == nil {
+		t.Error("expected the capture to reference its transaction")
+	}
+
+	// A transfer can only be settled once
+	w, _ = doRequest[models.PendingTransfer](t, r, http.MethodPost, path, "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 on second capture, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCaptureTransfer_FullWithoutBody(t *testing.T) {
+	r := newTestTransferRouter()
+	_, p := doRequest[models.PendingTransfer](t, r, http.MethodPost, "/transfers", `{"source_account_id": 1, "destination_account_id": 2, "amount": "40.00"}`)
+
+	w, captured := doRequest[models.PendingTransfer](t, r, http.MethodPost, fmt.Sprintf("/transfers/%d/capture", p.ID), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if captured.CapturedAmount == nil || captured.CapturedAmount.String() != "40" {
+		t.Errorf("expected full capture, got %+v", captured.CapturedAmount)
+	}
+}
+
+func TestVoidTransfer(t *testing.T) {
+	r := newTestTransferRouter()
+	_, p := doRequest[models.PendingTransfer](t, r, http.MethodPost, "/transfers", `{"source_account_id": 1, "destination_account_id": 2, "amount": "40.00"}`)
+
+	w, voided := doRequest[models.PendingTransfer](t, r, http.MethodPost, fmt.Sprintf("/transfers/%d/void", p.ID), "")
+	if w.Code != http.StatusOK || voided.Status != models.PendingTransferVoided {
+		t.Fatalf("expected voided transfer, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w, _ = doRequest[models.PendingTransfer](t, r, http.MethodPost, fmt.Sprintf("/transfers/%d/capture", p.ID), "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 capturing a voided transfer, got %d", w.Code)
+	}
+
+	w, _ = doRequest[models.PendingTransfer](t, r, http.MethodPost, "/transfers/42/void", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown transfer, got %d", w.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"testing"
+	"transactions/apperrors"
+	"transactions/models"
+	"transactions/service"
+)
+
+type mockReconciliationRepo struct {
+	report   models.ReconciliationReport
+	err      error
+	recorded []models.ReconciliationReport
+}
+
+func (m *mockReconciliationRepo) Reconcile() (*models.ReconciliationReport, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	report := m.report
+	return &report, nil
+}
+
+func (m *mockReconciliationRepo) RecordReconciliationRun(report models.ReconciliationReport) (int64, error) {
+	m.recorded = append(m.recorded, report)
+	return int64(len(m.recorded)), nil
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	repo := &mockReconciliationRepo{report: models.ReconciliationReport{
+		AccountsChecked: 3,
+		Drifts: []models.AccountDrift{
+			{AccountID: 2, Currency: "USD", Balance: *money("110"), LedgerBalance: *money("100"), Drift: *money("10")},
+		},
+		Currencies: []models.CurrencyTotal{
+			{Currency: "USD", BalancesTotal: *money("10"), PostingsTotal: *money("0")},
+		},
+	}}
+	r := service.NewReconciler(repo, 0)
+
+	report, err := r.Reconcile(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.RunID != nil || len(repo.recorded) != 0 {
+		t.Error("expected a run without -record not to be stored")
+	}
+
+	report, err = r.Reconcile(true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.RunID == nil || *report.RunID != 1 || len(repo.recorded[0].Drifts) != 1 {
+		t.Errorf("expected the run and its drift to be recorded, got %+v", repo.recorded)
+	}
+
+	repo.err = apperrors.ErrDatabaseUnavailable
+	if _, err := r.Reconcile(true); err == nil {
+		t.Error("expected the database error to be returned")
+	}
+	if len(repo.recorded) != 1 {
+		t.Errorf("expected no run to be recorded when reconciliation fails, got %d", len(repo.recorded))
+	}
+}
+
+func TestCurrencyTotal_Conserved(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    models.CurrencyTotal
+		expected bool
+	}{
+		{"conserved", models.CurrencyTotal{BalancesTotal: *money("0"), PostingsTotal: *money("0.0000")}, true},
+		{"balances edited", models.CurrencyTotal{BalancesTotal: *money("5"), PostingsTotal: *money("0")}, false},
+		{"unbalanced postings", models.CurrencyTotal{BalancesTotal: *money("0"), PostingsTotal: *money("-0.01")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.total.Conserved(); got != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
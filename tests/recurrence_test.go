@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"testing"
+	"time"
+	"transactions/recurrence"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		expr, after, want string
+	}{
+		{"0 9 1 * *", "2025-01-15T10:00:00Z", "2025-02-01T09:00:00Z"},
+		{"@monthly", "2025-01-01T00:00:00Z", "2025-02-01T00:00:00Z"},
+		{"30 8 * * FRI", "2025-01-15T10:00:00Z", "2025-01-17T08:30:00Z"},
+		{"0 0 * * 7", "2025-01-15T10:00:00Z", "2025-01-19T00:00:00Z"},
+		{"*/15 * * * *", "2025-01-15T10:07:12Z", "2025-01-15T10:15:00Z"},
+		// Days past the end of a month fall on its last day
+		{"0 12 31 * *", "2025-01-31T12:00:00Z", "2025-02-28T12:00:00Z"},
+		{"0 12 31 * *", "2024-02-01T00:00:00Z", "2024-02-29T12:00:00Z"},
+		{"0 12 L * *", "2025-04-01T00:00:00Z", "2025-04-30T12:00:00Z"},
+		{"0 0 29 2 *", "2025-01-01T00:00:00Z", "2025-02-28T00:00:00Z"},
+		// A restricted day of month and weekday match if either does
+		{"0 0 13 * FRI", "2025-01-01T00:00:00Z", "2025-01-03T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr+" after "+tt.after, func(t *testing.T) {
+			s, err := recurrence.Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := s.Next(at(tt.after)); !got.Equal(at(tt.want)) {
+				t.Errorf("expected %s, got %s", tt.want, got.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * MON-", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := recurrence.Parse(expr); err == nil {
+			t.Errorf("expected %q to be rejected", expr)
+		}
+	}
+}
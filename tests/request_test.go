@@ -0,0 +1,28 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// doRequest serves a request through r and decodes the data field of the
+// JSON response, which is left zero for error responses
+func doRequest[T any](t *testing.T, r http.Handler, method, path, body string) (*httptest.ResponseRecorder, T) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp struct {
+		Data T `json:"data"`
+	}
+	if w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s %s: decode response %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+	return w, resp.Data
+}
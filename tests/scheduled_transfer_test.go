@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+	"transactions/apperrors"
+	"transactions/handler"
+	"transactions/models"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+type mockScheduledTransferRepo struct {
+	transfers map[int64]*models.ScheduledTransfer
+	due       []models.ScheduledTransfer
+	completed map[int64]int64
+	failed    map[int64]string
+}
+
+func newMockScheduledTransferRepo() *mockScheduledTransferRepo {
+	return &mockScheduledTransferRepo{
+		transfers: map[int64]*models.ScheduledTransfer{},
+		completed: map[int64]int64{},
+		failed:    map[int64]string{},
+	}
+}
+
+func (m *mockScheduledTransferRepo) CreateScheduledTransfer(req models.TransferRequest, executeAt time.Time) (*models.ScheduledTransfer, error) {
+	s := &models.ScheduledTransfer{
+		ID:                   int64(len(m.transfers) + 1),
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               req.Amount,
+		ExecuteAt:            executeAt,
+		Status:               models.ScheduledTransferScheduled,
+	}
+	m.transfers[s.ID] = s
+	return s, nil
+}
+
+func (m *mockScheduledTransferRepo) GetScheduledTransfer(id int64) (*models.ScheduledTransfer, error) {
+	s, ok := m.transfers[id]
+	if !ok {
+		return nil, apperrors.ErrScheduleNotFound
+	}
+	return s, nil
+}
+
+func (m *mockScheduledTransferRepo) ListScheduledTransfers(filter models.ScheduledTransferFilter) ([]models.ScheduledTransfer, error) {
+	return nil, nil
+}
+
+func (m *mockScheduledTransferRepo) CancelScheduledTransfer(id int64) (*models.ScheduledTransfer, error) {
+	s, err := m.GetScheduledTransfer(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status != models.ScheduledTransferScheduled {
+		return nil, fmt.Errorf("%w: scheduled transfer %d is %s", apperrors.ErrScheduleNotScheduled, id, s.Status)
+	}
+	s.Status = models.ScheduledTransferCancelled
+	return s, nil
+}
+
+func (m *mockScheduledTransferRepo) ClaimDueScheduledTransfers(limit int, lease time.Duration) ([]models.ScheduledTransfer, error) {
+	due := m.due
+	m.due = nil
+	return due, nil
+}
+
+func (m *mockScheduledTransferRepo) CompleteScheduledTransfer(id, transactionID int64) error {
+	m.completed[id] = transactionID
+	return nil
+}
+
+func (m *mockScheduledTransferRepo) FailScheduledTransfer(id int64, code, reason string) error {
+	m.failed[id] = code
+	return nil
+}
+
+// submitRecorder records the idempotency keys transfers are submitted under
+// and reports the database as unavailable for an amount of 503.
+type submitRecorder struct {
+	fakeTransactionService
+	keys []string
+}
+
+func (s *submitRecorder) SubmitTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	s.keys = append(s.keys, req.IdempotencyKey)
+	if req.Amount.String() == "503" {
+		return nil, apperrors.ErrDatabaseUnavailable
+	}
+	return s.fakeTransactionService.SubmitTransaction(req)
+}
+
+func TestScheduledTransferWorker_ExecuteDue(t *testing.T) {
+	repo := newMockScheduledTransferRepo()
+	submitted := int64(77)
+	due := func(id int64, amount string) models.ScheduledTransfer {
+		return models.ScheduledTransfer{ID: id, SourceAccountID: 1, DestinationAccountID: 2, Amount: *money(amount), Status: models.ScheduledTransferProcessing}
+	}
+	repo.due = []models.ScheduledTransfer{due(1, "10"), due(2, "9999"), due(3, "503"), due(4, "10")}
+	repo.due[3].SubmittedTransactionID = &submitted
+
+	transactions := &submitRecorder{}
+	worker := service.NewScheduledTransferWorker(repo, transactions, time.Minute, time.Minute)
+	if n := worker.ExecuteDue(); n != 2 {
+		t.Errorf("expected 2 executed transfers, got %d", n)
+	}
+
+	if repo.completed[1] != 10 {
+		t.Errorf("expected transfer 1 to record transaction 10, got %d", repo.completed[1])
+	}
+	if repo.failed[2] != "insufficient_funds" {
+		t.Errorf("expected transfer 2 to fail with insufficient_funds, got %q", repo.failed[2])
+	}
+	if _, ok := repo.failed[3]; ok {
+		t.Error("expected transfer 3 to be left for retry when the database is unavailable")
+	}
+	if repo.completed[4] != submitted {
+		t.Errorf("expected transfer 4 to record its earlier transaction, got %d", repo.completed[4])
+	}
+
+	want := []string{"scheduled-1", "scheduled-2", "scheduled-3"}
+	if fmt.Sprint(transactions.keys) != fmt.Sprint(want) {
+		t.Errorf("expected submissions under %v, got %v", want, transactions.keys)
+	}
+}
+
+func newTestScheduledTransferRouter() http.Handler {
+	h := handler.NewScheduledTransferHandler(service.NewScheduledTransferService(newMockScheduledTransferRepo()))
+	r := mux.NewRouter()
+	r.HandleFunc("/scheduled-transfers", h.CreateScheduledTransfer).Methods("POST")
+	r.HandleFunc("/scheduled-transfers/{id:[0-9]+}", h.GetScheduledTransfer).Methods("GET")
+	r.HandleFunc("/scheduled-transfers/{id:[0-9]+}/cancel", h.CancelScheduledTransfer).Methods("POST")
+	return r
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	r := newTestScheduledTransferRouter()
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	past := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"valid", `{"source_account_id": 1, "destination_account_id": 2, "amount": "10", "execute_at": "` + future + `"}`, http.StatusCreated},
+		{"past execute_at", `{"source_account_id": 1, "destination_account_id": 2, "amount": "10", "execute_at": "` + past + `"}`, http.StatusBadRequest},
+		{"missing execute_at", `{"source_account_id": 1, "destination_account_id": 2, "amount": "10"}`, http.StatusBadRequest},
+		{"invalid amount", `{"source_account_id": 1, "destination_account_id": 2, "amount": "0", "execute_at": "` + future + `"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, s := doRequest[models.ScheduledTransfer](t, r, http.MethodPost, "/scheduled-transfers", tt.body)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code == http.StatusCreated && s.Status != models.ScheduledTransferScheduled {
+				t.Errorf("expected status scheduled, got %q", s.Status)
+			}
+		})
+	}
+}
+
+func TestCreateScheduledTransfer_NormalizesOffsetToUTC(t *testing.T) {
+	repo := newMockScheduledTransferRepo()
+	h := handler.NewScheduledTransferHandler(service.NewScheduledTransferService(repo))
+	r := mux.NewRouter()
+	r.HandleFunc("/scheduled-transfers", h.CreateScheduledTransfer).Methods("POST")
+
+	// execute_at is stored without a time zone, so it must reach the
+	// repository as UTC: 10:00 at +02:00 is 08:00 UTC
+	at := time.Now().Add(48 * time.Hour).In(time.FixedZone("", 2*60*60)).Truncate(time.Hour)
+	body := `{"source_account_id": 1, "destination_account_id": 2, "amount": "10", "execute_at": "` + at.Format(time.RFC3339) + `"}`
+	w, _ := doRequest[models.ScheduledTransfer](t, r, http.MethodPost, "/scheduled-transfers", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	got := repo.transfers[1].ExecuteAt
+	if got.Location() != time.UTC || !got.Equal(at) || got.Hour() != at.UTC().Hour() {
+		t.Errorf("expected execute_at %s, got %s", at.UTC(), got)
+	}
+}
+
+func TestCancelScheduledTransfer(t *testing.T) {
+	r := newTestScheduledTransferRouter()
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	_, s := doRequest[models.ScheduledTransfer](t, r, http.MethodPost, "/scheduled-transfers", `{"source_account_id": 1, "destination_account_id": 2, "amount": "10", "execute_at": "`+future+`"}`)
+	path := fmt.Sprintf("/scheduled-transfers/%d/cancel", s.ID)
+
+	w, cancelled := doRequest[models.ScheduledTransfer](t, r, http.MethodPost, path, "")
+	if w.Code != http.StatusOK || cancelled.Status != models.ScheduledTransferCancelled {
+		t.Fatalf("expected cancelled transfer, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w, _ = doRequest[models.ScheduledTransfer](t, r, http.MethodPost, path, "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status 409 cancelling twice, got %d", w.Code)
+	}
+
+	w, _ = doRequest[models.ScheduledTransfer](t, r, http.MethodPost, "/scheduled-transfers/42/cancel", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for unknown transfer, got %d", w.Code)
+	}
+}
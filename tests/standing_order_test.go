@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"testing"
+	"time"
+	"transactions/models"
+	"transactions/service"
+)
+
+type recordedRun struct {
+	run       models.StandingOrderRun
+	nextRunAt *time.Time
+	retryAt   *time.Time
+}
+
+type mockStandingOrderRepo struct {
+	due      []models.StandingOrder
+	recorded map[int64]recordedRun
+}
+
+func (m *mockStandingOrderRepo) CreateStandingOrder(o models.StandingOrder) (*models.StandingOrder, error) {
+	return &o, nil
+}
+
+func (m *mockStandingOrderRepo) GetStandingOrder(id int64) (*models.StandingOrder, error) {
+	return nil, nil
+}
+
+func (m *mockStandingOrderRepo) ListStandingOrders(filter models.StandingOrderFilter) ([]models.StandingOrder, error) {
+	return nil, nil
+}
+
+func (m *mockStandingOrderRepo) UpdateStandingOrderStatus(id int64, status string, nextRunAt *time.Time) (*models.StandingOrder, error) {
+	return nil, nil
+}
+
+func (m *mockStandingOrderRepo) ListStandingOrderRuns(id int64, limit int) ([]models.StandingOrderRun, error) {
+	return nil, nil
+}
+
+func (m *mockStandingOrderRepo) ClaimDueStandingOrders(limit int, lease time.Duration) ([]models.StandingOrder, error) {
+	due := m.due
+	m.due = nil
+	return due, nil
+}
+
+func (m *mockStandingOrderRepo) RecordStandingOrderRun(run models.StandingOrderRun, nextRunAt, retryAt *time.Time) error {
+	m.recorded[run.StandingOrderID] = recordedRun{run, nextRunAt, retryAt}
+	return nil
+}
+
+func TestStandingOrderWorker_RunDue(t *testing.T) {
+	now := time.Date(2025, 1, 31, 9, 0, 30, 0, time.UTC)
+	occurrence := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(2025, 2, 28, 9, 0, 0, 0, time.UTC)
+	endsAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	order := func(id int64, amount, policy string, retries int) models.StandingOrder {
+		return models.StandingOrder{
+			ID: id, SourceAccountID: 1, DestinationAccountID: 2, Amount: *money(amount),
+			Schedule: "0 9 31 * *", OnInsufficientFunds: policy, MaxRetries: 2, RetryIntervalSeconds: 3600,
+			Status: models.StandingOrderActive, NextRunAt: occurrence, Retries: retries,
+		}
+	}
+	ending := order(6, "10", models.InsufficientFundsSkip, 0)
+	ending.EndsAt = &endsAt
+
+	repo := &mockStandingOrderRepo{recorded: map[int64]recordedRun{}}
+	repo.due = []models.StandingOrder{
+		order(1, "10", models.InsufficientFundsSkip, 0),
+		order(2, "9999", models.InsufficientFundsRetry, 0),
+		order(3, "9999", models.InsufficientFundsRetry, 2),
+		order(4, "9999", models.InsufficientFundsSkip, 0),
+		order(5, "503", models.InsufficientFundsSkip, 0),
+		ending,
+	}
+	transactions := &submitRecorder{}
+	worker := service.NewStandingOrderWorker(repo, transactions, time.Minute, time.Minute)
+	worker.Now = func() time.Time { return now }
+
+	if n := worker.RunDue(); n != 2 {
+		t.Errorf("expected 2 executed runs, got %d", n)
+	}
+	if transactions.keys[0] != "standing-1-202501310900" {
+		t.Errorf("unexpected idempotency key %q", transactions.keys[0])
+	}
+
+	tests := []struct {
+		id        int64
+		status    string
+		attempt   int
+		nextRunAt *time.Time
+		retry     bool
+	}{
+		{1, models.StandingOrderRunExecuted, 1, &nextMonth, false},
+		{2, models.StandingOrderRunRetrying, 1, &nextMonth, true},
+		{3, models.StandingOrderRunSkipped, 3, &nextMonth, false},
+		{4, models.StandingOrderRunSkipped, 1, &nextMonth, false},
+		{6, models.StandingOrderRunExecuted, 1, nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := repo.recorded[tt.id]
+		if !ok {
+			t.Errorf("order %d: no run recorded", tt.id)
+			continue
+		}
+		if got.run.Status != tt.status || got.run.Attempt != tt.attempt {
+			t.Errorf("order %d: expected %s attempt %d, got %s attempt %d", tt.id, tt.status, tt.attempt, got.run.Status, got.run.Attempt)
+		}
+		if (got.retryAt != nil) != tt.retry {
+			t.Errorf("order %d: unexpected retry time %v", tt.id, got.retryAt)
+		}
+		if (got.nextRunAt == nil) != (tt.nextRunAt == nil) || (got.nextRunAt != nil && !got.nextRunAt.Equal(*tt.nextRunAt)) {
+			t.Errorf("order %d: expected next run %v, got %v", tt.id, tt.nextRunAt, got.nextRunAt)
+		}
+	}
+	if got := repo.recorded[4].run.FailureCode; got != "insufficient_funds" {
+		t.Errorf("expected skipped run to record insufficient_funds, got %q", got)
+	}
+	if _, ok := repo.recorded[5]; ok {
+		t.Error("expected no run recorded while the database is unavailable")
+	}
+}
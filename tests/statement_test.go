@@ -0,0 +1,222 @@
+package tests
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"transactions/apperrors"
+	"transactions/handler"
+	"transactions/models"
+	"transactions/repository"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+// mockStatementRepo streams a fixed statement for account 1: an opening
+// balance of 100, a debit of 30 with a fee of 1 and a credit of 5.50.
+type mockStatementRepo struct {
+	from, to time.Time
+	failAt   int
+}
+
+func (m *mockStatementRepo) StreamStatement(accountID int64, from, to time.Time, w models.StatementWriter) error {
+	if accountID == 404 {
+		return fmt.Errorf("%w: account %d", apperrors.ErrAccountNotFound, accountID)
+	}
+	m.from, m.to = from, to
+	s := models.Statement{AccountID: accountID, Currency: "USD", From: from, To: to, OpeningBalance: *money("100")}
+	if err := w.Open(s); err != nil {
+		return err
+	}
+	lines := []models.StatementLine{
+		{TransactionID: 7, CreatedAt: from.Add(time.Hour), Direction: models.DirectionDebit, CounterpartyAccountID: 2,
+			Description: "Transfer to account 2", Amount: *money("-31"), Fee: *money("1"), Balance: *money("69")},
+		{TransactionID: 9, CreatedAt: from.Add(2 * time.Hour), Direction: models.DirectionCredit, CounterpartyAccountID: 3,
+			Description: "Transfer from account 3 & co", Amount: *money("5.5"), Balance: *money("74.5")},
+	}
+	for i, l := range lines {
+		if m.failAt == i+1 {
+			return errors.New("connection reset")
+		}
+		if err := w.Line(l); err != nil {
+			return err
+		}
+	}
+	s.ClosingBalance = *money("74.5")
+	return w.Close(s)
+}
+
+func newTestStatementRouter(repo *mockStatementRepo) http.Handler {
+	h := handler.NewStatementHandler(service.NewStatementService(repo))
+	r := mux.NewRouter()
+	r.HandleFunc("/accounts/{account_id}/statements", h.GetStatement).Methods("GET")
+	return r
+}
+
+func getStatement(r http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestStatement_JSON(t *testing.T) {
+	repo := &mockStatementRepo{}
+	w := getStatement(newTestStatementRouter(repo), "/accounts/1/statements?from=2025-01-01&to=2025-01-31")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Success bool `json:"success"`
+		Data    struct {
+			models.Statement
+			Transactions []models.StatementLine `json:"transactions"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v: %s", err, w.Body.String())
+	}
+	if !resp.Success || resp.Data.OpeningBalance.String() != "100" || resp.Data.ClosingBalance.String() != "74.5" {
+		t.Errorf("unexpected statement: %+v", resp.Data.Statement)
+	}
+	if len(resp.Data.Transactions) != 2 || resp.Data.Transactions[1].Balance.String() != "74.5" {
+		t.Errorf("unexpected transactions: %+v", resp.Data.Transactions)
+	}
+
+	// The end date is included, so the statement runs to the next midnight
+	if !repo.to.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the statement to end on 2025-02-01, got %s", repo.to)
+	}
+}
+
+func TestStatement_CSV(t *testing.T) {
+	w := getStatement(newTestStatementRouter(&mockStatementRepo{}), "/accounts/1/statements?from=2025-01-01&to=2025-01-31&format=csv")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Errorf("unexpected Content-Type %q", w.Header().Get("Content-Type"))
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("expected a header, opening, 2 transactions and closing, got %d rows", len(rows))
+	}
+	if rows[1][2] != "Opening balance" || rows[1][6] != "100.00" {
+		t.Errorf("unexpected opening row %v", rows[1])
+	}
+	if rows[2][4] != "-31.00" || rows[2][5] != "1.00" || rows[2][6] != "69.00" {
+		t.Errorf("unexpected transaction row %v", rows[2])
+	}
+	if rows[4][2] != "Closing balance" || rows[4][6] != "74.50" {
+		t.Errorf("unexpected closing row %v", rows[4])
+	}
+}
+
+func TestStatement_OFX(t *testing.T) {
+	w := getStatement(newTestStatementRouter(&mockStatementRepo{}), "/accounts/1/statements?from=2025-01-01T00:00:00Z&to=2025-02-01T00:00:00Z&format=ofx")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"<CURDEF>USD</CURDEF>",
+		"<ACCTID>1</ACCTID>",
+		"<TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20250101010000.000[0:GMT]</DTPOSTED><TRNAMT>-31.00</TRNAMT><FITID>7</FITID>",
+		"<MEMO>Transfer from account 3 &amp; co</MEMO>",
+		"<LEDGERBAL><BALAMT>74.50</BALAMT>",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected OFX to contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestStatement_Errors(t *testing.T) {
+	r := newTestStatementRouter(&mockStatementRepo{})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"missing range", "/accounts/1/statements", http.StatusBadRequest},
+		{"to before from", "/accounts/1/statements?from=2025-02-01&to=2025-01-01", http.StatusBadRequest},
+		{"unknown format", "/accounts/1/statements?from=2025-01-01&to=2025-01-31&format=pdf", http.StatusBadRequest},
+		{"unknown account", "/accounts/404/statements?from=2025-01-01&to=2025-01-31", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getStatement(r, tt.path)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestStatement_FailureWhileStreamingAborts(t *testing.T) {
+	r := newTestStatementRouter(&mockStatementRepo{failAt: 2})
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("expected the response to be aborted, got %v", rec)
+		}
+	}()
+	getStatement(r, "/accounts/1/statements?from=2025-01-01&to=2025-01-31")
+}
+
+// recordingStatementWriter keeps the statement it is given
+type recordingStatementWriter struct {
+	statement models.Statement
+	lines     []models.StatementLine
+}
+
+func (w *recordingStatementWriter) Open(s models.Statement) error {
+	return nil
+}
+
+func (w *recordingStatementWriter) Line(l models.StatementLine) error {
+	w.lines = append(w.lines, l)
+	return nil
+}
+
+func (w *recordingStatementWriter) Close(s models.Statement) error {
+	w.statement = s
+	return nil
+}
+
+func TestStreamStatement_IncludesOpeningBalance(t *testing.T) {
+	db := openTestDB(t)
+	from := time.Now().UTC().Add(-time.Minute)
+	ids := createTestAccounts(t, repository.NewAccountRepository(db), "100.00", "0")
+	submitTestTransfer(t, db, ids[0], ids[1], "30.00")
+
+	var w recordingStatementWriter
+	if err := repository.NewStatementRepository(db).StreamStatement(ids[0], from, time.Now().UTC().Add(time.Minute), &w); err != nil {
+		t.Fatalf("statement: %v", err)
+	}
+	if len(w.lines) != 2 {
+		t.Fatalf("expected the opening balance and the transfer, got %+v", w.lines)
+	}
+	opening, transfer := w.lines[0], w.lines[1]
+	if opening.TransactionID != 0 || opening.Description != "Opening balance" || opening.CounterpartyAccountID != systemAccount(t, db, models.SystemAccountEquity) ||
+		!opening.Amount.Equal(money("100").Decimal) || !opening.Balance.Equal(money("100").Decimal) {
+		t.Errorf("unexpected opening balance line %+v", opening)
+	}
+	if !transfer.Balance.Equal(money("70").Decimal) || !w.statement.ClosingBalance.Equal(transfer.Balance.Decimal) {
+		t.Errorf("expected the running balance to reach the closing balance, got %s and %s", transfer.Balance, w.statement.ClosingBalance)
+	}
+	if !w.statement.OpeningBalance.IsZero() {
+		t.Errorf("expected no opening balance before the account existed, got %s", w.statement.OpeningBalance)
+	}
+}
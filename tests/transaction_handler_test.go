@@ -4,26 +4,87 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"transactions/apperrors"
 	"transactions/handler"
 	"transactions/models"
+
+	"github.com/gorilla/mux"
 )
 
 type fakeTransactionService struct{}
 
-func (f *fakeTransactionService) SubmitTransaction(sourceID, destID int64, amount models.Money) error {
-	if sourceID == 0 || destID == 0 {
-		return errors.New("invalid account id")
+func (f *fakeTransactionService) SubmitTransaction(req models.TransferRequest) (*models.Transaction, error) {
+	if req.SourceAccountID == 0 || req.DestinationAccountID == 0 {
+		return nil, errors.New("invalid account id")
+	}
+	if req.Amount.Decimal.IsZero() {
+		return nil, errors.New("amount must be positive")
+	}
+	if req.IdempotencyKey == "reused-key" {
+		return nil, apperrors.ErrIdempotencyKeyReused
+	}
+	if req.Amount.Decimal.String() == "9999" {
+		return nil, apperrors.ErrInsufficientFunds
+	}
+	if req.DestinationAccountID == 3 && !req.AllowConversion {
+		return nil, apperrors.ErrCurrencyMismatch
 	}
-	if amount.Decimal.IsZero() {
-		return errors.New("amount must be positive")
+	return &models.Transaction{ID: 10, SourceAccountID: req.SourceAccountID, DestinationAccountID: req.DestinationAccountID, Amount: req.Amount, Currency: models.DefaultCurrency}, nil
+}
+
+func (f *fakeTransactionService) GetTransaction(id int64) (*models.Transaction, error) {
+	if id != 1 {
+		return nil, apperrors.ErrTransactionNotFound
+	}
+	amount, _ := models.NewMoneyFromString("25.00")
+	return &models.Transaction{ID: 1, SourceAccountID: 1, DestinationAccountID: 2, Amount: amount}, nil
+}
+
+func (f *fakeTransactionService) ListAccountTransactions(accountID int64, filter models.TransactionFilter) (*models.TransactionPage, error) {
+	if accountID != 1 {
+		return nil, apperrors.ErrAccountNotFound
+	}
+	amount, _ := models.NewMoneyFromString("25.00")
+	t := models.AccountTransaction{
+		Transaction:  models.Transaction{ID: 7, SourceAccountID: 1, DestinationAccountID: 2, Amount: amount},
+		Direction:    models.DirectionDebit,
+		SignedAmount: models.Money{Decimal: amount.Neg()},
+	}
+	return &models.TransactionPage{Transactions: []models.AccountTransaction{t}, NextCursor: models.EncodeCursor(7)}, nil
+}
+
+func (f *fakeTransactionService) SubmitBatch(legs []models.TransferRequest, audit models.AuditContext) (*models.TransferBatch, error) {
+	batch := &models.TransferBatch{ID: 3}
+	for i, leg := range legs {
+		if leg.Amount.Decimal.String() == "9999" {
+			return nil, fmt.Errorf("%w: leg %d", apperrors.ErrInsufficientFunds, i)
+		}
+		batch.Transactions = append(batch.Transactions, models.Transaction{
+			ID: int64(20 + i), SourceAccountID: leg.SourceAccountID, DestinationAccountID: leg.DestinationAccountID,
+			Amount: leg.Amount, BatchID: &batch.ID,
+		})
+	}
+	return batch, nil
+}
+
+// ReverseTransaction treats transaction 1 as a 25.00 transfer with nothing
+// reversed yet.
+func (f *fakeTransactionService) ReverseTransaction(id int64, amount *models.Money, audit models.AuditContext) (*models.Transaction, error) {
+	if id != 1 {
+		return nil, apperrors.ErrTransactionNotFound
 	}
-	if amount.Decimal.String() == "9999" {
-		return errors.New("insufficient funds")
+	refund, _ := models.NewMoneyFromString("25.00")
+	if amount != nil {
+		if amount.GreaterThan(refund.Decimal) {
+			return nil, apperrors.ErrReversalExceedsAmount
+		}
+		refund = *amount
 	}
-	return nil
+	return &models.Transaction{ID: 11, SourceAccountID: 2, DestinationAccountID: 1, Amount: refund, ReversesTransactionID: &id}, nil
 }
 
 func newTestTransactionHandler() *handler.TransactionHandler {
@@ -50,6 +111,13 @@ func TestSubmitTransaction_Success(t *testing.T) {
 	if resp["message"] != "transaction submitted successfully" {
 		t.Errorf("unexpected message: %v", resp["message"])
 	}
+	data, ok := resp["data"].(map[string]interface{})
+	if !ok || data["id"] != float64(10) || data["amount"] != "100" {
+		t.Errorf("unexpected data: %v", resp["data"])
+	}
+	if loc := w.Header().Get("Location"); loc != "/transactions/10" {
+		t.Errorf("unexpected Location header: %q", loc)
+	}
 }
 
 func TestSubmitTransaction_Error(t *testing.T) {
@@ -73,3 +141,234 @@ func TestSubmitTransaction_Error(t *testing.T) {
 		t.Errorf("expected error message, got: %v", resp["error"])
 	}
 }
+
+func TestSubmitTransaction_ReportsAllInvalidFields(t *testing.T) {
+	h := newTestTransactionHandler()
+	body := []byte(`{"source_account_id": -1, "destination_account_id": 0, "amount": "abc"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	h.SubmitTransaction(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	var resp handler.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if resp.Code != "validation_failed" || len(resp.Errors) != 3 {
+		t.Errorf("expected 3 field errors with code validation_failed, got %+v", resp)
+	}
+}
+
+func TestSubmitTransaction_IdempotencyKeyConflict(t *testing.T) {
+	h := newTestTransactionHandler()
+	body := []byte(`{"source_account_id": 1, "destination_account_id": 2, "amount": "75.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewBuffer(body))
+	req.Header.Set(handler.IdempotencyKeyHeader, "reused-key")
+	w := httptest.NewRecorder()
+
+	h.SubmitTransaction(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", w.Code)
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if resp["success"].(bool) {
+		t.Errorf("expected success false, got true")
+	}
+}
+
+func TestSubmitTransaction_InsufficientFunds(t *testing.T) {
+	h := newTestTransactionHandler()
+	body := []byte(`{"source_account_id": 1, "destination_account_id": 2, "amount": "9999"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	h.SubmitTransaction(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", w.Code)
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if resp["code"] != "insufficient_funds" {
+		t.Errorf("expected code insufficient_funds, got: %v", resp["code"])
+	}
+}
+
+func TestSubmitTransaction_CurrencyMismatch(t *testing.T) {
+	h := newTestTransactionHandler()
+	body := []byte(`{"source_account_id": 1, "destination_account_id": 3, "amount": "10.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	h.SubmitTransaction(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", w.Code)
+	}
+	var resp handler.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if resp.Code != "currency_mismatch" {
+		t.Errorf("expected code currency_mismatch, got %q", resp.Code)
+	}
+}
+
+func TestGetTransaction_NotFound(t *testing.T) {
+	h := newTestTransactionHandler()
+	req := httptest.NewRequest(http.MethodGet, "/transactions/42", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+	w := httptest.NewRecorder()
+
+	h.GetTransaction(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+func TestListAccountTransactions_Success(t *testing.T) {
+	h := newTestTransactionHandler()
+	req := httptest.NewRequest(http.MethodGet, "/accounts/1/transactions?direction=debit&limit=1", nil)
+	req = mux.SetURLVars(req, map[string]string{"account_id": "1"})
+	w := httptest.NewRecorder()
+
+	h.ListAccountTransactions(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp struct {
+		Success bool                   `json:"success"`
+		Data    models.TransactionPage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if len(resp.Data.Transactions) != 1 || resp.Data.Transactions[0].SignedAmount.String() != "-25" {
+		t.Errorf("unexpected transactions: %+v", resp.Data.Transactions)
+	}
+	if cursor, err := models.DecodeCursor(resp.Data.NextCursor); err != nil || cursor != 7 {
+		t.Errorf("unexpected next cursor %q", resp.Data.NextCursor)
+	}
+}
+
+func TestListAccountTransactions_InvalidFilter(t *testing.T) {
+	h := newTestTransactionHandler()
+	req := httptest.NewRequest(http.MethodGet, "/accounts/1/transactions?direction=sideways", nil)
+	req = mux.SetURLVars(req, map[string]string{"account_id": "1"})
+	w := httptest.NewRecorder()
+
+	h.ListAccountTransactions(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
+
+func TestSubmitBatch_Success(t *testing.T) {
+	h := newTestTransactionHandler()
+	body := []byte(`{"legs": [
+		{"source_account_id": 1, "destination_account_id": 2, "amount": "10.00"},
+		{"source_account_id": 1, "destination_account_id": 3, "amount": "15.00"}
+	]}`)
+	req := httptest.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	h.SubmitBatch(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.TransferBatch `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if resp.Data.ID != 3 || len(resp.Data.Transactions) != 2 {
+		t.Errorf("unexpected batch: %+v", resp.Data)
+	}
+}
+
+func TestSubmitBatch_RejectsWholeBatch(t *testing.T) {
+	h := newTestTransactionHandler()
+	body := []byte(`{"legs": [
+		{"source_account_id": 1, "destination_account_id": 2, "amount": "10.00"},
+		{"source_account_id": 1, "destination_account_id": 3, "amount": "9999"}
+	]}`)
+	req := httptest.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	h.SubmitBatch(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d", w.Code)
+	}
+}
+
+func TestSubmitBatch_ValidatesEveryLeg(t *testing.T) {
+	h := newTestTransactionHandler()
+	body := []byte(`{"legs": [
+		{"source_account_id": 1, "destination_account_id": 1, "amount": "10.00"},
+		{"source_account_id": 0, "destination_account_id": 3, "amount": "-1"}
+	]}`)
+	req := httptest.NewRequest(http.MethodPost, "/transfers/batch", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	h.SubmitBatch(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+	var resp handler.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if len(resp.Errors) != 3 || resp.Errors[0].Field != "legs[0].destination_account_id" {
+		t.Errorf("unexpected field errors: %+v", resp.Errors)
+	}
+}
+
+func TestReverseTransaction(t *testing.T) {
+	h := newTestTransactionHandler()
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		expectedStatus int
+		expectedAmount string
+	}{
+		{"full", "1", "", http.StatusCreated, "25"},
+		{"partial", "1", `{"amount": "10.00"}`, http.StatusCreated, "10"},
+		{"exceeds original", "1", `{"amount": "30.00"}`, http.StatusUnprocessableEntity, ""},
+		{"invalid amount", "1", `{"amount": "-5"}`, http.StatusBadRequest, ""},
+		{"unknown transaction", "42", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/transactions/"+tt.id+"/reversals", bytes.NewBufferString(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+
+			h.ReverseTransaction(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if tt.expectedAmount == "" {
+				return
+			}
+			var resp struct {
+				Data models.Transaction `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("invalid JSON response: %v", err)
+			}
+			if resp.Data.Amount.String() != tt.expectedAmount || resp.Data.ReversesTransactionID == nil || *resp.Data.ReversesTransactionID != 1 {
+				t.Errorf("unexpected reversal: %+v", resp.Data)
+			}
+			if loc := w.Header().Get("Location"); loc != "/transactions/11" {
+				t.Errorf("unexpected Location header: %q", loc)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"transactions/apperrors"
+	"transactions/handler"
+	"transactions/models"
+	"transactions/repository"
+	"transactions/service"
+
+	"github.com/gorilla/mux"
+)
+
+// mockWebhookRepo keeps deliveries in memory and claims every pending
+// delivery that is due, ignoring the lease
+type mockWebhookRepo struct {
+	repository.WebhookRepositoryInterface
+	now        time.Time
+	deliveries map[int64]*models.WebhookDelivery
+	created    models.WebhookSubscription
+}
+
+func newMockWebhookRepo(now time.Time, deliveries ...models.WebhookDelivery) *mockWebhookRepo {
+	m := &mockWebhookRepo{now: now, deliveries: map[int64]*models.WebhookDelivery{}}
+	for i := range deliveries {
+		m.deliveries[deliveries[i].ID] = &deliveries[i]
+	}
+	return m
+}
+
+func (m *mockWebhookRepo) ClaimDueWebhookDeliveries(limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	var claimed []models.WebhookDelivery
+	for _, d := range m.deliveries {
+		if d.Status == models.WebhookDeliveryPending && (d.NextAttemptAt == nil || !d.NextAttemptAt.After(m.now)) {
+			claimed = append(claimed, *d)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *mockWebhookRepo) RecordWebhookAttempt(deliveryID int64, attempt models.WebhookAttempt, status string, nextAttemptAt *time.Time) error {
+	d := m.deliveries[deliveryID]
+	d.Attempts++
+	d.Status = status
+	d.NextAttemptAt = nextAttemptAt
+	d.LastError = attempt.Error
+	d.AttemptLog = append(d.AttemptLog, attempt)
+	return nil
+}
+
+func (m *mockWebhookRepo) CreateWebhook(sub models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	m.created = sub
+	sub.ID = 1
+	sub.Active = true
+	return &sub, nil
+}
+
+func (m *mockWebhookRepo) RedeliverWebhook(id int64) (*models.WebhookDelivery, error) {
+	d, ok := m.deliveries[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: webhook delivery %d", apperrors.ErrWebhookDeliveryNotFound, id)
+	}
+	if d.Status == models.WebhookDeliveryPending {
+		return nil, fmt.Errorf("%w: webhook delivery %d", apperrors.ErrWebhookDeliveryPending, id)
+	}
+	d.Status, d.Attempts, d.NextAttemptAt = models.WebhookDeliveryPending, 0, nil
+	return d, nil
+}
+
+// webhookReceiver is an httptest server answering with the queued status
+// codes, then 200, and recording each request it gets
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newWebhookReceiver(statuses ...int) *webhookReceiver {
+	rec := &webhookReceiver{statuses: statuses}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.requests = append(rec.requests, r)
+		rec.bodies = append(rec.bodies, body)
+		status := http.StatusOK
+		if len(rec.statuses) > 0 {
+			status, rec.statuses = rec.statuses[0], rec.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	return rec
+}
+
+func webhookDelivery(id int64, url string) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		ID: id, SubscriptionID: 1, EventID: 10, EventType: models.EventTransferCompleted,
+		Payload: json.RawMessage(`{"id":10,"type":"TransferCompleted"}`),
+		Status:  models.WebhookDeliveryPending, URL: url, Secret: "whsec_test",
+	}
+}
+
+func newTestWebhookWorker(repo *mockWebhookRepo, maxAttempts int) *service.WebhookWorker {
+	w := service.NewWebhookWorker(repo, http.DefaultClient, time.Second, time.Minute, maxAttempts, 30*time.Second, 2*time.Minute)
+	w.Now = func() time.Time { return repo.now }
+	return w
+}
+
+func TestWebhookWorker_SignsDelivery(t *testing.T) {
+	receiver := newWebhookReceiver()
+	defer receiver.Close()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := newMockWebhookRepo(now, webhookDelivery(7, receiver.URL))
+
+	if n := newTestWebhookWorker(repo, 3).DeliverDue(context.Background()); n != 1 {
+		t.Fatalf("expected 1 delivery, got %d", n)
+	}
+	if d := repo.deliveries[7]; d.Status != models.WebhookDeliveryDelivered || d.Attempts != 1 {
+		t.Errorf("expected delivered after 1 attempt, got %s after %d", d.Status, d.Attempts)
+	}
+
+	r, body := receiver.requests[0], receiver.bodies[0]
+	want := fmt.Sprintf("t=%d,v1=%s", now.Unix(), models.WebhookSignature("whsec_test", now.Unix(), body))
+	if got := r.Header.Get(models.WebhookSignatureHeader); got != want {
+		t.Errorf("expected signature %q, got %q", want, got)
+	}
+	if r.Header.Get(models.WebhookEventHeader) != models.EventTransferCompleted || r.Header.Get(models.WebhookDeliveryHeader) != "7" {
+		t.Errorf("unexpected headers %v", r.Header)
+	}
+	if !bytes.Equal(body, repo.deliveries[7].Payload) {
+		t.Errorf("expected the payload as body, got %s", body)
+	}
+}
+
+func TestWebhookWorker_RetriesWithBackoffThenDies(t *testing.T) {
+	receiver := newWebhookReceiver(http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusInternalServerError)
+	defer receiver.Close()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := newMockWebhookRepo(now, webhookDelivery(1, receiver.URL))
+	worker := newTestWebhookWorker(repo, 4)
+	d := repo.deliveries[1]
+
+	// 30s, then doubled, then capped at 2m
+	for _, wait := range []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute} {
+		worker.DeliverDue(context.Background())
+		if d.Status != models.WebhookDeliveryPending || d.NextAttemptAt == nil || !d.NextAttemptAt.Equal(repo.now.Add(wait)) {
+			t.Fatalf("after attempt %d expected pending until +%s, got %s %v", d.Attempts, wait, d.Status, d.NextAttemptAt)
+		}
+		// Not due yet
+		worker.DeliverDue(context.Background())
+		repo.now = *d.NextAttemptAt
+	}
+
+	worker.DeliverDue(context.Background())
+	if d.Status != models.WebhookDeliveryDead || d.Attempts != 4 || d.NextAttemptAt != nil {
+		t.Fatalf("expected dead after 4 attempts, got %s after %d", d.Status, d.Attempts)
+	}
+	if len(receiver.requests) != 4 {
+		t.Errorf("expected 4 requests, got %d", len(receiver.requests))
+	}
+	if code := d.AttemptLog[0].StatusCode; code == nil || *code != http.StatusInternalServerError {
+		t.Errorf("expected the status code in the attempt log, got %+v", d.AttemptLog[0])
+	}
+
+	// Redelivery starts over and succeeds against the recovered receiver
+	if _, err := service.NewWebhookService(repo).RedeliverWebhook(1); err != nil {
+		t.Fatalf("redeliver: %v", err)
+	}
+	if n := worker.DeliverDue(context.Background()); n != 1 || d.Status != models.WebhookDeliveryDelivered {
+		t.Errorf("expected redelivery to succeed, got %s", d.Status)
+	}
+}
+
+func TestWebhookWorker_UnreachableReceiver(t *testing.T) {
+	receiver := newWebhookReceiver()
+	url := receiver.URL
+	receiver.Close()
+	repo := newMockWebhookRepo(time.Now(), webhookDelivery(1, url))
+
+	newTestWebhookWorker(repo, 3).DeliverDue(context.Background())
+	d := repo.deliveries[1]
+	if d.Status != models.WebhookDeliveryPending || d.LastError == "" || d.AttemptLog[0].StatusCode != nil {
+		t.Errorf("expected a pending delivery with a connection error, got %+v", d)
+	}
+}
+
+func TestCreateWebhook_Validation(t *testing.T) {
+	h := handler.NewWebhookHandler(service.NewWebhookService(newMockWebhookRepo(time.Now())))
+
+	for _, body := range []string{
+		`{"url":"ftp://example.com/hook","event_types":["TransferCompleted"]}`,
+		`{"url":"/hook","event_types":["TransferCompleted"]}`,
+		`{"url":"https://example.com/hook","event_types":[]}`,
+		`{"url":"https://example.com/hook","event_types":["TransferExploded"]}`,
+		`{"url":"https://example.com/hook","event_types":["TransferCompleted"],"account_ids":[0]}`,
+		`{"url":"https://example.com/hook","event_types":["TransferCompleted"],"secret":"short"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.CreateWebhook(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestCreateWebhook_GeneratesSecret(t *testing.T) {
+	repo := newMockWebhookRepo(time.Now())
+	h := handler.NewWebhookHandler(service.NewWebhookService(repo))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"https://example.com/hook","event_types":["TransferCompleted","TransferFailed"]}`))
+	w := httptest.NewRecorder()
+	h.CreateWebhook(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data models.WebhookSubscription `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if !strings.HasPrefix(resp.Data.Secret, "whsec_") || resp.Data.Secret != repo.created.Secret {
+		t.Errorf("expected the generated secret to be stored and returned, got %q", resp.Data.Secret)
+	}
+}
+
+func TestRedeliverWebhook_Conflicts(t *testing.T) {
+	repo := newMockWebhookRepo(time.Now(), webhookDelivery(1, "http://example.com"))
+	h := handler.NewWebhookHandler(service.NewWebhookService(repo))
+
+	tests := []struct {
+		id     string
+		status int
+	}{
+		{"1", http.StatusConflict},
+		{"2", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/webhook-deliveries/"+tt.id+"/redeliver", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+		w := httptest.NewRecorder()
+		h.RedeliverWebhook(w, req)
+		if w.Code != tt.status {
+			t.Errorf("delivery %s: expected status %d, got %d", tt.id, tt.status, w.Code)
+		}
+	}
+}